@@ -2,61 +2,426 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	redis "github.com/go-redis/redis/v8"
 	"github.com/labstack/echo"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 
+	"github.com/hezbymuhammad/golang-marvel-demo/config"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	apikeyHttpDelivery "github.com/hezbymuhammad/golang-marvel-demo/model/apikey/delivery/http"
+	apikeyRepository "github.com/hezbymuhammad/golang-marvel-demo/model/apikey/repository"
+	apikeyUsecase "github.com/hezbymuhammad/golang-marvel-demo/model/apikey/usecase"
+	cacheHttpDelivery "github.com/hezbymuhammad/golang-marvel-demo/model/cache/delivery/http"
+	cacheRepository "github.com/hezbymuhammad/golang-marvel-demo/model/cache/repository"
+	cacheUsecase "github.com/hezbymuhammad/golang-marvel-demo/model/cache/usecase"
+	characterGraphQLDelivery "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/graphql"
+	characterGrpcDelivery "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/grpc"
 	characterHttpDelivery "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/http"
 	characterRepository "github.com/hezbymuhammad/golang-marvel-demo/model/character/repository"
 	characterUsecase "github.com/hezbymuhammad/golang-marvel-demo/model/character/usecase"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/health"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/prerefresh"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/ratelimit"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/tracing"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/worker"
 )
 
-func init() {
-	viper.SetConfigFile(`config/common.json`)
-	err := viper.ReadInConfig()
+const (
+	exitOK = iota
+	exitServerError
+	exitShutdownError
+	exitConfigError
+)
+
+const (
+	redisPingTimeout    = 5 * time.Second
+	jwksTimeout         = 5 * time.Second
+	statusCountInterval = time.Minute
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	loader := config.NewLoader(os.Args[0])
+	if err := loader.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitConfigError
+	}
+
+	cfg, err := loader.Load()
+	if loader.PrintConfig() {
+		fmt.Println(cfg)
+	}
 	if err != nil {
-		panic(err)
+		slog.Error("Failed loading configuration", "error", err)
+		return exitConfigError
+	}
+	if loader.PrintConfig() {
+		return exitOK
 	}
-}
 
-func main() {
-	apiUrl := viper.GetString(`marvel_api.url`)
-	publicKey := viper.GetString(`marvel_api.public_key`)
-	privateKey := viper.GetString(`marvel_api.private_key`)
-	httpMarvelApiTimeout := time.Duration(viper.GetInt(`marvel_api.timeout_in_sec`)) * time.Second
-	cacheExpiration := time.Duration(viper.GetInt(`cache_expiration_in_sec`)) * time.Second
-	httpTimeout := time.Duration(viper.GetInt(`server.timeout_in_sec`)) * time.Second
-	redisHost := viper.GetString(`redis.host`)
-	redisPort := viper.GetString(`redis.port`)
+	logs := logger.New(os.Stdout, cfg.Log.Format)
+	if err := logs.SetLevels(cfg.Log.Level, cfg.Log.Components); err != nil {
+		slog.Error("Failed configuring logs", "error", err)
+		return exitConfigError
+	}
+	slog.SetDefault(logs.For("default"))
+	log := logs.For("main")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	flushTraces, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		Version:     health.Version,
+	})
+	if err != nil {
+		log.Error("Failed setting up tracing", "error", err)
+		return exitConfigError
+	}
 
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.Use(tracing.Middleware("/healthz", "/readyz", "/status", "/metrics"))
+	e.Use(logger.Middleware(logs.For("http")))
+	e.Use(metrics.Middleware())
 	redisConn := redis.NewClient(&redis.Options{
-		Addr: redisHost + ":" + redisPort,
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
 	})
+	redisConn.AddHook(tracing.RedisHook{})
+	if err := pingRedis(ctx, redisConn); err != nil {
+		log.Error("Redis is not reachable", "addr", cfg.Redis.Addr(), "error", err)
+		redisConn.Close()
+		return exitConfigError
+	}
+	pool := worker.NewPool()
+	pool.SetPaused(!cfg.Features.BackgroundRefresh)
+	metrics.RegisterPendingJobs(pool.Pending)
+	metrics.UpstreamQuotaLimit.Set(float64(cfg.MarvelAPI.DailyQuota))
 
-	crRead := characterRepository.NewCharacterReadRepository(redisConn)
+	characterRepository.HttpClient = &http.Client{Transport: tracing.Transport(http.DefaultTransport)}
+	keys := cachekey.New(cfg.Redis.KeyPrefix).WithLayout(cachekey.Layout(cfg.Redis.Layout))
+	// The codec settings were validated with the configuration.
+	encoder, _ := codec.New(cfg.Codec.Format, cfg.Codec.Compression, cfg.Codec.CompressionThreshold)
+	var crRead domain.CharacterReadRepository = characterRepository.NewCharacterReadRepository(redisConn, keys, logs.For("read_repository"))
+	if keys.Layout() == cachekey.LayoutHash {
+		crRead = characterRepository.NewCharacterHashReadRepository(redisConn, keys, logs.For("read_repository"))
+	}
 	crWrite := characterRepository.NewCharacterWriteRepository(
-		apiUrl,
-		publicKey,
-		privateKey,
+		cfg.MarvelAPI.URL,
+		cfg.MarvelAPI.PublicKey,
+		cfg.MarvelAPI.PrivateKey,
 		redisConn,
-		httpMarvelApiTimeout,
-		cacheExpiration,
+		keys,
+		encoder,
+		cfg.MarvelAPI.Timeout(),
+		cfg.CacheExpiration(),
+		cfg.NotFoundExpiration(),
+		logs.For("write_repository"),
 	)
+	crWrite.SetCacheExpirationJitter(cfg.CacheExpirationJitter)
+	crWrite.SetPopularExpiration(cfg.Popularity.Expiration())
+	// The usecase is handed a nil interface, not a nil pointer, when lookups
+	// are not counted.
+	var popularity domain.PopularityRepository
+	if cfg.Popularity.Enabled {
+		pr := characterRepository.NewPopularityRepository(redisConn, keys, cfg.Popularity.Bucket(), cfg.Popularity.Retention(), logs.For("popularity_repository"))
+		crRead = characterRepository.TrackLookups(crRead, pr)
+		popularity = pr
+		go pr.Run(ctx, cfg.Popularity.FlushInterval())
+	}
+	// Validation made sure lookups are counted.
+	if cfg.PreRefresh.Enabled {
+		refresher := prerefresh.NewRefresher(redisConn, keys, crWrite, pool, popularity, prerefresh.Options{
+			Interval:   cfg.PreRefresh.Interval(),
+			Window:     cfg.PreRefresh.Window(),
+			Popularity: cfg.Popularity.Window(),
+			Top:        cfg.PreRefresh.Top,
+			MinHits:    int64(cfg.PreRefresh.MinHits),
+		}, logs.For("prerefresh"))
+		crWrite.SetPopularity(refresher)
+		go refresher.Run(ctx)
+	}
 	cu := characterUsecase.NewCharacterUsecase(
 		crRead,
 		crWrite,
-		httpTimeout,
+		popularity,
+		pool,
+		cfg.Server.Timeout(),
+		logs.For("usecase"),
 	)
-	characterHttpDelivery.NewCharacterHandler(e, cu)
+	// Characters and admin endpoints accept every configured way of
+	// authenticating, the scopes of the identity decide what it may do.
+	var consumerMethods, adminMethods []auth.Method
+	if cfg.JWT.Enabled() {
+		verifier, err := newJWTVerifier(cfg.JWT)
+		if err != nil {
+			log.Error("Failed loading the JWKS", "error", err)
+			redisConn.Close()
+			return exitConfigError
+		}
+		consumerMethods = append(consumerMethods, auth.JWTMethod(verifier))
+		adminMethods = append(adminMethods, auth.JWTMethod(verifier))
+	}
+	var au domain.APIKeyUsecase
+	if cfg.APIKeys.Enabled {
+		ar := apikeyRepository.NewAPIKeyRepository(redisConn, keys, logs.For("apikey_repository"))
+		au = apikeyUsecase.NewAPIKeyUsecase(ar, cfg.APIKeys.RateLimit, cfg.APIKeys.DailyQuota, cfg.Server.Timeout(), logs.For("apikey_usecase"))
+		consumerMethods = append(consumerMethods, auth.APIKeyMethod(au))
+	}
+	adminMethods = auth.WithAdminToken(cfg.Admin.Token, adminMethods...)
+	var consumerMiddleware []echo.MiddlewareFunc
+	if len(consumerMethods) > 0 {
+		consumerMiddleware = append(consumerMiddleware, auth.Require(auth.ScopeCharactersRead, consumerMethods...))
+	}
+	// Rate limits come after auth, so that authenticated clients are told
+	// apart by their identity rather than their IP.
+	limiter := newLimiter(cfg.RateLimit.Backend, redisConn, keys)
+	limits := ratelimit.NewDynamicLimits(rateLimits(cfg.RateLimit))
+	if limiter != nil {
+		consumerMiddleware = append(consumerMiddleware, ratelimit.Middleware(limiter, limits, logs.For("ratelimit")))
+	}
+	characterHttpDelivery.NewCharacterHandler(e, cu, logs.For("handler"), consumerMiddleware...)
+	characterGraphQLDelivery.NewCharacterHandler(e, cu, logs.For("graphql"), characterGraphQLDelivery.Options{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+		Playground:    cfg.GraphQL.Playground,
+	}, consumerMiddleware...)
+	// gRPC calls are authenticated and limited like the HTTP requests.
+	grpcServer := grpc.NewServer(characterGrpcDelivery.Interceptors(characterGrpcDelivery.Guard{
+		Methods: consumerMethods,
+		Limiter: limiter,
+		Limits:  limits,
+	}, logs.For("ratelimit"))...)
+	characterGrpcDelivery.NewCharacterServer(grpcServer, cu, logs.For("grpc"))
+	reflection.Register(grpcServer)
+
+	if len(adminMethods) > 0 {
+		adminAuth := auth.Require(auth.ScopeAdmin, adminMethods...)
+		cacheRepo := cacheRepository.NewCacheRepository(redisConn, keys, logs.For("cache_repository"))
+		cacheU := cacheUsecase.NewCacheUsecase(cacheRepo, crWrite, keys, cfg.Server.Timeout(), logs.For("cache_usecase"))
+		cacheHttpDelivery.NewCacheHandler(e, cacheU, logs.For("admin"), adminAuth)
+		if au != nil {
+			apikeyHttpDelivery.NewAPIKeyHandler(e, au, logs.For("admin"), adminAuth)
+		}
+	} else {
+		log.Info("No admin token or JWKS set, not serving the admin endpoints")
+		if au != nil {
+			log.Warn("API keys are required but cannot be issued without admin access")
+		}
+	}
+
+	reloader := config.NewReloader(loader, cfg, logs.For("reloader"))
+	reloader.OnReload(func(cfg config.Config) {
+		_ = logs.SetLevels(cfg.Log.Level, cfg.Log.Components)
+		crWrite.SetCacheExpiration(cfg.CacheExpiration())
+		crWrite.SetCacheExpirationJitter(cfg.CacheExpirationJitter)
+		crWrite.SetPopularExpiration(cfg.Popularity.Expiration())
+		crWrite.SetNotFoundExpiration(cfg.NotFoundExpiration())
+		crWrite.SetTimeout(cfg.MarvelAPI.Timeout())
+		limits.Store(rateLimits(cfg.RateLimit))
+		pool.SetPaused(!cfg.Features.BackgroundRefresh)
+		metrics.UpstreamQuotaLimit.Set(float64(cfg.MarvelAPI.DailyQuota))
+	})
+	go reloader.Watch(ctx)
+
+	checker := health.NewChecker(
+		redisConn,
+		cfg.MarvelAPI.URL,
+		map[string]string{
+			"characters": keys.CharacterPrefix() + "*",
+			"pages":      keys.PagePrefix() + "*",
+		},
+		func() error { return reloader.Current().Validate() },
+		pool.Pending,
+	)
+	go checker.Run(ctx, statusCountInterval)
+	health.NewHandler(e, checker)
+	metrics.NewHandler(e)
+
+	grpcListener, err := net.Listen("tcp", cfg.GRPC.Address)
+	if err != nil {
+		log.Error("Failed listening for gRPC", "address", cfg.GRPC.Address, "error", err)
+		redisConn.Close()
+		return exitConfigError
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		serverErr <- e.Start(cfg.Server.Address)
+	}()
+	log.Info("HTTP server started", "address", cfg.Server.Address)
+	go func() {
+		serverErr <- grpcServer.Serve(grpcListener)
+	}()
+	log.Info("gRPC server started", "address", cfg.GRPC.Address)
+
+	log.Info("Warming up cache for several seconds")
+	err = pool.Go(ctx, func(jobCtx context.Context) {
+		jobCtx, span := otel.Tracer("github.com/hezbymuhammad/golang-marvel-demo/cmd").Start(jobCtx, "Warmup")
+		defer span.End()
 
-	log.Println("[INFO] Warming up cache for several seconds")
-	for i := 0; i <= 15; i++ {
-		crWrite.StoreByPage(context.Background(), i)
+		// Stop starting new pages on shutdown, the page in flight is drained.
+		for i := 0; i <= 15 && ctx.Err() == nil; i++ {
+			crWrite.StoreByPage(jobCtx, i)
+		}
+		checker.MarkWarm()
+		log.Info("Cache warmup done")
+	})
+	if err != nil {
+		log.Info("Skipping cache warmup", "error", err)
+		checker.MarkWarm()
+	}
+
+	code := exitOK
+	select {
+	case err := <-serverErr:
+		log.Error("Server stopped", "error", err)
+		code = exitServerError
+	case <-ctx.Done():
+		log.Info("Shutdown signal received, draining", "timeout", cfg.Server.ShutdownTimeout().String())
+	}
+	// A second signal falls back to the default behaviour and kills the process.
+	stop()
+
+	if err := shutdown(e, grpcServer, pool, redisConn, flushTraces, cfg.Server.ShutdownTimeout(), log); err != nil && code == exitOK {
+		code = exitShutdownError
+	}
+
+	return code
+}
+
+func pingRedis(c context.Context, redisConn *redis.Client) error {
+	ctx, cancel := context.WithTimeout(c, redisPingTimeout)
+	defer cancel()
+
+	return redisConn.Ping(ctx).Err()
+}
+
+// newJWTVerifier verifies bearer JWTs with the JWKS of cfg. A remote JWKS is
+// fetched lazily, so that the server starts while the issuer is down.
+func newJWTVerifier(cfg config.JWT) (*auth.JWTVerifier, error) {
+	if cfg.JWKSFile != "" {
+		keys, err := auth.LoadKeySet(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewJWTVerifier(keys, cfg.Issuer, cfg.Audience), nil
+	}
+
+	client := &http.Client{Transport: tracing.Transport(http.DefaultTransport), Timeout: jwksTimeout}
+	return auth.NewJWTVerifier(auth.NewRemoteKeySet(cfg.JWKSURL, client), cfg.Issuer, cfg.Audience), nil
+}
+
+// newLimiter returns the rate limiter of backend, nil when requests are not
+// limited.
+func newLimiter(backend string, redisConn *redis.Client, keys cachekey.Builder) ratelimit.Limiter {
+	switch backend {
+	case "memory":
+		return ratelimit.NewMemoryLimiter()
+	case "redis":
+		return ratelimit.NewRedisLimiter(redisConn, keys)
+	default:
+		return nil
 	}
+}
 
-	log.Fatal(e.Start(viper.GetString("server.address")))
+// rateLimits returns the limits of cfg, whose backend is picked at startup.
+func rateLimits(cfg config.RateLimit) ratelimit.Limits {
+	return ratelimit.Limits{
+		Client: ratelimit.Limit{PerMinute: cfg.PerMinute, Burst: cfg.Burst},
+		Global: ratelimit.Limit{PerMinute: cfg.GlobalPerMinute, Burst: cfg.GlobalBurst},
+		Miss:   ratelimit.Limit{PerMinute: cfg.MissPerMinute, Burst: cfg.MissBurst},
+	}
+}
+
+// shutdown stops accepting connections, then waits for in-flight requests,
+// calls and streams, and background cache refreshes to finish before closing
+// the Redis connection and flushing the spans they recorded. Every step
+// shares the same deadline.
+func shutdown(e *echo.Echo, grpcServer *grpc.Server, pool *worker.Pool, redisConn *redis.Client, flushTraces func(context.Context) error, timeout time.Duration, log *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+
+	if err := e.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error("Shutdown http server failed", "error", err)
+		errs = append(errs, err)
+	}
+
+	if err := stopGrpc(ctx, grpcServer); err != nil {
+		log.Error("Shutdown grpc server failed", "error", err)
+		errs = append(errs, err)
+	}
+
+	pending := pool.Pending()
+	if err := pool.Shutdown(ctx); err != nil {
+		log.Error("Shutdown background jobs failed", "error", err, "abandoned", pool.Pending(), "pending", pending)
+		errs = append(errs, err)
+	}
+
+	if err := redisConn.Close(); err != nil {
+		log.Error("Shutdown redis connection failed", "error", err)
+		errs = append(errs, err)
+	}
+
+	if err := flushTraces(ctx); err != nil {
+		log.Error("Flush traces failed", "error", err)
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	log.Info("Shutdown complete")
+	return nil
+}
+
+// stopGrpc waits for the calls in flight like GracefulStop, but cancels the
+// ones still running once ctx is done.
+func stopGrpc(ctx context.Context, grpcServer *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		grpcServer.Stop()
+		return ctx.Err()
+	}
 }
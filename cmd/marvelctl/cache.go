@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+)
+
+// scanCount is the COUNT hint of each SCAN call.
+const scanCount = 100
+
+// entry describes a cache key. TTL is in seconds, -1 without expiry. An empty
+// entry caches a resource Marvel API does not have. The size of a sorted set
+// is its member count.
+type entry struct {
+	Key       string          `json:"key"`
+	TTL       int64           `json:"ttl"`
+	Size      int             `json:"size"`
+	Encoding  string          `json:"encoding,omitempty"`
+	FetchedAt *time.Time      `json:"fetchedAt,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+}
+
+func (a *app) cache(ctx context.Context, args []string) error {
+	flags := a.flags("cache keys|show|purge|migrate|export|import")
+	flags.SetInterspersed(false)
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+	if err := a.requireDirect("cache"); err != nil {
+		return err
+	}
+
+	switch flags.Arg(0) {
+	case "keys":
+		return a.cacheKeys(ctx, flags.Args()[1:])
+	case "show":
+		return a.cacheShow(ctx, flags.Args()[1:])
+	case "purge":
+		return a.cachePurge(ctx, flags.Args()[1:])
+	case "migrate":
+		return a.cacheMigrate(ctx, flags.Args()[1:])
+	case "export":
+		return a.cacheExport(ctx, flags.Args()[1:])
+	case "import":
+		return a.cacheImport(ctx, flags.Args()[1:])
+	default:
+		flags.Usage()
+		return errUsage
+	}
+}
+
+func (a *app) cacheKeys(ctx context.Context, args []string) error {
+	flags := a.flags("cache keys [--match PATTERN]")
+	match := flags.String("match", a.keys.Prefix()+"*", "glob pattern of the keys")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	entries := []entry{}
+	err := scan(ctx, a.redis, *match, func(keys []string) error {
+		found, err := a.entries(ctx, a.cached(keys))
+		entries = append(entries, found...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	return a.print(a.stdout, entries, func(w io.Writer) {
+		fmt.Fprintln(w, "KEY\tTTL\tSIZE\tFETCHED AT")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", e.Key, ttlString(e.TTL), e.Size, fetchedAtString(e.FetchedAt))
+		}
+	})
+}
+
+func (a *app) cacheShow(ctx context.Context, args []string) error {
+	flags := a.flags("cache show KEY")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	entries, err := a.entries(ctx, flags.Args())
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("%s is not cached", flags.Arg(0))
+	}
+	e := entries[0]
+
+	return a.print(a.stdout, e, func(w io.Writer) {
+		fmt.Fprintln(w, "KEY\tTTL\tSIZE\tFETCHED AT")
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n\n%s\n", e.Key, ttlString(e.TTL), e.Size, fetchedAtString(e.FetchedAt), e.Value)
+	})
+}
+
+// cachePurge only lists the keys it would delete unless --yes is given.
+// Patterns and keys must start with the key prefix so that other data in the
+// same Redis is left alone.
+func (a *app) cachePurge(ctx context.Context, args []string) error {
+	flags := a.flags("cache purge [--match PATTERN] [KEY...] [--yes]")
+	match := flags.String("match", "", "glob pattern of the keys, starting with "+a.keys.Prefix())
+	yes := flags.Bool("yes", false, "delete the keys rather than listing them")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *match == "" && flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+	if *match != "" && !strings.HasPrefix(*match, a.keys.Prefix()) {
+		return fmt.Errorf("--match must start with %s", a.keys.Prefix())
+	}
+	for _, key := range flags.Args() {
+		if !strings.HasPrefix(key, a.keys.Prefix()) {
+			return fmt.Errorf("key %s does not start with %s", key, a.keys.Prefix())
+		}
+		if a.keys.Reserved(key) {
+			return fmt.Errorf("key %s holds API keys or rate limits, not cache", key)
+		}
+	}
+
+	keys := flags.Args()
+	if *match != "" {
+		err := scan(ctx, a.redis, *match, func(found []string) error {
+			keys = append(keys, a.cached(found)...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	sort.Strings(keys)
+
+	if !*yes {
+		for _, key := range keys {
+			fmt.Fprintln(a.stdout, key)
+		}
+		fmt.Fprintf(a.stderr, "Would delete %d keys, run again with --yes to delete them\n", len(keys))
+		return nil
+	}
+
+	var deleted int64
+	for start := 0; start < len(keys); start += scanCount {
+		end := start + scanCount
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		n, err := a.delete(ctx, keys[start:end])
+		if err != nil {
+			return err
+		}
+		deleted += n
+	}
+	fmt.Fprintf(a.stderr, "Deleted %d keys\n", deleted)
+
+	return nil
+}
+
+// delete deletes keys, and drops the characters of the hash layout among
+// them from the ByModified index, like the purges of the server.
+func (a *app) delete(ctx context.Context, keys []string) (int64, error) {
+	hash := a.keys.WithLayout(cachekey.LayoutHash)
+	var members []interface{}
+	for _, key := range keys {
+		if kind, id, ok := hash.Parse(key); ok && kind == cachekey.KindCharacter {
+			members = append(members, strconv.Itoa(id))
+		}
+	}
+
+	pipe := a.redis.TxPipeline()
+	del := pipe.Del(ctx, keys...)
+	if len(members) > 0 {
+		pipe.ZRem(ctx, hash.ByModified(), members...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return del.Val(), nil
+}
+
+// cached leaves out the keys of API keys and rate limits.
+func (a *app) cached(keys []string) []string {
+	kept := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !a.keys.Reserved(key) {
+			kept = append(kept, key)
+		}
+	}
+
+	return kept
+}
+
+// entries reads keys in two pipelines, their types then their values. Keys
+// that do not exist or are neither strings, hashes nor sorted sets are left
+// out.
+func (a *app) entries(ctx context.Context, keys []string) ([]entry, error) {
+	pipe := a.redis.Pipeline()
+	types := make([]*redis.StatusCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		types[i] = pipe.Type(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	pipe = a.redis.Pipeline()
+	vals := make([]redis.Cmder, len(keys))
+	for i, key := range keys {
+		switch types[i].Val() {
+		case "string":
+			vals[i] = pipe.Get(ctx, key)
+		case "hash":
+			vals[i] = pipe.HGetAll(ctx, key)
+		case "zset":
+			vals[i] = pipe.ZRangeWithScores(ctx, key, 0, -1)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(keys))
+	for i, key := range keys {
+		if vals[i] == nil || vals[i].Err() == redis.Nil {
+			continue
+		}
+		if err := vals[i].Err(); err != nil {
+			return nil, err
+		}
+
+		e := entry{Key: key, TTL: -1}
+		if ttl := ttls[i].Val(); ttl > 0 {
+			e.TTL = int64(ttl / time.Second)
+		}
+		switch cmd := vals[i].(type) {
+		case *redis.StringCmd:
+			a.stringEntry(&e, cmd.Val())
+		case *redis.StringStringMapCmd:
+			hashEntry(&e, cmd.Val())
+		case *redis.ZSliceCmd:
+			e.Encoding = "zset"
+			e.Size = len(cmd.Val())
+			e.Value = indexValue(cmd.Val())
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func (a *app) stringEntry(e *entry, val string) {
+	e.Size = len(val)
+	kind := a.keys.Kind(e.Key)
+	if kind != "" && val != "" {
+		e.Encoding = codec.Describe([]byte(val))
+	}
+
+	var decoded interface{}
+	switch {
+	case kind == cachekey.KindCharacter && val != "":
+		var cached domain.Character
+		if codec.Decode([]byte(val), &cached) == nil {
+			decoded = cached
+			if !cached.FetchedAt.IsZero() {
+				e.FetchedAt = &cached.FetchedAt
+			}
+		}
+	case kind == cachekey.KindPage && val != "":
+		var ids []int
+		if codec.Decode([]byte(val), &ids) == nil {
+			decoded = ids
+		}
+	}
+	switch {
+	case json.Valid([]byte(val)):
+		e.Value = json.RawMessage(val)
+	case decoded != nil:
+		e.Value, _ = json.Marshal(decoded)
+	case val != "":
+		e.Value, _ = json.Marshal(val)
+	}
+}
+
+// hashEntry describes a character of the hash layout, empty when it is a
+// tombstone.
+func hashEntry(e *entry, fields map[string]string) {
+	e.Encoding = "hash"
+	cached, tombstone, err := codec.DecodeHash(fields)
+	if tombstone {
+		return
+	}
+	for field, val := range fields {
+		e.Size += len(field) + len(val)
+	}
+	if err != nil {
+		return
+	}
+
+	if !cached.FetchedAt.IsZero() {
+		e.FetchedAt = &cached.FetchedAt
+	}
+	e.Value, _ = json.Marshal(cached)
+}
+
+// indexValue lists the members of a sorted set with their scores, in order.
+func indexValue(members []redis.Z) json.RawMessage {
+	type member struct {
+		Member interface{} `json:"member"`
+		Score  float64     `json:"score"`
+	}
+	value := make([]member, len(members))
+	for i, z := range members {
+		value[i] = member{Member: z.Member, Score: z.Score}
+	}
+
+	data, _ := json.Marshal(value)
+	return data
+}
+
+// scan calls fn with the keys matching pattern, a SCAN batch at a time, so
+// that a large keyspace does not block Redis like KEYS would.
+func scan(ctx context.Context, client redis.Cmdable, pattern string, fn func([]string) error) error {
+	seen := map[string]bool{}
+
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		// SCAN may return a key more than once.
+		fresh := keys[:0]
+		for _, key := range keys {
+			if !seen[key] {
+				seen[key] = true
+				fresh = append(fresh, key)
+			}
+		}
+		if len(fresh) > 0 {
+			if err := fn(fresh); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+func ttlString(ttl int64) string {
+	if ttl < 0 {
+		return "none"
+	}
+
+	return (time.Duration(ttl) * time.Second).String()
+}
+
+func fetchedAtString(fetchedAt *time.Time) string {
+	if fetchedAt == nil {
+		return "-"
+	}
+
+	return fetchedAt.Format(time.RFC3339)
+}
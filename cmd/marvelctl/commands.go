@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+)
+
+// maxBatchSize is the most characters looked up at once, the limit of the
+// batch endpoint.
+const maxBatchSize = 100
+
+// result is the outcome of a warmup or sync of one page or character.
+type result struct {
+	Kind   string `json:"kind"`
+	ID     int    `json:"id"`
+	Result string `json:"result"`
+}
+
+func (a *app) get(ctx context.Context, args []string) error {
+	flags := a.flags("get ID...")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil || len(ids) == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	if len(ids) == 1 {
+		character, err := a.source.GetByID(ctx, ids[0])
+		if err != nil {
+			return err
+		}
+		return a.print(a.stdout, character, charactersTable([]domain.Character{character}))
+	}
+
+	batch, err := a.getByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	a.warnMissing(batch)
+
+	return a.print(a.stdout, batch, charactersTable(batch.Characters))
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	flags := a.flags("list [--page N] [--expand]")
+	page := flags.Int("page", 1, "page number")
+	expand := flags.Bool("expand", false, "show the characters rather than their IDs")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	if !*expand {
+		ids, err := a.source.Fetch(ctx, *page)
+		if err != nil {
+			return err
+		}
+		return a.print(a.stdout, ids, idsTable(ids))
+	}
+
+	characters, err := a.page(ctx, *page)
+	if err != nil {
+		return err
+	}
+	a.warnMissing(characters.CharacterBatch)
+
+	return a.print(a.stdout, characters, charactersTable(characters.Characters))
+}
+
+func (a *app) search(ctx context.Context, args []string) error {
+	flags := a.flags("search TEXT")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	text := strings.ToLower(strings.Join(flags.Args(), " "))
+	if text == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	found := []domain.Character{}
+	err := a.each(ctx, func(characters []domain.Character) {
+		for _, character := range characters {
+			if strings.Contains(strings.ToLower(character.Name), text) {
+				found = append(found, character)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	sortByID(found)
+
+	return a.print(a.stdout, found, charactersTable(found))
+}
+
+func (a *app) export(ctx context.Context, args []string) error {
+	flags := a.flags("export [--file PATH]")
+	file := flags.String("file", "", "file to write to rather than stdout")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+
+	all := []domain.Character{}
+	err := a.each(ctx, func(characters []domain.Character) {
+		all = append(all, characters...)
+	})
+	if err != nil {
+		return err
+	}
+	sortByID(all)
+
+	if *file == "" {
+		return a.print(a.stdout, all, charactersTable(all))
+	}
+
+	f, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if err := a.print(f, all, charactersTable(all)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "Exported %d characters to %s\n", len(all), *file)
+
+	return nil
+}
+
+// warmup caches pages that are not cached yet. Through the API, it asks the
+// server for them, which fetches them in the background.
+func (a *app) warmup(ctx context.Context, args []string) error {
+	flags := a.flags("warmup [--from N] [--to N]")
+	from := flags.Int("from", 1, "first page")
+	to := flags.Int("to", 15, "last page")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if a.redis != nil {
+		if err := a.requireWriter("warmup"); err != nil {
+			return err
+		}
+	}
+
+	results := []result{}
+	for page := *from; page <= *to; page++ {
+		outcome, err := a.warmPage(ctx, page)
+		if err != nil {
+			return err
+		}
+		results = append(results, result{Kind: "page", ID: page, Result: outcome})
+	}
+
+	return a.print(a.stdout, results, resultsTable(results))
+}
+
+func (a *app) warmPage(ctx context.Context, page int) (string, error) {
+	if a.redis == nil {
+		_, err := a.source.Fetch(ctx, page)
+		switch domain.CodeOf(err) {
+		case domain.CodeNotCached:
+			return "scheduled", nil
+		case domain.CodeNotFound:
+			return "not found", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return "already cached", nil
+	}
+
+	exists, err := a.redis.Exists(ctx, a.keys.Page(page)).Result()
+	if err != nil {
+		return "", err
+	}
+	if exists == 1 {
+		return "already cached", nil
+	}
+
+	return outcomeOf(a.writer.StoreByPage(ctx, page))
+}
+
+// sync drops the cached characters and pages, with the characters of the
+// pages, then fetches them again. A failed fetch leaves them uncached until
+// the next request.
+func (a *app) sync(ctx context.Context, args []string) error {
+	flags := a.flags("sync [--page N]... [ID...]")
+	pages := flags.IntSlice("page", nil, "page to refetch with its characters, repeatable")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(flags.Args())
+	if err != nil || (len(ids) == 0 && len(*pages) == 0) {
+		flags.Usage()
+		return errUsage
+	}
+	if err := a.requireWriter("sync"); err != nil {
+		return err
+	}
+
+	results := []result{}
+	for _, page := range *pages {
+		keys := []string{a.keys.Page(page)}
+		if cached, err := a.source.Fetch(ctx, page); err == nil {
+			for _, id := range cached {
+				keys = append(keys, a.keys.Character(id))
+			}
+		}
+		if err := a.redis.Del(ctx, keys...).Err(); err != nil {
+			return err
+		}
+
+		outcome, err := outcomeOf(a.writer.StoreByPage(ctx, page))
+		if err != nil {
+			return err
+		}
+		results = append(results, result{Kind: "page", ID: page, Result: outcome})
+	}
+	for _, id := range ids {
+		if err := a.redis.Del(ctx, a.keys.Character(id)).Err(); err != nil {
+			return err
+		}
+
+		outcome, err := outcomeOf(a.writer.StoreByID(ctx, id))
+		if err != nil {
+			return err
+		}
+		results = append(results, result{Kind: "character", ID: id, Result: outcome})
+	}
+
+	return a.print(a.stdout, results, resultsTable(results))
+}
+
+func outcomeOf(err error) (string, error) {
+	switch {
+	case err == nil:
+		return "cached", nil
+	case errors.Is(err, domain.ErrNotFound):
+		return "not found", nil
+	case errors.Is(err, domain.ErrCacheKeyExists):
+		return "already cached", nil
+	default:
+		return "", err
+	}
+}
+
+// page returns the characters of page, like ?expand=characters.
+func (a *app) page(ctx context.Context, page int) (domain.CharacterPage, error) {
+	ids, err := a.source.Fetch(ctx, page)
+	if err != nil {
+		return domain.CharacterPage{}, err
+	}
+
+	batch, err := a.getByIDs(ctx, ids)
+	if err != nil {
+		return domain.CharacterPage{}, err
+	}
+
+	return domain.CharacterPage{Page: page, CharacterBatch: batch, Partial: len(batch.Missing) > 0}, nil
+}
+
+// getByIDs looks the characters up in batches the API accepts.
+func (a *app) getByIDs(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	all := domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{}, NotFound: []int{}}
+	for start := 0; start < len(ids); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		batch, err := a.source.GetByIDs(ctx, ids[start:end])
+		if err != nil {
+			return domain.CharacterBatch{}, err
+		}
+		all.Characters = append(all.Characters, batch.Characters...)
+		all.Missing = append(all.Missing, batch.Missing...)
+		all.NotFound = append(all.NotFound, batch.NotFound...)
+	}
+
+	return all, nil
+}
+
+// each calls fn with every cached character, batch by batch. Directly, it
+// scans the character keys. Through the API, it walks the pages from the
+// first one and stops at the first page that is not cached.
+func (a *app) each(ctx context.Context, fn func([]domain.Character)) error {
+	if a.redis != nil {
+		return scan(ctx, a.redis, a.keys.CharacterPrefix()+"*", func(keys []string) error {
+			ids := make([]int, 0, len(keys))
+			for _, key := range keys {
+				if _, id, ok := a.keys.Parse(key); ok {
+					ids = append(ids, id)
+				}
+			}
+
+			batch, err := a.source.GetByIDs(ctx, ids)
+			if err != nil {
+				return err
+			}
+			fn(batch.Characters)
+			return nil
+		})
+	}
+
+	for page := 1; ; page++ {
+		characters, err := a.page(ctx, page)
+		switch domain.CodeOf(err) {
+		case domain.CodeNotFound:
+			return nil
+		case domain.CodeNotCached:
+			fmt.Fprintf(a.stderr, "Page %d is not cached yet, stopping there\n", page)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(characters.Characters)
+	}
+}
+
+func (a *app) warnMissing(batch domain.CharacterBatch) {
+	if len(batch.Missing) > 0 {
+		fmt.Fprintf(a.stderr, "Not cached yet: %s\n", joinInts(batch.Missing))
+	}
+	if len(batch.NotFound) > 0 {
+		fmt.Fprintf(a.stderr, "Not found: %s\n", joinInts(batch.NotFound))
+	}
+}
+
+// flags returns the flag set of a command, whose usage prints line.
+func (a *app) flags(line string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(strings.Fields(line)[0], pflag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: marvelctl %s\n", line)
+		if flags.HasFlags() {
+			fmt.Fprint(a.stderr, "\nFlags:\n")
+			flags.PrintDefaults()
+		}
+	}
+
+	return flags
+}
+
+func parse(flags *pflag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	return nil
+}
+
+func parseIDs(args []string) ([]int, error) {
+	ids := make([]int, 0, len(args))
+	for _, arg := range args {
+		for _, part := range strings.Split(arg, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func sortByID(characters []domain.Character) {
+	sort.Slice(characters, func(i, j int) bool { return characters[i].ID < characters[j].ID })
+}
+
+func joinInts(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func idsTable(ids []int) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID")
+		for _, id := range ids {
+			fmt.Fprintln(w, id)
+		}
+	}
+}
+
+func resultsTable(results []result) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "KIND\tID\tRESULT")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", r.Kind, r.ID, r.Result)
+		}
+	}
+}
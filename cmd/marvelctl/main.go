@@ -0,0 +1,235 @@
+// Command marvelctl queries the cached characters and manages the cache,
+// either through the HTTP API of a running server or directly in Redis with
+// the repositories of the server.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/spf13/pflag"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/config"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	characterRepository "github.com/hezbymuhammad/golang-marvel-demo/model/character/repository"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/client"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+)
+
+const usage = `Usage: marvelctl [flags] <command> [args]
+
+Commands:
+  get ID...         show characters
+  list              show the character IDs of a page, or the characters with --expand
+  search TEXT       find the cached characters whose name contains TEXT
+  export            dump every cached character
+  warmup            cache pages from the Marvel API
+  sync              refetch characters or pages from the Marvel API (--direct only)
+  cache keys        list cache keys with their TTL (--direct only)
+  cache show KEY    show a raw cache entry (--direct only)
+  cache purge       delete cache keys (--direct only)
+  cache migrate     upgrade cache entries of older schema versions (--direct only)
+  cache export      write the cached characters and pages to a snapshot (--direct only)
+  cache import      write a snapshot to the cache (--direct only)
+
+Run marvelctl <command> --help for the flags of a command.
+
+Flags:
+`
+
+// errUsage reports a malformed command line, whose usage was printed.
+var errUsage = errors.New("usage")
+
+// app holds what the commands work with. Source reads through the HTTP API,
+// or Redis with --direct, which also sets redis, keys, encoder and writer.
+type app struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	format     string
+	source     domain.CharacterReadRepository
+	redis      redis.Cmdable
+	keys       cachekey.Builder
+	encoder    *codec.Encoder
+	encoderErr error
+	writer     *characterRepository.CharacterWriteRepository
+	writeErr   error
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := pflag.NewFlagSet("marvelctl", pflag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	server := flags.String("server", envOr("MARVELCTL_SERVER", "http://localhost:8080"), "base url of the server, also read from MARVELCTL_SERVER")
+	apiKey := flags.String("api-key", os.Getenv("MARVELCTL_API_KEY"), "API key sent to the server, also read from MARVELCTL_API_KEY")
+	direct := flags.Bool("direct", false, "work on Redis directly, configured like the server by --config and the same environment variables")
+	configFile := flags.String("config", "config/common.json", "server config file used with --direct")
+	format := flags.StringP("output", "o", "table", "output format: table, json or yaml")
+	timeout := flags.Duration("timeout", time.Minute, "deadline of the whole command")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	if *format != "table" && *format != "json" && *format != "yaml" {
+		fmt.Fprintln(stderr, "--output must be table, json or yaml")
+		return exitUsage
+	}
+
+	a := &app{stdout: stdout, stderr: stderr, format: *format}
+	if *direct {
+		redisConn, err := a.connect(*configFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		defer redisConn.Close()
+	} else {
+		c := client.New(*server, nil)
+		c.APIKey = *apiKey
+		a.source = c
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	err := a.dispatch(ctx, flags.Arg(0), flags.Args()[1:])
+	switch {
+	case err == nil, errors.Is(err, pflag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		fmt.Fprintln(stderr, "Error:", err)
+		return exitFailure
+	}
+}
+
+// connect loads the server configuration and opens the repositories on its
+// Redis. The Marvel API settings are only needed by the commands writing to
+// the cache, which fail with writeErr when they are invalid.
+func (a *app) connect(configFile string) (*redis.Client, error) {
+	loader := config.NewLoader("marvelctl")
+	if err := loader.Parse([]string{"--config", configFile}); err != nil {
+		return nil, err
+	}
+
+	cfg, err := loader.Load()
+	var invalid config.ValidationError
+	if err != nil && !errors.As(err, &invalid) {
+		return nil, err
+	}
+	a.writeErr = err
+
+	logs := logger.New(a.stderr, "text")
+	_ = logs.SetLevels("warn", nil)
+
+	redisConn := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
+	})
+	a.redis = redisConn
+	a.keys = cachekey.New(cfg.Redis.KeyPrefix).WithLayout(cachekey.Layout(cfg.Redis.Layout))
+	// Invalid codec settings are also in writeErr, only writing needs them.
+	encoder, encoderErr := codec.New(cfg.Codec.Format, cfg.Codec.Compression, cfg.Codec.CompressionThreshold)
+	a.encoder, a.encoderErr = encoder, encoderErr
+	a.source = characterRepository.NewCharacterReadRepository(redisConn, a.keys, logs.For("read_repository"))
+	if a.keys.Layout() == cachekey.LayoutHash {
+		a.source = characterRepository.NewCharacterHashReadRepository(redisConn, a.keys, logs.For("read_repository"))
+	}
+	a.writer = characterRepository.NewCharacterWriteRepository(
+		cfg.MarvelAPI.URL,
+		cfg.MarvelAPI.PublicKey,
+		cfg.MarvelAPI.PrivateKey,
+		redisConn,
+		a.keys,
+		encoder,
+		cfg.MarvelAPI.Timeout(),
+		cfg.CacheExpiration(),
+		cfg.NotFoundExpiration(),
+		logs.For("write_repository"),
+	)
+	a.writer.SetCacheExpirationJitter(cfg.CacheExpirationJitter)
+
+	return redisConn, nil
+}
+
+func (a *app) dispatch(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "get":
+		return a.get(ctx, args)
+	case "list":
+		return a.list(ctx, args)
+	case "search":
+		return a.search(ctx, args)
+	case "export":
+		return a.export(ctx, args)
+	case "warmup":
+		return a.warmup(ctx, args)
+	case "sync":
+		return a.sync(ctx, args)
+	case "cache":
+		return a.cache(ctx, args)
+	default:
+		fmt.Fprintf(a.stderr, "Unknown command %q\n\n", command)
+		fmt.Fprint(a.stderr, usage)
+		return errUsage
+	}
+}
+
+// requireDirect fails commands that the HTTP API does not serve.
+func (a *app) requireDirect(command string) error {
+	if a.redis == nil {
+		return fmt.Errorf("%s needs --direct, the HTTP API does not serve it", command)
+	}
+
+	return nil
+}
+
+// requireWriter fails commands fetching from the Marvel API when the server
+// configuration cannot do it.
+func (a *app) requireWriter(command string) error {
+	if err := a.requireDirect(command); err != nil {
+		return err
+	}
+	if a.writeErr != nil {
+		return fmt.Errorf("%s needs a valid server configuration: %w", command, a.writeErr)
+	}
+
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	characterHttp "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/http"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type MarvelctlTestSuite struct {
+	suite.Suite
+	mr      *miniredis.Miniredis
+	marvel  *httptest.Server
+	server  *httptest.Server
+	usecase *mocks.CharacterUsecase
+	config  string
+}
+
+func TestMarvelctl(t *testing.T) {
+	suite.Run(t, new(MarvelctlTestSuite))
+}
+
+func (s *MarvelctlTestSuite) SetupTest() {
+	mr, err := miniredis.Run()
+	s.Require().NoError(err)
+	s.mr = mr
+	s.mr.Set("marvel-v2-character-id-1", `{"id":1,"name":"Hulk","description":"Green","fetchedAt":"2021-07-21T10:08:56Z"}`)
+	s.mr.Set("marvel-v2-character-id-2", `{"id":2,"name":"Spider-Man","description":"","fetchedAt":"2021-07-21T10:08:56Z"}`)
+	s.mr.Set("marvel-v2-character-id-3", "")
+	s.mr.Set("marvel-v2-characters-page-1", "[1,2]")
+	s.mr.SetTTL("marvel-v2-character-id-1", time.Hour)
+
+	s.marvel = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"results": [{"id": 4, "name": "Thor", "description": "Hammer"}]}}`)
+	}))
+
+	s.usecase = new(mocks.CharacterUsecase)
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	characterHttp.NewCharacterHandler(e, s.usecase, logger.Discard())
+	s.server = httptest.NewServer(e)
+
+	host, port, _ := net.SplitHostPort(s.mr.Addr())
+	s.config = filepath.Join(s.T().TempDir(), "config.json")
+	config := fmt.Sprintf(`{"marvel_api": {"url": %q, "public_key": "public"}, "redis": {"host": %q, "port": %q}}`, s.marvel.URL, host, port)
+	s.Require().NoError(os.WriteFile(s.config, []byte(config), 0o600))
+	s.T().Setenv("MARVEL_PRIVATE_KEY", "private")
+}
+
+func (s *MarvelctlTestSuite) TearDownTest() {
+	s.mr.Close()
+	s.marvel.Close()
+	s.server.Close()
+}
+
+func (s *MarvelctlTestSuite) direct(args ...string) (int, string, string) {
+	return s.run(append([]string{"--direct", "--config", s.config}, args...)...)
+}
+
+func (s *MarvelctlTestSuite) http(args ...string) (int, string, string) {
+	return s.run(append([]string{"--server", s.server.URL}, args...)...)
+}
+
+func (s *MarvelctlTestSuite) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func (s *MarvelctlTestSuite) TestGetDirect() {
+	code, out, _ := s.direct("-o", "json", "get", "1")
+	s.Require().Equal(exitOK, code)
+	s.Assert().JSONEq(`{"id":1,"name":"Hulk","description":"Green","fetchedAt":"2021-07-21T10:08:56Z"}`, out)
+
+	code, out, errOut := s.direct("get", "1,2", "3", "5")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, "Hulk")
+	s.Assert().Contains(out, "Spider-Man")
+	s.Assert().Contains(errOut, "Not cached yet: 5")
+	s.Assert().Contains(errOut, "Not found: 3")
+
+	code, _, errOut = s.direct("get", "5")
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().Contains(errOut, "Resource not cached yet")
+}
+
+func (s *MarvelctlTestSuite) TestGetHTTP() {
+	s.usecase.On("GetByID", mock.Anything, 1).Return(domain.Character{ID: 1, Name: "Hulk"}, nil).Once()
+
+	code, out, _ := s.http("-o", "yaml", "get", "1")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, "name: Hulk")
+}
+
+func (s *MarvelctlTestSuite) TestListHTTP() {
+	batch := domain.CharacterBatch{Characters: []domain.Character{{ID: 1, Name: "Hulk"}}, Missing: []int{2}}
+	s.usecase.On("Fetch", mock.Anything, 2).Return([]int{1, 2}, nil).Twice()
+	s.usecase.On("GetByIDs", mock.Anything, []int{1, 2}).Return(batch, nil).Once()
+
+	code, out, _ := s.http("-o", "json", "list", "--page", "2")
+	s.Require().Equal(exitOK, code)
+	s.Assert().JSONEq(`[1,2]`, out)
+
+	code, out, errOut := s.http("list", "--page", "2", "--expand")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, "Hulk")
+	s.Assert().Contains(errOut, "Not cached yet: 2")
+}
+
+func (s *MarvelctlTestSuite) TestSearch() {
+	code, out, _ := s.direct("-o", "json", "search", "SPIDER")
+	s.Require().Equal(exitOK, code)
+
+	var found []domain.Character
+	s.Require().NoError(json.Unmarshal([]byte(out), &found))
+	s.Require().Len(found, 1)
+	s.Assert().Equal("Spider-Man", found[0].Name)
+
+	batch := domain.CharacterBatch{Characters: []domain.Character{{ID: 1, Name: "Hulk"}}, Missing: []int{}}
+	s.usecase.On("Fetch", mock.Anything, 1).Return([]int{1}, nil).Once()
+	s.usecase.On("GetByIDs", mock.Anything, []int{1}).Return(batch, nil).Once()
+	s.usecase.On("Fetch", mock.Anything, 2).Return(nil, domain.ErrNotFound).Once()
+
+	code, out, _ = s.http("search", "hulk")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, "Hulk")
+}
+
+func (s *MarvelctlTestSuite) TestExport() {
+	file := filepath.Join(s.T().TempDir(), "characters.json")
+	code, _, errOut := s.direct("-o", "json", "export", "--file", file)
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(errOut, "Exported 2 characters")
+
+	data, err := os.ReadFile(file)
+	s.Require().NoError(err)
+	var all []domain.Character
+	s.Require().NoError(json.Unmarshal(data, &all))
+	s.Require().Len(all, 2)
+	s.Assert().Equal(uint(1), all[0].ID)
+}
+
+func (s *MarvelctlTestSuite) TestWarmupDirect() {
+	code, out, _ := s.direct("-o", "json", "warmup", "--from", "1", "--to", "2")
+	s.Require().Equal(exitOK, code)
+	s.Assert().JSONEq(`[{"kind":"page","id":1,"result":"already cached"},{"kind":"page","id":2,"result":"cached"}]`, out)
+	s.Assert().True(s.mr.Exists("marvel-v2-characters-page-2"))
+	s.Assert().True(s.mr.Exists("marvel-v2-character-id-4"))
+}
+
+func (s *MarvelctlTestSuite) TestSyncDirect() {
+	code, out, _ := s.direct("-o", "json", "sync", "1")
+	s.Require().Equal(exitOK, code)
+	s.Assert().JSONEq(`[{"kind":"character","id":1,"result":"cached"}]`, out)
+	s.Assert().False(s.mr.Exists("marvel-v2-character-id-1"))
+	s.Assert().True(s.mr.Exists("marvel-v2-character-id-4"))
+}
+
+func (s *MarvelctlTestSuite) TestFailedSyncHTTP() {
+	code, _, errOut := s.http("sync", "1")
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().Contains(errOut, "needs --direct")
+}
+
+func (s *MarvelctlTestSuite) TestCacheKeys() {
+	code, out, _ := s.direct("-o", "json", "cache", "keys", "--match", "marvel-v2-character-id-*")
+	s.Require().Equal(exitOK, code)
+
+	var entries []entry
+	s.Require().NoError(json.Unmarshal([]byte(out), &entries))
+	s.Require().Len(entries, 3)
+	s.Assert().Equal("marvel-v2-character-id-1", entries[0].Key)
+	s.Assert().Equal(int64(3600), entries[0].TTL)
+	s.Require().NotNil(entries[0].FetchedAt)
+	s.Assert().Equal(int64(-1), entries[1].TTL)
+	s.Assert().Equal(0, entries[2].Size)
+	s.Assert().Nil(entries[2].FetchedAt)
+}
+
+func (s *MarvelctlTestSuite) TestCacheShow() {
+	code, out, _ := s.direct("cache", "show", "marvel-v2-characters-page-1")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, "[1,2]")
+
+	code, _, errOut := s.direct("cache", "show", "marvel-v2-characters-page-9")
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().Contains(errOut, "is not cached")
+}
+
+func (s *MarvelctlTestSuite) TestCacheHashLayout() {
+	s.T().Setenv("REDIS_LAYOUT", "hash")
+	s.mr.HSet("marvel-v2-hash-character-id-1", "id", "1")
+	s.mr.HSet("marvel-v2-hash-character-id-1", "name", "Hulk")
+	s.mr.ZAdd("marvel-v2-hash-characters-by-name", 10, "1")
+
+	code, out, _ := s.direct("-o", "json", "cache", "keys")
+	s.Require().Equal(exitOK, code)
+	var entries []entry
+	s.Require().NoError(json.Unmarshal([]byte(out), &entries))
+	s.Require().Len(entries, 6)
+
+	code, out, _ = s.direct("-o", "json", "cache", "show", "marvel-v2-hash-character-id-1")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, `"encoding": "hash"`)
+	s.Assert().Contains(out, `"name": "Hulk"`)
+
+	code, out, _ = s.direct("cache", "show", "marvel-v2-hash-characters-by-name")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, `[{"member":"1","score":10}]`)
+
+	code, _, errOut := s.direct("cache", "migrate")
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().Contains(errOut, "only upgrades to the string layout")
+
+	s.mr.ZAdd("marvel-v2-hash-characters-by-modified", 10, "1")
+	code, _, _ = s.direct("cache", "purge", "marvel-v2-hash-character-id-1", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().False(s.mr.Exists("marvel-v2-hash-characters-by-modified"))
+	s.Assert().True(s.mr.Exists("marvel-v2-hash-characters-by-name"))
+}
+
+func (s *MarvelctlTestSuite) TestCachePurge() {
+	code, out, errOut := s.direct("cache", "purge", "--match", "marvel-v2-character-id-*")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, "marvel-v2-character-id-3")
+	s.Assert().Contains(errOut, "Would delete 3 keys")
+	s.Assert().True(s.mr.Exists("marvel-v2-character-id-1"))
+
+	code, _, errOut = s.direct("cache", "purge", "--match", "marvel-v2-character-id-*", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(errOut, "Deleted 3 keys")
+	s.Assert().False(s.mr.Exists("marvel-v2-character-id-1"))
+	s.Assert().True(s.mr.Exists("marvel-v2-characters-page-1"))
+
+	code, _, _ = s.direct("cache", "purge", "--match", "*", "--yes")
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().True(s.mr.Exists("marvel-v2-characters-page-1"))
+
+	s.mr.Set("other-app-key", "lorem")
+	code, _, errOut = s.direct("cache", "purge", "marvel-v2-characters-page-1", "other-app-key", "--yes")
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().Contains(errOut, "other-app-key does not start with marvel-")
+	s.Assert().True(s.mr.Exists("other-app-key"))
+	s.Assert().True(s.mr.Exists("marvel-v2-characters-page-1"))
+
+	code, _, _ = s.direct("cache", "purge", "marvel-v2-characters-page-1", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().False(s.mr.Exists("marvel-v2-characters-page-1"))
+
+	s.mr.Set("marvel-auth-apikey-id-lorem", "{}")
+	s.mr.SetAdd("marvel-auth-apikeys", "lorem")
+	code, _, errOut = s.direct("cache", "purge", "marvel-auth-apikey-id-lorem", "--yes")
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().Contains(errOut, "holds API keys or rate limits")
+
+	code, _, errOut = s.direct("cache", "purge", "--match", "marvel-*", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(errOut, "Deleted 0 keys")
+	s.Assert().True(s.mr.Exists("marvel-auth-apikey-id-lorem"))
+	s.Assert().True(s.mr.Exists("marvel-auth-apikeys"))
+}
+
+func (s *MarvelctlTestSuite) TestUsage() {
+	code, _, _ := s.run()
+	s.Assert().Equal(exitUsage, code)
+
+	code, _, errOut := s.run("frobnicate")
+	s.Assert().Equal(exitUsage, code)
+	s.Assert().Contains(errOut, "Unknown command")
+
+	code, _, _ = s.run("get", "lorem")
+	s.Assert().Equal(exitUsage, code)
+
+	code, _, _ = s.run("-o", "xml", "get", "1")
+	s.Assert().Equal(exitUsage, code)
+}
+
+func (s *MarvelctlTestSuite) TestCacheMigrate() {
+	s.mr.Set("marvel-character-id-5", `{"id":5,"name":"Thor","description":"","fetchedAt":"2021-07-21T10:08:56Z"}`)
+	s.mr.SetTTL("marvel-character-id-5", time.Hour)
+	s.mr.Set("marvel-character-id-1", `{"id":1,"name":"Old Hulk"}`)
+	s.mr.Set("marvel-character-id-6", "lorem")
+	s.mr.Set("marvel-characters-page-2", "[5]")
+
+	code, out, errOut := s.direct("-o", "json", "cache", "migrate")
+	s.Require().Equal(exitOK, code)
+	s.Assert().JSONEq(`[
+		{"key":"marvel-character-id-1","to":"marvel-v2-character-id-1","result":"upgraded"},
+		{"key":"marvel-character-id-5","to":"marvel-v2-character-id-5","result":"upgraded"},
+		{"key":"marvel-character-id-6","result":"dropped"},
+		{"key":"marvel-characters-page-2","to":"marvel-v2-characters-page-2","result":"upgraded"}
+	]`, out)
+	s.Assert().Contains(errOut, "Would migrate 4 keys to schema version 2")
+	s.Assert().True(s.mr.Exists("marvel-character-id-6"))
+
+	code, out, _ = s.direct("-o", "json", "cache", "migrate", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, `"result": "kept newer"`)
+	for _, key := range []string{"marvel-character-id-1", "marvel-character-id-5", "marvel-character-id-6", "marvel-characters-page-2"} {
+		s.Assert().False(s.mr.Exists(key), key)
+	}
+	thor, _ := s.mr.Get("marvel-v2-character-id-5")
+	s.Assert().Contains(thor, "Thor")
+	s.Assert().InDelta(time.Hour, s.mr.TTL("marvel-v2-character-id-5"), float64(time.Second))
+	hulk, _ := s.mr.Get("marvel-v2-character-id-1")
+	s.Assert().Contains(hulk, `"name":"Hulk"`)
+	s.Assert().False(s.mr.Exists("marvel-v2-character-id-6"))
+	page, _ := s.mr.Get("marvel-v2-characters-page-2")
+	s.Assert().Equal("[5]", page)
+
+	code, out, _ = s.direct("-o", "json", "cache", "migrate", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().JSONEq(`[]`, out)
+}
+
+func (s *MarvelctlTestSuite) TestCacheMigrateDrop() {
+	s.mr.Set("marvel-character-id-5", `{"id":5,"name":"Thor"}`)
+
+	code, _, errOut := s.direct("cache", "migrate", "--drop", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(errOut, "Migrated 1 keys")
+	s.Assert().False(s.mr.Exists("marvel-character-id-5"))
+	s.Assert().False(s.mr.Exists("marvel-v2-character-id-5"))
+}
+
+func (s *MarvelctlTestSuite) TestCacheMigratePrefix() {
+	s.T().Setenv("REDIS_KEY_PREFIX", "staging-")
+	s.mr.Set("marvel-character-id-5", `{"id":5,"name":"Thor"}`)
+	s.mr.Set("marvel-characters-page-2", "[5]")
+
+	code, out, _ := s.direct("-o", "json", "cache", "migrate", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().JSONEq(`[
+		{"key":"marvel-character-id-5","to":"staging-v2-character-id-5","result":"upgraded"},
+		{"key":"marvel-characters-page-2","to":"staging-v2-characters-page-2","result":"upgraded"}
+	]`, out)
+	s.Assert().False(s.mr.Exists("marvel-character-id-5"))
+	s.Assert().True(s.mr.Exists("staging-v2-character-id-5"))
+	s.Assert().True(s.mr.Exists("marvel-v2-character-id-1"))
+}
+
+func (s *MarvelctlTestSuite) TestCacheSnapshotProtobuf() {
+	s.T().Setenv("CODEC_FORMAT", "protobuf")
+	file := filepath.Join(s.T().TempDir(), "snapshot.ndjson.gz")
+	code, _, errOut := s.direct("cache", "export", "--file", file)
+	s.Require().Equal(exitOK, code, errOut)
+
+	s.mr.FlushAll()
+	code, _, errOut = s.direct("cache", "import", "--file", file, "--yes")
+	s.Require().Equal(exitOK, code, errOut)
+	s.Assert().Contains(errOut, "Wrote 4 of 4 records")
+
+	hulk, _ := s.mr.Get("marvel-v2-character-id-1")
+	s.Assert().Equal("protobuf", codec.Describe([]byte(hulk)))
+	var character domain.Character
+	s.Require().NoError(codec.Decode([]byte(hulk), &character))
+	s.Assert().Equal("Hulk", character.Name)
+
+	code, out, errOut := s.direct("-o", "json", "cache", "import", "--file", file)
+	s.Require().Equal(exitOK, code, errOut)
+	s.Assert().NotContains(out, `"added"`)
+	s.Assert().Contains(out, `"unchanged"`)
+}
+
+func (s *MarvelctlTestSuite) TestCacheSnapshot() {
+	file := filepath.Join(s.T().TempDir(), "snapshot.ndjson.gz")
+	code, _, errOut := s.direct("cache", "export", "--file", file)
+	s.Require().Equal(exitOK, code, errOut)
+	s.Assert().Contains(errOut, "Exported 4 records")
+
+	f, err := os.Open(file)
+	s.Require().NoError(err)
+	m, records, err := readSnapshot(f)
+	f.Close()
+	s.Require().NoError(err)
+	s.Assert().Equal(snapshotFormat, m.Format)
+	s.Assert().Equal("string", m.Layout)
+	s.Require().Len(records, 4)
+	s.Assert().Equal("Hulk", records[0].Character.Name)
+	s.Assert().NotNil(records[0].ExpiresAt)
+	s.Assert().Equal(etag(*records[0].Character), records[0].ETag)
+	s.Assert().Nil(records[1].ExpiresAt)
+	s.Assert().True(records[2].Tombstone)
+	s.Assert().Equal([]int{1, 2}, records[3].IDs)
+
+	s.mr.FlushAll()
+	s.mr.Set("marvel-v2-character-id-2", `{"id":2,"name":"Peter Parker","description":"","fetchedAt":"2021-07-21T10:08:56Z"}`)
+
+	code, out, errOut := s.direct("-o", "json", "cache", "import", "--file", file)
+	s.Require().Equal(exitOK, code, errOut)
+	s.Assert().JSONEq(`[
+		{"key":"marvel-v2-character-id-1","result":"added"},
+		{"key":"marvel-v2-character-id-2","result":"kept"},
+		{"key":"marvel-v2-character-id-3","result":"added"},
+		{"key":"marvel-v2-characters-page-1","result":"added"}
+	]`, out)
+	s.Assert().Contains(errOut, "Would write 3 of 4 records")
+	s.Assert().False(s.mr.Exists("marvel-v2-character-id-1"))
+
+	code, out, _ = s.direct("-o", "json", "cache", "import", "--file", file, "--overwrite", "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(out, `"result": "overwritten"`)
+	hulk, _ := s.mr.Get("marvel-v2-character-id-1")
+	s.Assert().Contains(hulk, `"name":"Hulk"`)
+	s.Assert().InDelta(time.Hour, s.mr.TTL("marvel-v2-character-id-1"), float64(time.Minute))
+	spiderMan, _ := s.mr.Get("marvel-v2-character-id-2")
+	s.Assert().Contains(spiderMan, `"name":"Spider-Man"`)
+	s.Assert().Zero(s.mr.TTL("marvel-v2-character-id-2"))
+	tombstone, _ := s.mr.Get("marvel-v2-character-id-3")
+	s.Assert().Empty(tombstone)
+	page, _ := s.mr.Get("marvel-v2-characters-page-1")
+	s.Assert().Equal("[1,2]", page)
+
+	code, _, errOut = s.direct("cache", "import", "--file", file, "--yes")
+	s.Require().Equal(exitOK, code)
+	s.Assert().Contains(errOut, "Wrote 0 of 4 records")
+}
+
+func (s *MarvelctlTestSuite) TestFailedCacheImport() {
+	file := filepath.Join(s.T().TempDir(), "snapshot.ndjson.gz")
+	f, err := os.Create(file)
+	s.Require().NoError(err)
+	zw := gzip.NewWriter(f)
+	fmt.Fprintf(zw, `{"format":%q,"version":1,"schemaVersion":2}`+"\n", snapshotFormat)
+	fmt.Fprintln(zw, `{"kind":"character","id":5,"character":{"id":5,"name":"Thor"},"etag":"\"lorem\""}`)
+	s.Require().NoError(zw.Close())
+	s.Require().NoError(f.Close())
+
+	code, _, errOut := s.direct("cache", "import", "--file", file, "--yes")
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().Contains(errOut, "snapshot is truncated")
+	s.Assert().False(s.mr.Exists("marvel-v2-character-id-5"))
+
+	code, _, errOut = s.direct("cache", "import", "--file", s.config)
+	s.Assert().Equal(exitFailure, code)
+	s.Assert().Contains(errOut, "not a snapshot")
+}
+
+func (s *MarvelctlTestSuite) TestCacheSnapshotHashLayout() {
+	file := filepath.Join(s.T().TempDir(), "snapshot.ndjson.gz")
+	code, _, errOut := s.direct("cache", "export", "--file", file)
+	s.Require().Equal(exitOK, code, errOut)
+
+	s.T().Setenv("REDIS_LAYOUT", "hash")
+	s.mr.HSet("marvel-v2-hash-character-id-2", "id", "2")
+	s.mr.HSet("marvel-v2-hash-character-id-2", "name", "Peter Parker")
+	code, out, errOut := s.direct("-o", "json", "cache", "import", "--file", file, "--yes")
+	s.Require().Equal(exitOK, code, errOut)
+	s.Assert().JSONEq(`[
+		{"key":"marvel-v2-hash-character-id-1","result":"added"},
+		{"key":"marvel-v2-hash-character-id-2","result":"kept"},
+		{"key":"marvel-v2-hash-character-id-3","result":"added"},
+		{"key":"marvel-v2-hash-characters-page-1","result":"added"}
+	]`, out)
+	s.Assert().Equal("Hulk", s.mr.HGet("marvel-v2-hash-character-id-1", "name"))
+	s.Assert().InDelta(time.Hour, s.mr.TTL("marvel-v2-hash-character-id-1"), float64(time.Minute))
+	s.Assert().Equal("1", s.mr.HGet("marvel-v2-hash-character-id-3", "tombstone"))
+	members, _ := s.mr.ZMembers("marvel-v2-hash-characters-by-name")
+	s.Assert().Equal([]string{"1", "2"}, members)
+
+	// The modification dates of the hash layout are exported with it.
+	s.mr.HSet("marvel-v2-hash-character-id-1", "modified", "2014-04-29T18:18:17Z")
+	code, _, errOut = s.direct("cache", "export", "--file", file)
+	s.Require().Equal(exitOK, code, errOut)
+	s.mr.FlushAll()
+	code, _, errOut = s.direct("cache", "import", "--file", file, "--yes")
+	s.Require().Equal(exitOK, code, errOut)
+	s.Assert().Contains(errOut, "Wrote 4 of 4 records")
+	s.Assert().Equal("Peter Parker", s.mr.HGet("marvel-v2-hash-character-id-2", "name"))
+	members, _ = s.mr.ZMembers("marvel-v2-hash-characters-by-modified")
+	s.Assert().Equal([]string{"1"}, members)
+
+	code, _, errOut = s.direct("cache", "import", "--file", file, "--yes")
+	s.Require().Equal(exitOK, code, errOut)
+	s.Assert().Contains(errOut, "Wrote 0 of 4 records")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+)
+
+// migration is the outcome of migrating the entry at Key to the key To.
+type migration struct {
+	Key    string `json:"key"`
+	To     string `json:"to,omitempty"`
+	Result string `json:"result"`
+}
+
+// upgrades convert the values of a schema version to the next one. They fail
+// on values that cannot be upgraded, which are dropped.
+var upgrades = map[int]func(kind, val string) (string, error){
+	// Version 1 lacked the version in its keys, its values are unchanged.
+	1: func(kind, val string) (string, error) {
+		if val == "" {
+			return val, nil
+		}
+
+		var target interface{} = &domain.Character{}
+		if kind == cachekey.KindPage {
+			target = &[]int{}
+		}
+		return val, json.Unmarshal([]byte(val), target)
+	},
+}
+
+// upgrade converts val, cached by version, to the current schema version.
+func upgrade(version int, kind, val string) (string, error) {
+	for v := version; v < cachekey.Version; v++ {
+		fn, ok := upgrades[v]
+		if !ok {
+			return "", fmt.Errorf("no upgrade from schema version %d", v)
+		}
+
+		var err error
+		if val, err = fn(kind, val); err != nil {
+			return "", err
+		}
+	}
+
+	return val, nil
+}
+
+// cacheMigrate moves the entries of older schema versions to the keys of the
+// current one, upgrading their values and keeping their TTL. Entries already
+// cached by the current version are kept. With --drop, or when they cannot
+// be upgraded, old entries are deleted instead, to be fetched again on their
+// next lookup. It only lists what it would do unless --yes is given.
+func (a *app) cacheMigrate(ctx context.Context, args []string) error {
+	flags := a.flags("cache migrate [--drop] [--yes]")
+	drop := flags.Bool("drop", false, "delete the entries of older versions rather than upgrading them")
+	yes := flags.Bool("yes", false, "migrate the entries rather than listing them")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	// Older versions only had the string layout.
+	if a.keys.Layout() != cachekey.LayoutString {
+		return fmt.Errorf("cache migrate only upgrades to the string layout, not %s", a.keys.Layout())
+	}
+
+	migrations := []migration{}
+	for version := 1; version < cachekey.Version; version++ {
+		old := a.keys.WithVersion(version)
+		// Version 1 predates redis.key_prefix, its keys always started
+		// with the default one.
+		if version == 1 {
+			old = cachekey.New(cachekey.DefaultPrefix).WithVersion(version)
+		}
+		for _, prefix := range []string{old.CharacterPrefix(), old.PagePrefix()} {
+			err := scan(ctx, a.redis, prefix+"*", func(keys []string) error {
+				for _, key := range keys {
+					m, err := a.migrate(ctx, old, key, *drop, !*yes)
+					if err != nil {
+						return err
+					}
+					migrations = append(migrations, m)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Key < migrations[j].Key })
+
+	err := a.print(a.stdout, migrations, func(w io.Writer) {
+		fmt.Fprintln(w, "KEY\tTO\tRESULT")
+		for _, m := range migrations {
+			to := m.To
+			if to == "" {
+				to = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", m.Key, to, m.Result)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		fmt.Fprintf(a.stderr, "Would migrate %d keys to schema version %d, run again with --yes to migrate them\n", len(migrations), cachekey.Version)
+	} else {
+		fmt.Fprintf(a.stderr, "Migrated %d keys to schema version %d\n", len(migrations), cachekey.Version)
+	}
+
+	return nil
+}
+
+// migrate moves the entry at key, laid out by old, unless dryRun.
+func (a *app) migrate(ctx context.Context, old cachekey.Builder, key string, drop, dryRun bool) (migration, error) {
+	m := migration{Key: key}
+	kind, id, ok := old.Parse(key)
+	if !ok {
+		m.Result = "skipped"
+		return m, nil
+	}
+
+	pipe := a.redis.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err == redis.Nil {
+		m.Result = "expired"
+		return m, nil
+	} else if err != nil {
+		return m, err
+	}
+	val, ttl := get.Val(), pttl.Val()
+
+	upgraded, err := upgrade(old.Version(), kind, val)
+	if drop || err != nil {
+		m.Result = "dropped"
+		if dryRun {
+			return m, nil
+		}
+		return m, a.redis.Del(ctx, key).Err()
+	}
+
+	m.To = a.keys.Character(id)
+	if kind == cachekey.KindPage {
+		m.To = a.keys.Page(id)
+	}
+	m.Result = "upgraded"
+	if dryRun {
+		return m, nil
+	}
+
+	// Entries without expiry stay so.
+	if ttl < 0 {
+		ttl = 0
+	}
+	set, err := a.redis.SetNX(ctx, m.To, upgraded, ttl).Result()
+	if err != nil {
+		return m, err
+	}
+	if !set {
+		m.Result = "kept newer"
+	}
+
+	return m, a.redis.Del(ctx, key).Err()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+)
+
+// descriptionWidth is where descriptions are cut in tables.
+const descriptionWidth = 60
+
+// print writes v to w in the chosen format. Table output is written by table,
+// whose tab separated columns get aligned.
+func (a *app) print(w io.Writer, v interface{}, table func(io.Writer)) error {
+	switch a.format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		// Going through JSON keeps the field names of the API.
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+}
+
+func charactersTable(characters []domain.Character) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tFETCHED AT\tDESCRIPTION")
+		for _, c := range characters {
+			fetchedAt := "-"
+			if !c.FetchedAt.IsZero() {
+				fetchedAt = fetchedAtString(&c.FetchedAt)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", c.ID, c.Name, fetchedAt, truncate(c.Description, descriptionWidth))
+		}
+	}
+}
+
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	return string([]rune(s)[:width-1]) + "…"
+}
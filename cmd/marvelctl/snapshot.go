@@ -0,0 +1,560 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/httpcache"
+)
+
+// snapshotFormat names the archives of cache export. snapshotVersion is the
+// shape of their lines, bumped when it changes.
+const (
+	snapshotFormat  = "marvel-cache-snapshot"
+	snapshotVersion = 1
+)
+
+// manifest is the first line of a snapshot. Records follow, a line each, then
+// a trailer counting them, which tells a truncated snapshot apart.
+// SchemaVersion is the cachekey.Version of the characters.
+type manifest struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion int       `json:"schemaVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	KeyPrefix     string    `json:"keyPrefix"`
+	Layout        string    `json:"layout"`
+}
+
+// record is a cached character or page, whatever the layout it was cached
+// in. ExpiresAt is nil without expiry. Tombstones have neither character nor
+// IDs. Modified is when Marvel API last modified the character, only known
+// by the hash layout. ETag is what the server answers for the character, to
+// check it was not altered.
+type record struct {
+	Kind      string            `json:"kind"`
+	ID        int               `json:"id"`
+	ExpiresAt *time.Time        `json:"expiresAt,omitempty"`
+	Tombstone bool              `json:"tombstone,omitempty"`
+	Character *domain.Character `json:"character,omitempty"`
+	Modified  *time.Time        `json:"modified,omitempty"`
+	IDs       []int             `json:"ids,omitempty"`
+	ETag      string            `json:"etag,omitempty"`
+}
+
+type trailer struct {
+	Records int `json:"records"`
+}
+
+// snapshotLine reads the records and the trailer alike.
+type snapshotLine struct {
+	record
+	End *trailer `json:"end,omitempty"`
+}
+
+// imported is the outcome of importing a record to Key.
+type imported struct {
+	Key    string `json:"key"`
+	Result string `json:"result"`
+}
+
+// etag is the entity tag GET /characters/:id answers for character.
+func etag(character domain.Character) string {
+	body, _ := json.Marshal(character)
+	return httpcache.ETag(append(body, '\n'))
+}
+
+// cacheExport writes every cached character and page, tombstones included,
+// to a gzipped NDJSON snapshot, whatever the layout. Indexes are rebuilt
+// from them rather than exported.
+func (a *app) cacheExport(ctx context.Context, args []string) error {
+	flags := a.flags("cache export --file PATH")
+	file := flags.String("file", "", "snapshot to write, - for stdout")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *file == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	records := []record{}
+	characters := func(keys []string) error {
+		found, err := a.exportCharacters(ctx, keys)
+		records = append(records, found...)
+		return err
+	}
+	pages := func(keys []string) error {
+		found, err := a.exportPages(ctx, keys)
+		records = append(records, found...)
+		return err
+	}
+	if err := scan(ctx, a.redis, a.keys.CharacterPrefix()+"*", characters); err != nil {
+		return err
+	}
+	if err := scan(ctx, a.redis, a.keys.PagePrefix()+"*", pages); err != nil {
+		return err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Kind != records[j].Kind {
+			return records[i].Kind < records[j].Kind
+		}
+		return records[i].ID < records[j].ID
+	})
+
+	m := manifest{
+		Format:        snapshotFormat,
+		Version:       snapshotVersion,
+		SchemaVersion: a.keys.Version(),
+		CreatedAt:     time.Now().UTC(),
+		KeyPrefix:     a.keys.Prefix(),
+		Layout:        string(a.keys.Layout()),
+	}
+	if *file == "-" {
+		return writeSnapshot(a.stdout, m, records)
+	}
+
+	f, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if err := writeSnapshot(f, m, records); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "Exported %d records to %s\n", len(records), *file)
+
+	return nil
+}
+
+// exportCharacters reads the characters cached at keys through the read
+// repository, and the tombstones among those it misses.
+func (a *app) exportCharacters(ctx context.Context, keys []string) ([]record, error) {
+	expiries, err := a.expiries(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	for _, key := range keys {
+		if _, ok := expiries[key]; !ok {
+			continue
+		}
+		if kind, id, ok := a.keys.Parse(key); ok && kind == cachekey.KindCharacter {
+			ids = append(ids, id)
+		}
+	}
+	batch, err := a.getByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	modified, err := a.modified(ctx, batch.Characters)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]record, 0, len(ids))
+	for i := range batch.Characters {
+		character := batch.Characters[i]
+		records = append(records, record{
+			Kind:      cachekey.KindCharacter,
+			ID:        int(character.ID),
+			ExpiresAt: expiries[a.keys.Character(int(character.ID))],
+			Character: &character,
+			Modified:  modified[character.ID],
+			ETag:      etag(character),
+		})
+	}
+	// The missing ones expired since they were listed.
+	for _, id := range batch.NotFound {
+		records = append(records, record{Kind: cachekey.KindCharacter, ID: id, ExpiresAt: expiries[a.keys.Character(id)], Tombstone: true})
+	}
+
+	return records, nil
+}
+
+// modified tells when Marvel API last modified characters, which only the
+// hashes of the hash layout know. Those it is not known for are left out.
+func (a *app) modified(ctx context.Context, characters []domain.Character) (map[uint]*time.Time, error) {
+	modified := map[uint]*time.Time{}
+	if a.keys.Layout() != cachekey.LayoutHash || len(characters) == 0 {
+		return modified, nil
+	}
+
+	pipe := a.redis.Pipeline()
+	gets := make([]*redis.StringCmd, len(characters))
+	for i, character := range characters {
+		gets[i] = pipe.HGet(ctx, a.keys.Character(int(character.ID)), codec.FieldModified)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	for i, get := range gets {
+		if t, err := time.Parse(time.RFC3339, get.Val()); err == nil {
+			modified[characters[i].ID] = &t
+		}
+	}
+
+	return modified, nil
+}
+
+// exportPages reads the pages cached at keys through the read repository.
+func (a *app) exportPages(ctx context.Context, keys []string) ([]record, error) {
+	expiries, err := a.expiries(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]record, 0, len(keys))
+	for _, key := range keys {
+		kind, page, ok := a.keys.Parse(key)
+		if _, exists := expiries[key]; !exists || !ok || kind != cachekey.KindPage {
+			continue
+		}
+
+		ids, err := a.source.Fetch(ctx, page)
+		switch domain.CodeOf(err) {
+		case domain.CodeNotFound:
+			records = append(records, record{Kind: cachekey.KindPage, ID: page, ExpiresAt: expiries[key], Tombstone: true})
+			continue
+		case domain.CodeNotCached:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record{Kind: cachekey.KindPage, ID: page, ExpiresAt: expiries[key], IDs: ids})
+	}
+
+	return records, nil
+}
+
+// expiries tells when keys expire, nil for those without expiry. Keys that
+// do not exist anymore are left out.
+func (a *app) expiries(ctx context.Context, keys []string) (map[string]*time.Time, error) {
+	pipe := a.redis.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expiries := make(map[string]*time.Time, len(keys))
+	for i, key := range keys {
+		switch ttl := ttls[i].Val(); {
+		case ttl > 0:
+			expiresAt := now.Add(ttl).Truncate(time.Millisecond)
+			expiries[key] = &expiresAt
+		// go-redis reads the -1 of keys without expiry as -1ns.
+		case ttl == -1:
+			expiries[key] = nil
+		}
+	}
+
+	return expiries, nil
+}
+
+func writeSnapshot(w io.Writer, m manifest, records []record) error {
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	if err := enc.Encode(map[string]trailer{"end": {Records: len(records)}}); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// readSnapshot reads a whole snapshot, so that nothing is imported from one
+// that turns out to be truncated or of another version.
+func readSnapshot(r io.Reader) (manifest, []record, error) {
+	var m manifest
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return m, nil, fmt.Errorf("not a snapshot: %w", err)
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	if err := dec.Decode(&m); err != nil {
+		return m, nil, fmt.Errorf("not a snapshot: %w", err)
+	}
+	if m.Format != snapshotFormat || m.Version != snapshotVersion {
+		return m, nil, fmt.Errorf("unsupported snapshot %s version %d, want %s version %d", m.Format, m.Version, snapshotFormat, snapshotVersion)
+	}
+	if m.SchemaVersion != cachekey.Version {
+		return m, nil, fmt.Errorf("snapshot of schema version %d, want %d", m.SchemaVersion, cachekey.Version)
+	}
+
+	records := []record{}
+	for {
+		var line snapshotLine
+		if err := dec.Decode(&line); errors.Is(err, io.EOF) {
+			return m, nil, errors.New("snapshot is truncated, its trailer is missing")
+		} else if err != nil {
+			return m, nil, fmt.Errorf("snapshot is corrupt: %w", err)
+		}
+		if line.End != nil {
+			if line.End.Records != len(records) {
+				return m, nil, fmt.Errorf("snapshot is truncated, it has %d of %d records", len(records), line.End.Records)
+			}
+			return m, records, nil
+		}
+		records = append(records, line.record)
+	}
+}
+
+// cacheImport writes the records of a snapshot to the cache, keeping their
+// expiry unless --expiration is given. Records already cached otherwise are
+// kept, unless --overwrite is given. It only lists what it would do unless
+// --yes is given, which makes a diff of the snapshot and the cache.
+func (a *app) cacheImport(ctx context.Context, args []string) error {
+	flags := a.flags("cache import --file PATH [--overwrite] [--expiration DURATION] [--yes]")
+	file := flags.String("file", "", "snapshot to read, - for stdin")
+	overwrite := flags.Bool("overwrite", false, "replace the entries cached otherwise rather than keeping them")
+	expiration := flags.Duration("expiration", 0, "expiration of the imported entries, rather than their expiry in the snapshot")
+	yes := flags.Bool("yes", false, "import the records rather than listing them")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *file == "" {
+		flags.Usage()
+		return errUsage
+	}
+	if a.encoderErr != nil {
+		return fmt.Errorf("cache import needs valid codec settings: %w", a.encoderErr)
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	m, records, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	results := make([]imported, 0, len(records))
+	written := 0
+	for _, rec := range records {
+		res, err := a.importRecord(ctx, rec, *expiration, *overwrite, !*yes)
+		if err != nil {
+			return err
+		}
+		if res.Result == "added" || res.Result == "overwritten" {
+			written++
+		}
+		results = append(results, res)
+	}
+
+	err = a.print(a.stdout, results, func(w io.Writer) {
+		fmt.Fprintln(w, "KEY\tRESULT")
+		for _, res := range results {
+			fmt.Fprintf(w, "%s\t%s\n", res.Key, res.Result)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		fmt.Fprintf(a.stderr, "Would write %d of %d records of the snapshot of %s, run again with --yes to import them\n", written, len(records), m.CreatedAt.Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(a.stderr, "Wrote %d of %d records of the snapshot of %s\n", written, len(records), m.CreatedAt.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// importRecord writes rec unless dryRun. Its result is "added" when nothing
+// was cached, "unchanged" when the same was, "overwritten" or "kept" when
+// something else was, "expired" when rec expired since its export and
+// "invalid" when its ETag does not match its character. The hash layout is
+// written by importHash.
+func (a *app) importRecord(ctx context.Context, rec record, expiration time.Duration, overwrite, dryRun bool) (imported, error) {
+	res := imported{Key: a.keys.Character(rec.ID)}
+	var value interface{}
+	if rec.Character != nil {
+		value = *rec.Character
+	}
+	if rec.Kind == cachekey.KindPage {
+		res.Key = a.keys.Page(rec.ID)
+		value = rec.IDs
+	}
+
+	switch {
+	case rec.Kind != cachekey.KindCharacter && rec.Kind != cachekey.KindPage,
+		rec.Kind == cachekey.KindCharacter && !rec.Tombstone && (rec.Character == nil || etag(*rec.Character) != rec.ETag):
+		res.Result = "invalid"
+		return res, nil
+	}
+
+	var ttl time.Duration
+	switch {
+	case expiration > 0:
+		ttl = expiration
+	case rec.ExpiresAt != nil:
+		if ttl = time.Until(*rec.ExpiresAt); ttl <= 0 {
+			res.Result = "expired"
+			return res, nil
+		}
+	}
+
+	if a.keys.Layout() == cachekey.LayoutHash {
+		return a.importHash(ctx, rec, res, ttl, overwrite, dryRun)
+	}
+
+	var data []byte
+	if !rec.Tombstone {
+		var err error
+		if data, err = a.encoder.Encode(value); err != nil {
+			return res, err
+		}
+	}
+
+	cached, err := a.redis.Get(ctx, res.Key).Result()
+	switch {
+	case err == redis.Nil:
+		res.Result = "added"
+	case err != nil:
+		return res, err
+	case sameValue(cached, rec, value):
+		res.Result = "unchanged"
+		return res, nil
+	case overwrite:
+		res.Result = "overwritten"
+	default:
+		res.Result = "kept"
+		return res, nil
+	}
+	if dryRun {
+		return res, nil
+	}
+
+	if res.Result == "overwritten" {
+		return res, a.redis.Set(ctx, res.Key, string(data), ttl).Err()
+	}
+	set, err := a.redis.SetNX(ctx, res.Key, string(data), ttl).Result()
+	if err == nil && !set {
+		res.Result = "kept"
+	}
+
+	return res, err
+}
+
+// importHash writes rec to the hash layout through the write repository,
+// which lays out its hashes and indexes, after reading what is cached
+// through the read repository.
+func (a *app) importHash(ctx context.Context, rec record, res imported, ttl time.Duration, overwrite, dryRun bool) (imported, error) {
+	var cached record
+	var err error
+	if rec.Kind == cachekey.KindPage {
+		cached.IDs, err = a.source.Fetch(ctx, rec.ID)
+	} else {
+		var character domain.Character
+		character, err = a.source.GetByID(ctx, rec.ID)
+		cached.Character = &character
+	}
+	switch {
+	case err == nil:
+		res.Result = "overwritten"
+	case domain.CodeOf(err) == domain.CodeNotFound:
+		cached.Tombstone = true
+		res.Result = "overwritten"
+	case domain.CodeOf(err) == domain.CodeNotCached:
+		res.Result = "added"
+	default:
+		return res, err
+	}
+
+	if res.Result == "overwritten" {
+		switch {
+		case sameRecord(cached, rec):
+			res.Result = "unchanged"
+			return res, nil
+		case !overwrite:
+			res.Result = "kept"
+			return res, nil
+		}
+	}
+	if dryRun {
+		return res, nil
+	}
+
+	if rec.Kind == cachekey.KindPage {
+		return res, a.writer.ImportPage(ctx, rec.ID, rec.IDs, ttl)
+	}
+	var modified time.Time
+	if rec.Modified != nil {
+		modified = *rec.Modified
+	}
+	if rec.Tombstone {
+		return res, a.writer.ImportCharacter(ctx, rec.ID, nil, modified, ttl)
+	}
+
+	return res, a.writer.ImportCharacter(ctx, rec.ID, rec.Character, modified, ttl)
+}
+
+// sameRecord tells whether cached, read back from the cache, holds what rec
+// does.
+func sameRecord(cached, rec record) bool {
+	switch {
+	case cached.Tombstone || rec.Tombstone:
+		return cached.Tombstone == rec.Tombstone
+	case rec.Kind == cachekey.KindPage:
+		return fmt.Sprint(cached.IDs) == fmt.Sprint(rec.IDs)
+	}
+
+	want, got := *rec.Character, *cached.Character
+	return want.ID == got.ID && want.Name == got.Name && want.Description == got.Description && want.FetchedAt.Equal(got.FetchedAt)
+}
+
+// sameValue tells whether cached, in any encoding, holds the value of rec.
+func sameValue(cached string, rec record, value interface{}) bool {
+	if cached == "" || rec.Tombstone {
+		return cached == "" && rec.Tombstone
+	}
+
+	var decoded interface{} = &domain.Character{}
+	if rec.Kind == cachekey.KindPage {
+		decoded = &[]int{}
+	}
+	if codec.Decode([]byte(cached), decoded) != nil {
+		return false
+	}
+	want, _ := json.Marshal(value)
+	got, _ := json.Marshal(decoded)
+
+	return string(want) == string(got)
+}
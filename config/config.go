@@ -0,0 +1,575 @@
+// Package config loads the service configuration from, in increasing order of
+// precedence, built-in defaults, a JSON config file, environment variables and
+// command line flags.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+const redacted = "[REDACTED]"
+
+type Config struct {
+	MarvelAPI               MarvelAPI  `mapstructure:"marvel_api" json:"marvel_api"`
+	Redis                   Redis      `mapstructure:"redis" json:"redis"`
+	CacheExpirationInSec    int        `mapstructure:"cache_expiration_in_sec" json:"cache_expiration_in_sec"`
+	CacheExpirationJitter   float64    `mapstructure:"cache_expiration_jitter" json:"cache_expiration_jitter"`
+	NotFoundExpirationInSec int        `mapstructure:"not_found_expiration_in_sec" json:"not_found_expiration_in_sec"`
+	Server                  Server     `mapstructure:"server" json:"server"`
+	Features                Features   `mapstructure:"features" json:"features"`
+	PreRefresh              PreRefresh `mapstructure:"pre_refresh" json:"pre_refresh"`
+	Popularity              Popularity `mapstructure:"popularity" json:"popularity"`
+	Log                     Log        `mapstructure:"log" json:"log"`
+	Tracing                 Tracing    `mapstructure:"tracing" json:"tracing"`
+	GraphQL                 GraphQL    `mapstructure:"graphql" json:"graphql"`
+	GRPC                    GRPC       `mapstructure:"grpc" json:"grpc"`
+	Admin                   Admin      `mapstructure:"admin" json:"admin"`
+	APIKeys                 APIKeys    `mapstructure:"api_keys" json:"api_keys"`
+	JWT                     JWT        `mapstructure:"jwt" json:"jwt"`
+	RateLimit               RateLimit  `mapstructure:"rate_limit" json:"rate_limit"`
+	Codec                   Codec      `mapstructure:"codec" json:"codec"`
+}
+
+type MarvelAPI struct {
+	URL            string `mapstructure:"url" json:"url"`
+	PublicKey      string `mapstructure:"public_key" json:"public_key"`
+	PrivateKey     string `mapstructure:"private_key" json:"private_key"`
+	PrivateKeyFile string `mapstructure:"private_key_file" json:"private_key_file"`
+	TimeoutInSec   int    `mapstructure:"timeout_in_sec" json:"timeout_in_sec"`
+	DailyQuota     int    `mapstructure:"daily_quota" json:"daily_quota"`
+}
+
+// Redis is where the cache lives. KeyPrefix starts its keys, to share the
+// Redis with other applications or environments. Layout is "string" or
+// "hash", see cachekey.Layout.
+type Redis struct {
+	Host         string `mapstructure:"host" json:"host"`
+	Port         string `mapstructure:"port" json:"port"`
+	Password     string `mapstructure:"password" json:"password"`
+	PasswordFile string `mapstructure:"password_file" json:"password_file"`
+	KeyPrefix    string `mapstructure:"key_prefix" json:"key_prefix"`
+	Layout       string `mapstructure:"layout" json:"layout"`
+}
+
+type Server struct {
+	Address              string `mapstructure:"address" json:"address"`
+	TimeoutInSec         int    `mapstructure:"timeout_in_sec" json:"timeout_in_sec"`
+	ShutdownTimeoutInSec int    `mapstructure:"shutdown_timeout_in_sec" json:"shutdown_timeout_in_sec"`
+}
+
+type Features struct {
+	BackgroundRefresh bool `mapstructure:"background_refresh" json:"background_refresh"`
+}
+
+// PreRefresh refreshes the Top characters and pages looked up at least
+// MinHits times within the popularity window once they expire within
+// WindowInSec, checking them every IntervalInSec. It needs Popularity.
+type PreRefresh struct {
+	Enabled       bool `mapstructure:"enabled" json:"enabled"`
+	IntervalInSec int  `mapstructure:"interval_in_sec" json:"interval_in_sec"`
+	WindowInSec   int  `mapstructure:"window_in_sec" json:"window_in_sec"`
+	Top           int  `mapstructure:"top" json:"top"`
+	MinHits       int  `mapstructure:"min_hits" json:"min_hits"`
+}
+
+// Popularity counts the lookups of characters and pages in Redis, in buckets
+// of BucketInSec kept RetentionInSec, flushed every FlushIntervalInSec.
+// Entries ranked by PreRefresh within WindowInSec are popular, and cached
+// ExpirationInSec when it is longer than cache_expiration_in_sec.
+type Popularity struct {
+	Enabled            bool `mapstructure:"enabled" json:"enabled"`
+	BucketInSec        int  `mapstructure:"bucket_in_sec" json:"bucket_in_sec"`
+	RetentionInSec     int  `mapstructure:"retention_in_sec" json:"retention_in_sec"`
+	FlushIntervalInSec int  `mapstructure:"flush_interval_in_sec" json:"flush_interval_in_sec"`
+	WindowInSec        int  `mapstructure:"window_in_sec" json:"window_in_sec"`
+	ExpirationInSec    int  `mapstructure:"expiration_in_sec" json:"expiration_in_sec"`
+}
+
+// Log sets the default level and format of the logs. Components overrides
+// the level of single components, keyed by the component name.
+type Log struct {
+	Level      string            `mapstructure:"level" json:"level"`
+	Format     string            `mapstructure:"format" json:"format"`
+	Components map[string]string `mapstructure:"components" json:"components"`
+}
+
+// Tracing picks where spans go. Exporter is "none", "otlp" to send them to
+// the OTLP/HTTP collector at Endpoint, "stdout", or "file" to append them to
+// File. SampleRatio is the share of new traces that are recorded.
+type Tracing struct {
+	Exporter    string  `mapstructure:"exporter" json:"exporter"`
+	Endpoint    string  `mapstructure:"endpoint" json:"endpoint"`
+	File        string  `mapstructure:"file" json:"file"`
+	SampleRatio float64 `mapstructure:"sample_ratio" json:"sample_ratio"`
+}
+
+// GraphQL bounds the cost of GraphQL queries. MaxComplexity is the number of
+// characters and pages a query may resolve. Playground serves GraphiQL, for
+// development.
+type GraphQL struct {
+	MaxDepth      int  `mapstructure:"max_depth" json:"max_depth"`
+	MaxComplexity int  `mapstructure:"max_complexity" json:"max_complexity"`
+	Playground    bool `mapstructure:"playground" json:"playground"`
+}
+
+// GRPC is where the gRPC CharacterService listens, next to the HTTP server.
+type GRPC struct {
+	Address string `mapstructure:"address" json:"address"`
+}
+
+// Admin protects the /admin endpoints with a bearer token. They are not
+// served without one.
+type Admin struct {
+	Token     string `mapstructure:"token" json:"token"`
+	TokenFile string `mapstructure:"token_file" json:"token_file"`
+}
+
+// APIKeys requires an API key on the character endpoints when Enabled. Keys
+// issued without limits may make RateLimit requests per minute and
+// DailyQuota per day. 0 means unlimited.
+type APIKeys struct {
+	Enabled    bool  `mapstructure:"enabled" json:"enabled"`
+	RateLimit  int64 `mapstructure:"rate_limit" json:"rate_limit"`
+	DailyQuota int64 `mapstructure:"daily_quota" json:"daily_quota"`
+}
+
+// JWT accepts bearer JWTs signed by the keys of the JWKS at JWKSURL, or in
+// JWKSFile. Issuer and Audience, when set, must match the iss and aud claims.
+type JWT struct {
+	JWKSURL  string `mapstructure:"jwks_url" json:"jwks_url"`
+	JWKSFile string `mapstructure:"jwks_file" json:"jwks_file"`
+	Issuer   string `mapstructure:"issuer" json:"issuer"`
+	Audience string `mapstructure:"audience" json:"audience"`
+}
+
+func (j JWT) Enabled() bool {
+	return j.JWKSURL != "" || j.JWKSFile != ""
+}
+
+// RateLimit limits the requests to the character endpoints with token
+// buckets kept in "memory", for a single instance, or in "redis", shared by
+// every replica, unless Backend is "none". Each client may make Burst
+// requests at once then PerMinute a minute, all clients together
+// GlobalBurst then GlobalPerMinute, and each client MissBurst then
+// MissPerMinute cache misses. A zero per minute rate disables its limit.
+type RateLimit struct {
+	Backend         string `mapstructure:"backend" json:"backend"`
+	PerMinute       int64  `mapstructure:"per_minute" json:"per_minute"`
+	Burst           int64  `mapstructure:"burst" json:"burst"`
+	GlobalPerMinute int64  `mapstructure:"global_per_minute" json:"global_per_minute"`
+	GlobalBurst     int64  `mapstructure:"global_burst" json:"global_burst"`
+	MissPerMinute   int64  `mapstructure:"miss_per_minute" json:"miss_per_minute"`
+	MissBurst       int64  `mapstructure:"miss_burst" json:"miss_burst"`
+}
+
+// Codec picks how cached values are encoded: Format is "json", "msgpack" or
+// "protobuf", and values of at least CompressionThreshold bytes are
+// compressed with Compression, "none", "zstd" or "snappy". Values written
+// with other settings stay readable.
+type Codec struct {
+	Format               string `mapstructure:"format" json:"format"`
+	Compression          string `mapstructure:"compression" json:"compression"`
+	CompressionThreshold int    `mapstructure:"compression_threshold" json:"compression_threshold"`
+}
+
+func (c Config) CacheExpiration() time.Duration {
+	return time.Duration(c.CacheExpirationInSec) * time.Second
+}
+
+// NotFoundExpiration is how long characters and pages Marvel API does not
+// know are cached as such. 0 does not cache them.
+func (c Config) NotFoundExpiration() time.Duration {
+	return time.Duration(c.NotFoundExpirationInSec) * time.Second
+}
+
+func (p PreRefresh) Interval() time.Duration {
+	return time.Duration(p.IntervalInSec) * time.Second
+}
+
+func (p PreRefresh) Window() time.Duration {
+	return time.Duration(p.WindowInSec) * time.Second
+}
+
+func (p Popularity) Bucket() time.Duration {
+	return time.Duration(p.BucketInSec) * time.Second
+}
+
+func (p Popularity) Retention() time.Duration {
+	return time.Duration(p.RetentionInSec) * time.Second
+}
+
+func (p Popularity) FlushInterval() time.Duration {
+	return time.Duration(p.FlushIntervalInSec) * time.Second
+}
+
+func (p Popularity) Window() time.Duration {
+	return time.Duration(p.WindowInSec) * time.Second
+}
+
+// Expiration is how long popular entries are cached, 0 as long as the
+// others.
+func (p Popularity) Expiration() time.Duration {
+	return time.Duration(p.ExpirationInSec) * time.Second
+}
+
+func (m MarvelAPI) Timeout() time.Duration {
+	return time.Duration(m.TimeoutInSec) * time.Second
+}
+
+func (r Redis) Addr() string {
+	return r.Host + ":" + r.Port
+}
+
+func (s Server) Timeout() time.Duration {
+	return time.Duration(s.TimeoutInSec) * time.Second
+}
+
+func (s Server) ShutdownTimeout() time.Duration {
+	return time.Duration(s.ShutdownTimeoutInSec) * time.Second
+}
+
+// ValidationError lists every problem found in a configuration, so they can
+// all be fixed at once.
+type ValidationError []string
+
+func (v ValidationError) Error() string {
+	return "Invalid configuration: " + strings.Join(v, "; ")
+}
+
+func (c Config) Validate() error {
+	var errs ValidationError
+
+	if c.MarvelAPI.URL == "" {
+		errs = append(errs, "marvel_api.url is empty")
+	}
+	if c.MarvelAPI.PublicKey == "" {
+		errs = append(errs, "marvel_api.public_key is empty")
+	}
+	if c.MarvelAPI.PrivateKey == "" {
+		errs = append(errs, "marvel_api.private_key is empty")
+	}
+	if c.MarvelAPI.TimeoutInSec <= 0 {
+		errs = append(errs, "marvel_api.timeout_in_sec must be positive")
+	}
+	if c.MarvelAPI.DailyQuota < 0 {
+		errs = append(errs, "marvel_api.daily_quota must not be negative")
+	}
+	if c.Redis.Host == "" || c.Redis.Port == "" {
+		errs = append(errs, "redis.host and redis.port are required")
+	}
+	if c.Redis.KeyPrefix == "" {
+		errs = append(errs, "redis.key_prefix is empty")
+	}
+	if _, err := cachekey.ParseLayout(c.Redis.Layout); err != nil {
+		errs = append(errs, "redis.layout: "+err.Error())
+	}
+	if c.CacheExpirationInSec <= 0 {
+		errs = append(errs, "cache_expiration_in_sec must be positive")
+	}
+	if c.CacheExpirationJitter < 0 || c.CacheExpirationJitter >= 1 {
+		errs = append(errs, "cache_expiration_jitter must be at least 0 and below 1")
+	}
+	if c.NotFoundExpirationInSec < 0 {
+		errs = append(errs, "not_found_expiration_in_sec must not be negative")
+	}
+	if c.PreRefresh.Enabled && (c.PreRefresh.IntervalInSec <= 0 || c.PreRefresh.WindowInSec <= 0) {
+		errs = append(errs, "pre_refresh.interval_in_sec and pre_refresh.window_in_sec must be positive")
+	}
+	if c.PreRefresh.Enabled && (c.PreRefresh.Top <= 0 || c.PreRefresh.MinHits <= 0) {
+		errs = append(errs, "pre_refresh.top and pre_refresh.min_hits must be positive")
+	}
+	if c.PreRefresh.Enabled && !c.Popularity.Enabled {
+		errs = append(errs, "pre_refresh.enabled needs popularity.enabled")
+	}
+	if c.Popularity.Enabled && (c.Popularity.BucketInSec <= 0 || c.Popularity.RetentionInSec <= 0 || c.Popularity.FlushIntervalInSec <= 0) {
+		errs = append(errs, "popularity.bucket_in_sec, popularity.retention_in_sec and popularity.flush_interval_in_sec must be positive")
+	}
+	if c.Popularity.Enabled && (c.Popularity.WindowInSec <= 0 || c.Popularity.WindowInSec > c.Popularity.RetentionInSec) {
+		errs = append(errs, "popularity.window_in_sec must be positive and at most popularity.retention_in_sec")
+	}
+	if c.Popularity.ExpirationInSec < 0 {
+		errs = append(errs, "popularity.expiration_in_sec must not be negative")
+	}
+	if c.Server.Address == "" {
+		errs = append(errs, "server.address is empty")
+	}
+	if c.Server.TimeoutInSec <= 0 {
+		errs = append(errs, "server.timeout_in_sec must be positive")
+	}
+	if c.Server.ShutdownTimeoutInSec <= 0 {
+		errs = append(errs, "server.shutdown_timeout_in_sec must be positive")
+	}
+	if c.GRPC.Address == "" {
+		errs = append(errs, "grpc.address is empty")
+	}
+	if c.APIKeys.RateLimit < 0 || c.APIKeys.DailyQuota < 0 {
+		errs = append(errs, "api_keys.rate_limit and api_keys.daily_quota must not be negative")
+	}
+	if c.JWT.JWKSURL != "" && c.JWT.JWKSFile != "" {
+		errs = append(errs, "jwt.jwks_url and jwt.jwks_file are mutually exclusive")
+	}
+	switch c.RateLimit.Backend {
+	case "none", "memory", "redis":
+	default:
+		errs = append(errs, fmt.Sprintf("unknown rate_limit.backend %q", c.RateLimit.Backend))
+	}
+	for _, l := range []struct {
+		name             string
+		perMinute, burst int64
+	}{
+		{"", c.RateLimit.PerMinute, c.RateLimit.Burst},
+		{"global_", c.RateLimit.GlobalPerMinute, c.RateLimit.GlobalBurst},
+		{"miss_", c.RateLimit.MissPerMinute, c.RateLimit.MissBurst},
+	} {
+		if l.perMinute < 0 {
+			errs = append(errs, "rate_limit."+l.name+"per_minute must not be negative")
+		}
+		if l.perMinute > 0 && l.burst <= 0 {
+			errs = append(errs, "rate_limit."+l.name+"burst must be positive")
+		}
+	}
+
+	if _, err := codec.ParseFormat(c.Codec.Format); err != nil {
+		errs = append(errs, "codec.format: "+err.Error())
+	}
+	if _, err := codec.ParseCompression(c.Codec.Compression); err != nil {
+		errs = append(errs, "codec.compression: "+err.Error())
+	}
+	if c.Codec.CompressionThreshold < 0 {
+		errs = append(errs, "codec.compression_threshold must not be negative")
+	}
+
+	if _, _, err := logger.ParseLevels(c.Log.Level, c.Log.Components); err != nil {
+		errs = append(errs, "log level: "+err.Error())
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, "log.format must be json or text")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, "tracing.endpoint is required by the otlp exporter")
+		}
+	case "file":
+		if c.Tracing.File == "" {
+			errs = append(errs, "tracing.file is required by the file exporter")
+		}
+	default:
+		errs = append(errs, "tracing.exporter must be none, otlp, stdout or file")
+	}
+	if c.GraphQL.MaxDepth <= 0 {
+		errs = append(errs, "graphql.max_depth must be positive")
+	}
+	if c.GraphQL.MaxComplexity <= 0 {
+		errs = append(errs, "graphql.max_complexity must be positive")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, "tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration that is safe to print.
+func (c Config) Redacted() Config {
+	if c.MarvelAPI.PrivateKey != "" {
+		c.MarvelAPI.PrivateKey = redacted
+	}
+	if c.Redis.Password != "" {
+		c.Redis.Password = redacted
+	}
+	if c.Admin.Token != "" {
+		c.Admin.Token = redacted
+	}
+
+	return c
+}
+
+func (c Config) String() string {
+	json_data, _ := json.MarshalIndent(c.Redacted(), "", "  ")
+	return string(json_data)
+}
+
+// binding ties a config key to the environment variable and the command line
+// flag that can override it. Keys without a flag are secrets that should not
+// show up in the process list.
+type binding struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	value interface{}
+}
+
+var bindings = []binding{
+	{key: "marvel_api.url", env: "MARVEL_API_URL", flag: "marvel-api-url", usage: "Marvel API base url", value: "https://gateway.marvel.com:443"},
+	{key: "marvel_api.public_key", env: "MARVEL_PUBLIC_KEY", flag: "marvel-public-key", usage: "Marvel API public key", value: ""},
+	{key: "marvel_api.private_key", env: "MARVEL_PRIVATE_KEY", value: ""},
+	{key: "marvel_api.private_key_file", env: "MARVEL_PRIVATE_KEY_FILE", flag: "marvel-private-key-file", usage: "file holding the Marvel API private key", value: ""},
+	{key: "marvel_api.timeout_in_sec", env: "MARVEL_API_TIMEOUT_IN_SEC", flag: "marvel-api-timeout", usage: "Marvel API timeout in seconds", value: 120},
+	{key: "marvel_api.daily_quota", env: "MARVEL_API_DAILY_QUOTA", flag: "marvel-api-daily-quota", usage: "Marvel API daily call quota", value: 3000},
+	{key: "redis.host", env: "REDIS_HOST", flag: "redis-host", usage: "redis host", value: "localhost"},
+	{key: "redis.port", env: "REDIS_PORT", flag: "redis-port", usage: "redis port", value: "6379"},
+	{key: "redis.password", env: "REDIS_PASSWORD", value: ""},
+	{key: "redis.password_file", env: "REDIS_PASSWORD_FILE", flag: "redis-password-file", usage: "file holding the redis password", value: ""},
+	{key: "redis.key_prefix", env: "REDIS_KEY_PREFIX", flag: "redis-key-prefix", usage: "prefix of the cache keys", value: "marvel-"},
+	{key: "redis.layout", env: "REDIS_LAYOUT", flag: "redis-layout", usage: "how characters and pages are stored: string or hash", value: "string"},
+	{key: "cache_expiration_in_sec", env: "CACHE_EXPIRATION_IN_SEC", flag: "cache-expiration", usage: "cache expiration in seconds", value: 604800},
+	{key: "cache_expiration_jitter", env: "CACHE_EXPIRATION_JITTER", flag: "cache-expiration-jitter", usage: "share of the expirations cut at random, so that keys written together expire apart", value: 0.1},
+	{key: "not_found_expiration_in_sec", env: "NOT_FOUND_EXPIRATION_IN_SEC", flag: "not-found-expiration", usage: "expiration in seconds of cached not found characters and pages, 0 to not cache them", value: 3600},
+	{key: "server.address", env: "SERVER_ADDRESS", flag: "address", usage: "http listen address", value: ":8080"},
+	{key: "server.timeout_in_sec", env: "SERVER_TIMEOUT_IN_SEC", flag: "timeout", usage: "request timeout in seconds", value: 60},
+	{key: "server.shutdown_timeout_in_sec", env: "SERVER_SHUTDOWN_TIMEOUT_IN_SEC", flag: "shutdown-timeout", usage: "graceful shutdown deadline in seconds", value: 30},
+	{key: "grpc.address", env: "GRPC_ADDRESS", flag: "grpc-address", usage: "gRPC listen address", value: ":9090"},
+	{key: "admin.token", env: "ADMIN_TOKEN", value: ""},
+	{key: "admin.token_file", env: "ADMIN_TOKEN_FILE", flag: "admin-token-file", usage: "file holding the bearer token of the admin endpoints", value: ""},
+	{key: "api_keys.enabled", env: "API_KEYS_ENABLED", flag: "api-keys", usage: "require an API key on the character endpoints", value: false},
+	{key: "api_keys.rate_limit", env: "API_KEYS_RATE_LIMIT", flag: "api-key-rate-limit", usage: "default requests per minute of an API key", value: 60},
+	{key: "api_keys.daily_quota", env: "API_KEYS_DAILY_QUOTA", flag: "api-key-daily-quota", usage: "default requests per day of an API key", value: 1000},
+	{key: "jwt.jwks_url", env: "JWT_JWKS_URL", flag: "jwt-jwks-url", usage: "URL of the JWKS verifying bearer JWTs", value: ""},
+	{key: "jwt.jwks_file", env: "JWT_JWKS_FILE", flag: "jwt-jwks-file", usage: "file holding the JWKS verifying bearer JWTs", value: ""},
+	{key: "jwt.issuer", env: "JWT_ISSUER", flag: "jwt-issuer", usage: "required iss claim of bearer JWTs", value: ""},
+	{key: "jwt.audience", env: "JWT_AUDIENCE", flag: "jwt-audience", usage: "required aud claim of bearer JWTs", value: ""},
+	{key: "rate_limit.backend", env: "RATE_LIMIT_BACKEND", flag: "rate-limit-backend", usage: "where rate limit buckets are kept: none, memory or redis", value: "none"},
+	{key: "rate_limit.per_minute", env: "RATE_LIMIT_PER_MINUTE", flag: "rate-limit-per-minute", usage: "requests per minute of each client", value: 600},
+	{key: "rate_limit.burst", env: "RATE_LIMIT_BURST", flag: "rate-limit-burst", usage: "requests each client may make at once", value: 60},
+	{key: "rate_limit.global_per_minute", env: "RATE_LIMIT_GLOBAL_PER_MINUTE", flag: "rate-limit-global-per-minute", usage: "requests per minute of all clients together", value: 0},
+	{key: "rate_limit.global_burst", env: "RATE_LIMIT_GLOBAL_BURST", flag: "rate-limit-global-burst", usage: "requests all clients together may make at once", value: 0},
+	{key: "rate_limit.miss_per_minute", env: "RATE_LIMIT_MISS_PER_MINUTE", flag: "rate-limit-miss-per-minute", usage: "cache misses per minute of each client", value: 30},
+	{key: "rate_limit.miss_burst", env: "RATE_LIMIT_MISS_BURST", flag: "rate-limit-miss-burst", usage: "cache misses each client may make at once", value: 10},
+	{key: "codec.format", env: "CODEC_FORMAT", flag: "codec-format", usage: "format of cached values: json, msgpack or protobuf", value: "json"},
+	{key: "codec.compression", env: "CODEC_COMPRESSION", flag: "codec-compression", usage: "compression of cached values: none, zstd or snappy", value: "none"},
+	{key: "codec.compression_threshold", env: "CODEC_COMPRESSION_THRESHOLD", flag: "codec-compression-threshold", usage: "bytes from which cached values are compressed", value: 1024},
+	{key: "log.level", env: "LOG_LEVEL", flag: "log-level", usage: "default log level: debug, info, warn or error", value: "info"},
+	{key: "log.format", env: "LOG_FORMAT", flag: "log-format", usage: "log format: json or text", value: "json"},
+	{key: "tracing.exporter", env: "TRACING_EXPORTER", flag: "tracing-exporter", usage: "span exporter: none, otlp, stdout or file", value: "none"},
+	{key: "tracing.endpoint", env: "TRACING_ENDPOINT", flag: "tracing-endpoint", usage: "OTLP/HTTP collector url", value: "http://localhost:4318"},
+	{key: "tracing.file", env: "TRACING_FILE", flag: "tracing-file", usage: "file the spans are appended to", value: "traces.json"},
+	{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", flag: "tracing-sample-ratio", usage: "share of new traces recorded, between 0 and 1", value: 1.0},
+	{key: "graphql.max_depth", env: "GRAPHQL_MAX_DEPTH", flag: "graphql-max-depth", usage: "deepest GraphQL selection allowed", value: 5},
+	{key: "graphql.max_complexity", env: "GRAPHQL_MAX_COMPLEXITY", flag: "graphql-max-complexity", usage: "characters and pages a GraphQL query may resolve", value: 200},
+	{key: "graphql.playground", env: "GRAPHQL_PLAYGROUND", flag: "graphql-playground", usage: "serve GraphiQL on GET /graphql, for development", value: false},
+	{key: "features.background_refresh", env: "FEATURE_BACKGROUND_REFRESH", flag: "background-refresh", usage: "refresh the cache from the Marvel API in the background", value: true},
+	{key: "pre_refresh.enabled", env: "PRE_REFRESH_ENABLED", flag: "pre-refresh", usage: "refresh popular entries shortly before they expire", value: true},
+	{key: "pre_refresh.interval_in_sec", env: "PRE_REFRESH_INTERVAL_IN_SEC", flag: "pre-refresh-interval", usage: "seconds between checks of the popular entries", value: 60},
+	{key: "pre_refresh.window_in_sec", env: "PRE_REFRESH_WINDOW_IN_SEC", flag: "pre-refresh-window", usage: "seconds before expiry from which popular entries are refreshed", value: 600},
+	{key: "pre_refresh.top", env: "PRE_REFRESH_TOP", flag: "pre-refresh-top", usage: "most looked up entries checked at a time", value: 100},
+	{key: "pre_refresh.min_hits", env: "PRE_REFRESH_MIN_HITS", flag: "pre-refresh-min-hits", usage: "lookups within the popularity window from which an entry is popular", value: 2},
+	{key: "popularity.enabled", env: "POPULARITY_ENABLED", flag: "popularity", usage: "count the lookups of characters and pages in Redis", value: true},
+	{key: "popularity.bucket_in_sec", env: "POPULARITY_BUCKET_IN_SEC", flag: "popularity-bucket", usage: "seconds of lookups counted together", value: 3600},
+	{key: "popularity.retention_in_sec", env: "POPULARITY_RETENTION_IN_SEC", flag: "popularity-retention", usage: "seconds lookup counts are kept, the longest popularity window", value: 604800},
+	{key: "popularity.flush_interval_in_sec", env: "POPULARITY_FLUSH_INTERVAL_IN_SEC", flag: "popularity-flush-interval", usage: "seconds between writes of the lookups counted in memory", value: 10},
+	{key: "popularity.window_in_sec", env: "POPULARITY_WINDOW_IN_SEC", flag: "popularity-window", usage: "seconds of lookups ranking the popular entries", value: 86400},
+	{key: "popularity.expiration_in_sec", env: "POPULARITY_EXPIRATION_IN_SEC", flag: "popularity-expiration", usage: "seconds popular entries are cached, 0 for cache_expiration_in_sec", value: 0},
+}
+
+type Loader struct {
+	v           *viper.Viper
+	flags       *pflag.FlagSet
+	configFile  string
+	printConfig bool
+}
+
+func NewLoader(name string) *Loader {
+	l := &Loader{
+		v:     viper.New(),
+		flags: pflag.NewFlagSet(name, pflag.ContinueOnError),
+	}
+
+	l.flags.StringVar(&l.configFile, "config", "config/common.json", "path to the JSON config file")
+	l.flags.BoolVar(&l.printConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+
+	for _, b := range bindings {
+		l.v.SetDefault(b.key, b.value)
+		_ = l.v.BindEnv(b.key, b.env)
+		if b.flag == "" {
+			continue
+		}
+
+		switch value := b.value.(type) {
+		case int:
+			l.flags.Int(b.flag, value, b.usage)
+		case float64:
+			l.flags.Float64(b.flag, value, b.usage)
+		case bool:
+			l.flags.Bool(b.flag, value, b.usage)
+		default:
+			l.flags.String(b.flag, fmt.Sprint(value), b.usage)
+		}
+		_ = l.v.BindPFlag(b.key, l.flags.Lookup(b.flag))
+	}
+
+	return l
+}
+
+// Parse reads the command line flags. It returns pflag.ErrHelp when usage
+// was requested.
+func (l *Loader) Parse(args []string) error {
+	return l.flags.Parse(args)
+}
+
+func (l *Loader) PrintConfig() bool {
+	return l.printConfig
+}
+
+// Load reads the config file and resolves every override. The returned
+// configuration is filled in even when it fails validation, so that it can
+// still be printed.
+func (l *Loader) Load() (Config, error) {
+	var cfg Config
+
+	l.v.SetConfigFile(l.configFile)
+	if err := l.v.ReadInConfig(); err != nil {
+		return cfg, err
+	}
+
+	if err := l.v.Unmarshal(&cfg); err != nil {
+		return cfg, err
+	}
+
+	if err := resolveSecretFile(&cfg.MarvelAPI.PrivateKey, cfg.MarvelAPI.PrivateKeyFile, "marvel_api.private_key"); err != nil {
+		return cfg, err
+	}
+	if err := resolveSecretFile(&cfg.Redis.Password, cfg.Redis.PasswordFile, "redis.password"); err != nil {
+		return cfg, err
+	}
+	if err := resolveSecretFile(&cfg.Admin.Token, cfg.Admin.TokenFile, "admin.token"); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func resolveSecretFile(secret *string, path, key string) error {
+	if path == "" {
+		return nil
+	}
+	if *secret != "" {
+		return errors.New("Both " + key + " and " + key + "_file are set. Use only one")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	*secret = strings.TrimSpace(string(content))
+
+	return nil
+}
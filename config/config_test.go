@@ -0,0 +1,238 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/config"
+)
+
+const fixture = `{
+	"marvel_api": {
+		"url": "http://foo.com",
+		"public_key": "file-public",
+		"private_key": "file-private",
+		"timeout_in_sec": 10
+	},
+	"redis": {
+		"host": "redis",
+		"port": "6379"
+	},
+	"cache_expiration_in_sec": 60,
+	"server": {
+		"timeout_in_sec": 5,
+		"shutdown_timeout_in_sec": 5,
+		"address": ":8080"
+	}
+}`
+
+type ConfigTestSuite struct {
+	suite.Suite
+	dir  string
+	file string
+}
+
+func TestConfig(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
+
+func (s *ConfigTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		s.T().Fatalf("Error: '%s'", err)
+	}
+	s.dir = dir
+	s.file = s.writeFile("common.json", fixture)
+}
+
+func (s *ConfigTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+	os.Unsetenv("MARVEL_PRIVATE_KEY")
+	os.Unsetenv("MARVEL_PRIVATE_KEY_FILE")
+	os.Unsetenv("REDIS_HOST")
+}
+
+func (s *ConfigTestSuite) writeFile(name, content string) string {
+	path := filepath.Join(s.dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		s.T().Fatalf("Error: '%s'", err)
+	}
+	return path
+}
+
+func (s *ConfigTestSuite) load(args ...string) (config.Config, error) {
+	loader := config.NewLoader("test")
+	if err := loader.Parse(append([]string{"--config", s.file}, args...)); err != nil {
+		s.T().Fatalf("Error: '%s'", err)
+	}
+	return loader.Load()
+}
+
+func (s *ConfigTestSuite) TestSuccessLoadFile() {
+	cfg, err := s.load()
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal("http://foo.com", cfg.MarvelAPI.URL)
+	s.Assert().Equal("file-private", cfg.MarvelAPI.PrivateKey)
+	s.Assert().Equal("redis:6379", cfg.Redis.Addr())
+	s.Assert().Equal("marvel-", cfg.Redis.KeyPrefix)
+	s.Assert().Equal("string", cfg.Redis.Layout)
+	s.Assert().Equal(config.Codec{Format: "json", Compression: "none", CompressionThreshold: 1024}, cfg.Codec)
+	s.Assert().Equal(float64(60), cfg.CacheExpiration().Seconds())
+	s.Assert().Equal(0.1, cfg.CacheExpirationJitter)
+	s.Assert().Equal(config.PreRefresh{Enabled: true, IntervalInSec: 60, WindowInSec: 600, Top: 100, MinHits: 2}, cfg.PreRefresh)
+	s.Assert().Equal(10*time.Minute, cfg.PreRefresh.Window())
+	s.Assert().Equal(config.Popularity{Enabled: true, BucketInSec: 3600, RetentionInSec: 604800, FlushIntervalInSec: 10, WindowInSec: 86400}, cfg.Popularity)
+	s.Assert().Equal(24*time.Hour, cfg.Popularity.Window())
+}
+
+func (s *ConfigTestSuite) TestSuccessEnvOverridesFile() {
+	os.Setenv("MARVEL_PRIVATE_KEY", "env-private")
+	os.Setenv("REDIS_HOST", "env-redis")
+
+	cfg, err := s.load()
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal("env-private", cfg.MarvelAPI.PrivateKey)
+	s.Assert().Equal("env-redis", cfg.Redis.Host)
+}
+
+func (s *ConfigTestSuite) TestSuccessFlagOverridesEnv() {
+	os.Setenv("REDIS_HOST", "env-redis")
+
+	cfg, err := s.load("--redis-host", "flag-redis", "--timeout", "7")
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal("flag-redis", cfg.Redis.Host)
+	s.Assert().Equal(7, cfg.Server.TimeoutInSec)
+}
+
+func (s *ConfigTestSuite) TestSuccessSecretFile() {
+	s.file = s.writeFile("common.json", strings.Replace(fixture, `"file-private"`, `""`, 1))
+	os.Setenv("MARVEL_PRIVATE_KEY_FILE", s.writeFile("private_key", "secret-private\n"))
+
+	cfg, err := s.load()
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal("secret-private", cfg.MarvelAPI.PrivateKey)
+}
+
+func (s *ConfigTestSuite) TestSuccessAdminTokenFile() {
+	cfg, err := s.load("--admin-token-file", s.writeFile("admin_token", "secret-admin\n"))
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal("secret-admin", cfg.Admin.Token)
+	s.Assert().Equal("[REDACTED]", cfg.Redacted().Admin.Token)
+	s.Assert().NotContains(cfg.String(), "secret-admin")
+}
+
+func (s *ConfigTestSuite) TestFailedSecretFileAndValue() {
+	os.Setenv("MARVEL_PRIVATE_KEY_FILE", s.writeFile("private_key", "secret-private"))
+
+	_, err := s.load()
+	s.Assert().NotEqual(err, nil)
+}
+
+func (s *ConfigTestSuite) TestFailedValidation() {
+	cfg, err := s.load("--marvel-public-key", "", "--marvel-api-timeout", "0")
+	s.Assert().Equal(err, config.ValidationError{
+		"marvel_api.public_key is empty",
+		"marvel_api.timeout_in_sec must be positive",
+	})
+	s.Assert().Equal("http://foo.com", cfg.MarvelAPI.URL)
+}
+
+func (s *ConfigTestSuite) TestSuccessAPIKeys() {
+	cfg, err := s.load("--api-keys", "--api-key-rate-limit", "10")
+	s.Assert().Equal(err, nil)
+	s.Assert().True(cfg.APIKeys.Enabled)
+	s.Assert().Equal(int64(10), cfg.APIKeys.RateLimit)
+	s.Assert().Equal(int64(1000), cfg.APIKeys.DailyQuota)
+}
+
+func (s *ConfigTestSuite) TestSuccessJWT() {
+	cfg, err := s.load("--jwt-jwks-url", "https://sso.example.com/jwks.json", "--jwt-audience", "marvel")
+	s.Assert().Equal(err, nil)
+	s.Assert().True(cfg.JWT.Enabled())
+	s.Assert().Equal("marvel", cfg.JWT.Audience)
+
+	_, err = s.load("--jwt-jwks-url", "https://sso.example.com/jwks.json", "--jwt-jwks-file", "jwks.json")
+	s.Assert().Equal(err, config.ValidationError{"jwt.jwks_url and jwt.jwks_file are mutually exclusive"})
+}
+
+func (s *ConfigTestSuite) TestFailedRateLimitValidation() {
+	_, err := s.load("--rate-limit-backend", "disk", "--rate-limit-global-per-minute", "100", "--rate-limit-miss-per-minute", "-1")
+	s.Assert().Equal(err, config.ValidationError{
+		`unknown rate_limit.backend "disk"`,
+		"rate_limit.global_burst must be positive",
+		"rate_limit.miss_per_minute must not be negative",
+	})
+}
+
+func (s *ConfigTestSuite) TestFailedCodecValidation() {
+	_, err := s.load("--codec-format", "xml", "--codec-compression", "gzip", "--codec-compression-threshold", "-1")
+	s.Assert().Equal(err, config.ValidationError{
+		`codec.format: unknown format "xml", want json, msgpack or protobuf`,
+		`codec.compression: unknown compression "gzip", want none, zstd or snappy`,
+		"codec.compression_threshold must not be negative",
+	})
+}
+
+func (s *ConfigTestSuite) TestFailedExpirationValidation() {
+	_, err := s.load("--cache-expiration-jitter", "1", "--pre-refresh-window", "0", "--pre-refresh-min-hits", "0")
+	s.Assert().Equal(err, config.ValidationError{
+		"cache_expiration_jitter must be at least 0 and below 1",
+		"pre_refresh.interval_in_sec and pre_refresh.window_in_sec must be positive",
+		"pre_refresh.top and pre_refresh.min_hits must be positive",
+	})
+
+	_, err = s.load("--pre-refresh=false", "--pre-refresh-window", "0")
+	s.Assert().Equal(err, nil)
+}
+
+func (s *ConfigTestSuite) TestFailedPopularityValidation() {
+	_, err := s.load("--popularity=false")
+	s.Assert().Equal(err, config.ValidationError{"pre_refresh.enabled needs popularity.enabled"})
+
+	_, err = s.load("--popularity-bucket", "0", "--popularity-window", "604801", "--popularity-expiration", "-1")
+	s.Assert().Equal(err, config.ValidationError{
+		"popularity.bucket_in_sec, popularity.retention_in_sec and popularity.flush_interval_in_sec must be positive",
+		"popularity.window_in_sec must be positive and at most popularity.retention_in_sec",
+		"popularity.expiration_in_sec must not be negative",
+	})
+
+	_, err = s.load("--popularity=false", "--pre-refresh=false", "--popularity-bucket", "0")
+	s.Assert().Equal(err, nil)
+}
+
+func (s *ConfigTestSuite) TestFailedLayoutValidation() {
+	_, err := s.load("--redis-layout", "list")
+	s.Assert().Equal(err, config.ValidationError{
+		`redis.layout: unknown layout "list", want string or hash`,
+	})
+}
+
+func (s *ConfigTestSuite) TestFailedTracingValidation() {
+	_, err := s.load("--tracing-exporter", "file", "--tracing-file", "", "--tracing-sample-ratio", "2")
+	s.Assert().Equal(err, config.ValidationError{
+		"tracing.file is required by the file exporter",
+		"tracing.sample_ratio must be between 0 and 1",
+	})
+}
+
+func (s *ConfigTestSuite) TestFailedMissingFile() {
+	s.file = filepath.Join(s.dir, "missing.json")
+
+	_, err := s.load()
+	s.Assert().NotEqual(err, nil)
+}
+
+func (s *ConfigTestSuite) TestRedacted() {
+	cfg, err := s.load()
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal("[REDACTED]", cfg.Redacted().MarvelAPI.PrivateKey)
+	s.Assert().Equal("", cfg.Redacted().Redis.Password)
+	s.Assert().Equal("file-private", cfg.MarvelAPI.PrivateKey)
+	s.Assert().NotContains(cfg.String(), "file-private")
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Reloader re-reads the configuration when the config file changes or the
+// process receives SIGHUP, and hands the settings that can change at runtime
+// to the registered hooks. Every other setting needs a restart.
+type Reloader struct {
+	loader  *Loader
+	logger  *slog.Logger
+	mu      sync.Mutex
+	current Config
+	hooks   []func(Config)
+}
+
+func NewReloader(l *Loader, current Config, logger *slog.Logger) *Reloader {
+	return &Reloader{
+		loader:  l,
+		logger:  logger,
+		current: current,
+	}
+}
+
+// OnReload registers fn to be called with the new configuration after each
+// successful reload. Hooks run sequentially and must not block.
+func (r *Reloader) OnReload(fn func(Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, fn)
+}
+
+func (r *Reloader) Current() Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload loads the configuration again. An invalid configuration is rejected
+// as a whole and the running one is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.loader.Load()
+	if err != nil {
+		r.logger.Error("Rejected configuration change", "error", err)
+		return err
+	}
+
+	applied := r.current.withReloadable(next)
+	for _, key := range diff(applied, next) {
+		r.logger.Warn("Ignored configuration change, restart to apply it", "key", key)
+	}
+
+	changes := diff(r.current, applied)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	for _, change := range describe(r.current, applied, changes) {
+		r.logger.Info("Applied configuration change", "key", change.key, "old", change.old, "new", change.new)
+	}
+	r.current = applied
+	for _, fn := range r.hooks {
+		fn(applied)
+	}
+
+	return nil
+}
+
+// Watch reloads the configuration on config file changes and on SIGHUP until
+// ctx is done.
+func (r *Reloader) Watch(ctx context.Context) {
+	r.loader.v.OnConfigChange(func(e fsnotify.Event) {
+		if ctx.Err() == nil {
+			_ = r.Reload()
+		}
+	})
+	r.loader.v.WatchConfig()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+			r.logger.Info("SIGHUP received, reloading configuration")
+			_ = r.Reload()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// withReloadable returns c with the settings that can change at runtime taken
+// from next.
+func (c Config) withReloadable(next Config) Config {
+	c.CacheExpirationInSec = next.CacheExpirationInSec
+	c.CacheExpirationJitter = next.CacheExpirationJitter
+	c.NotFoundExpirationInSec = next.NotFoundExpirationInSec
+	c.Popularity.ExpirationInSec = next.Popularity.ExpirationInSec
+	backend := c.RateLimit.Backend
+	c.RateLimit = next.RateLimit
+	c.RateLimit.Backend = backend
+	c.MarvelAPI.TimeoutInSec = next.MarvelAPI.TimeoutInSec
+	c.MarvelAPI.DailyQuota = next.MarvelAPI.DailyQuota
+	c.Features = next.Features
+	c.Log.Level = next.Log.Level
+	c.Log.Components = next.Log.Components
+
+	return c
+}
+
+// diff returns the dotted keys whose values differ between a and b.
+func diff(a, b Config) []string {
+	fa, fb := flatten(a), flatten(b)
+
+	union := map[string]bool{}
+	for key := range fa {
+		union[key] = true
+	}
+	for key := range fb {
+		union[key] = true
+	}
+
+	var keys []string
+	for key := range union {
+		if !reflect.DeepEqual(fa[key], fb[key]) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+type change struct {
+	key string
+	old string
+	new string
+}
+
+func describe(a, b Config, keys []string) []change {
+	fa, fb := flatten(a.Redacted()), flatten(b.Redacted())
+
+	var changes []change
+	for _, key := range keys {
+		changes = append(changes, change{key: key, old: fmt.Sprint(fa[key]), new: fmt.Sprint(fb[key])})
+	}
+
+	return changes
+}
+
+func flatten(c Config) map[string]interface{} {
+	var nested map[string]interface{}
+	json_data, _ := json.Marshal(c)
+	_ = json.Unmarshal(json_data, &nested)
+
+	flat := map[string]interface{}{}
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for key, value := range m {
+			if child, ok := value.(map[string]interface{}); ok {
+				walk(prefix+key+".", child)
+				continue
+			}
+			flat[prefix+key] = value
+		}
+	}
+	walk("", nested)
+
+	return flat
+}
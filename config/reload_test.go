@@ -0,0 +1,112 @@
+package config_test
+
+import (
+	"strings"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/config"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+func (s *ConfigTestSuite) newReloader() (*config.Reloader, *[]config.Config) {
+	loader := config.NewLoader("test")
+	if err := loader.Parse([]string{"--config", s.file}); err != nil {
+		s.T().Fatalf("Error: '%s'", err)
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		s.T().Fatalf("Error: '%s'", err)
+	}
+
+	var applied []config.Config
+	reloader := config.NewReloader(loader, cfg, logger.Discard())
+	reloader.OnReload(func(cfg config.Config) {
+		applied = append(applied, cfg)
+	})
+
+	return reloader, &applied
+}
+
+func (s *ConfigTestSuite) TestSuccessReload() {
+	reloader, applied := s.newReloader()
+	s.writeFile("common.json", strings.Replace(fixture, `"cache_expiration_in_sec": 60`, `"cache_expiration_in_sec": 120`, 1))
+
+	err := reloader.Reload()
+	s.Assert().Equal(err, nil)
+	s.Assert().Len(*applied, 1)
+	s.Assert().Equal(120, (*applied)[0].CacheExpirationInSec)
+	s.Assert().Equal(120, reloader.Current().CacheExpirationInSec)
+}
+
+func (s *ConfigTestSuite) TestSuccessReloadNotFoundExpiration() {
+	reloader, applied := s.newReloader()
+	s.writeFile("common.json", strings.Replace(fixture, `"cache_expiration_in_sec": 60`, `"cache_expiration_in_sec": 60, "not_found_expiration_in_sec": 30`, 1))
+
+	err := reloader.Reload()
+	s.Assert().Equal(err, nil)
+	s.Require().Len(*applied, 1)
+	s.Assert().Equal(30, (*applied)[0].NotFoundExpirationInSec)
+}
+
+func (s *ConfigTestSuite) TestSuccessReloadCacheExpirationJitter() {
+	reloader, applied := s.newReloader()
+	s.writeFile("common.json", strings.Replace(fixture, `"cache_expiration_in_sec": 60`, `"cache_expiration_in_sec": 60, "cache_expiration_jitter": 0.25`, 1))
+
+	err := reloader.Reload()
+	s.Assert().Equal(err, nil)
+	s.Require().Len(*applied, 1)
+	s.Assert().Equal(0.25, (*applied)[0].CacheExpirationJitter)
+}
+
+func (s *ConfigTestSuite) TestSuccessReloadPopularExpiration() {
+	reloader, applied := s.newReloader()
+	s.writeFile("common.json", strings.Replace(fixture, `"cache_expiration_in_sec": 60`, `"cache_expiration_in_sec": 60, "popularity": {"expiration_in_sec": 7200}`, 1))
+
+	err := reloader.Reload()
+	s.Assert().Equal(err, nil)
+	s.Require().Len(*applied, 1)
+	s.Assert().Equal(7200, (*applied)[0].Popularity.ExpirationInSec)
+	s.Assert().True((*applied)[0].Popularity.Enabled)
+}
+
+func (s *ConfigTestSuite) TestSuccessReloadRateLimit() {
+	reloader, applied := s.newReloader()
+	s.writeFile("common.json", strings.Replace(fixture, `"cache_expiration_in_sec": 60`, `"cache_expiration_in_sec": 60, "rate_limit": {"backend": "redis", "per_minute": 30, "burst": 5}`, 1))
+
+	err := reloader.Reload()
+	s.Assert().Equal(err, nil)
+	s.Require().Len(*applied, 1)
+	s.Assert().Equal(int64(30), (*applied)[0].RateLimit.PerMinute)
+	s.Assert().Equal(int64(5), (*applied)[0].RateLimit.Burst)
+	s.Assert().Equal("none", (*applied)[0].RateLimit.Backend)
+}
+
+func (s *ConfigTestSuite) TestSkipReloadUnchanged() {
+	reloader, applied := s.newReloader()
+
+	err := reloader.Reload()
+	s.Assert().Equal(err, nil)
+	s.Assert().Len(*applied, 0)
+}
+
+func (s *ConfigTestSuite) TestFailedReloadInvalid() {
+	reloader, applied := s.newReloader()
+	s.writeFile("common.json", strings.Replace(fixture, `"cache_expiration_in_sec": 60`, `"cache_expiration_in_sec": -1`, 1))
+
+	err := reloader.Reload()
+	s.Assert().NotEqual(err, nil)
+	s.Assert().Len(*applied, 0)
+	s.Assert().Equal(60, reloader.Current().CacheExpirationInSec)
+}
+
+func (s *ConfigTestSuite) TestSkipReloadStructural() {
+	reloader, applied := s.newReloader()
+	content := strings.Replace(fixture, `"host": "redis"`, `"host": "other"`, 1)
+	content = strings.Replace(content, `"timeout_in_sec": 10`, `"timeout_in_sec": 20`, 1)
+	s.writeFile("common.json", content)
+
+	err := reloader.Reload()
+	s.Assert().Equal(err, nil)
+	s.Assert().Len(*applied, 1)
+	s.Assert().Equal("redis", (*applied)[0].Redis.Host)
+	s.Assert().Equal(20, (*applied)[0].MarvelAPI.TimeoutInSec)
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// APIKey lets a consumer call the character endpoints. RateLimit is the
+// number of requests allowed per minute and DailyQuota per day, UTC. The
+// secret itself is never stored, only its SHA-256 in SecretHash.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	SecretHash string     `json:"-"`
+	RateLimit  int64      `json:"rateLimit"`
+	DailyQuota int64      `json:"dailyQuota"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// IssuedAPIKey is a new key with its token, which is only ever shown once.
+type IssuedAPIKey struct {
+	APIKey
+	Token string `json:"token"`
+}
+
+// APIKeyUsage counts the requests made with a key in the current minute, the
+// current day and overall. RetryAfter is set when a limit was hit.
+type APIKeyUsage struct {
+	Minute     int64         `json:"minute"`
+	Today      int64         `json:"today"`
+	Total      int64         `json:"total"`
+	RetryAfter time.Duration `json:"-"`
+}
+
+type APIKeyUsecase interface {
+	Issue(ctx context.Context, name string, rateLimit, dailyQuota int64) (IssuedAPIKey, error)
+	Revoke(ctx context.Context, id string) (APIKey, error)
+	Get(ctx context.Context, id string) (APIKey, error)
+	List(ctx context.Context) ([]APIKey, error)
+	Usage(ctx context.Context, id string) (APIKeyUsage, error)
+	Authenticate(ctx context.Context, token string) (APIKey, error)
+	Consume(ctx context.Context, key APIKey) (APIKeyUsage, error)
+}
+
+type APIKeyRepository interface {
+	Store(ctx context.Context, key APIKey) error
+	Get(ctx context.Context, id string) (APIKey, error)
+	List(ctx context.Context) ([]APIKey, error)
+	Count(ctx context.Context, id string, now time.Time) (APIKeyUsage, error)
+	Usage(ctx context.Context, id string, now time.Time) (APIKeyUsage, error)
+}
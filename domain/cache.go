@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// CacheEntry describes a cache key. Kind is "character", "page" or "index",
+// the sorted sets of the hash layout. TTL is in seconds, -1 without expiry.
+// An empty entry caches a resource Marvel API does not have. Encoding is the
+// format and compression of the value, like "msgpack+zstd", or "hash" and
+// "zset" for the hash layout. The size of an index is its member count.
+// Value is only set when the entry is looked up by key, as JSON whatever its
+// encoding.
+type CacheEntry struct {
+	Key       string          `json:"key"`
+	Kind      string          `json:"kind"`
+	TTL       int64           `json:"ttl"`
+	Size      int             `json:"size"`
+	Encoding  string          `json:"encoding,omitempty"`
+	FetchedAt *time.Time      `json:"fetchedAt,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+}
+
+// CacheKeys is a page of a key listing. Cursor resumes the listing and is
+// "0" once it is done.
+type CacheKeys struct {
+	Keys   []CacheEntry `json:"keys"`
+	Cursor string       `json:"cursor"`
+}
+
+// CachePurge lists the keys a purge deleted, or would delete on a dry run.
+type CachePurge struct {
+	Keys    []string `json:"keys"`
+	Deleted int64    `json:"deleted"`
+	DryRun  bool     `json:"dryRun"`
+}
+
+// CacheKindStats aggregates the keys of a kind. TTLs are in seconds, keys
+// without expiry are left out of them.
+type CacheKindStats struct {
+	Keys            int64      `json:"keys"`
+	Empty           int64      `json:"empty"`
+	Bytes           int64      `json:"bytes"`
+	MinTTL          int64      `json:"minTtl"`
+	MaxTTL          int64      `json:"maxTtl"`
+	OldestFetchedAt *time.Time `json:"oldestFetchedAt,omitempty"`
+	NewestFetchedAt *time.Time `json:"newestFetchedAt,omitempty"`
+}
+
+type CacheStats struct {
+	Characters CacheKindStats `json:"characters"`
+	Pages      CacheKindStats `json:"pages"`
+}
+
+type CacheUsecase interface {
+	List(ctx context.Context, match string, cursor uint64, count int64) (CacheKeys, error)
+	Get(ctx context.Context, key string) (CacheEntry, error)
+	RefreshCharacter(ctx context.Context, id int) (CacheEntry, error)
+	RefreshPage(ctx context.Context, page int) (CacheEntry, error)
+	Purge(ctx context.Context, match string, dryRun bool) (CachePurge, error)
+	Stats(ctx context.Context) (CacheStats, error)
+}
+
+type CacheRepository interface {
+	Scan(ctx context.Context, match string, cursor uint64, count int64) ([]CacheEntry, uint64, error)
+	Get(ctx context.Context, key string) (CacheEntry, error)
+	Delete(ctx context.Context, keys ...string) (int64, error)
+}
@@ -5,24 +5,86 @@ import (
 	"time"
 )
 
+// Character is a Marvel character as cached. ExpiresAt is when the cached
+// copy expires, zero when unknown. It is not part of the cached value.
 type Character struct {
 	ID          uint      `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	FetchedAt   time.Time `json:"fetchedAt"`
+	ExpiresAt   time.Time `json:"-"`
+}
+
+// CharacterBatch holds the characters found by a batch lookup, in the order
+// they were asked for, the IDs that are not cached yet and the IDs Marvel API
+// does not know.
+type CharacterBatch struct {
+	Characters []Character `json:"characters"`
+	Missing    []int       `json:"missing"`
+	NotFound   []int       `json:"notFound,omitempty"`
+}
+
+// CharacterPage is a page of characters with the characters themselves
+// rather than their IDs. Partial is set when some of them are missing.
+type CharacterPage struct {
+	Page int `json:"page"`
+	CharacterBatch
+	Partial bool `json:"partial"`
+}
+
+// Orders of the characters listed by Range: by name, in the order of Marvel
+// API, or by modification date, the latest first.
+const (
+	OrderByName     = "name"
+	OrderByModified = "modified"
+)
+
+// Hit counts the lookups of a character, or of a page, within a window.
+type Hit struct {
+	ID   int   `json:"id"`
+	Hits int64 `json:"hits"`
+}
+
+// PopularCharacters are the characters looked up the most within Window, the
+// most looked up first.
+type PopularCharacters struct {
+	Window     string `json:"window"`
+	Characters []Hit  `json:"characters"`
 }
 
 type CharacterUsecase interface {
 	Fetch(ctx context.Context, page int) ([]int, error)
+	FetchCharacters(ctx context.Context, page int) (CharacterPage, error)
 	GetByID(ctx context.Context, id int) (Character, error)
+	GetByIDs(ctx context.Context, ids []int) (CharacterBatch, error)
+	Popular(ctx context.Context, window time.Duration, limit int) (PopularCharacters, error)
+	Range(ctx context.Context, order string, offset, limit int) ([]int, error)
+	RangeCharacters(ctx context.Context, order string, offset, limit int) (CharacterBatch, error)
 }
 
+// CharacterReadRepository reads the cache. Range lists limit IDs of the
+// cached characters by order from offset on, which only the hash layout can.
 type CharacterReadRepository interface {
 	Fetch(ctx context.Context, page int) ([]int, error)
 	GetByID(ctx context.Context, id int) (Character, error)
+	GetByIDs(ctx context.Context, ids []int) (CharacterBatch, error)
+	Range(ctx context.Context, order string, offset, limit int) ([]int, error)
 }
 
+// CharacterWriteRepository caches characters from Marvel API. The Store
+// methods leave cached copies alone, the Refresh ones overwrite them.
 type CharacterWriteRepository interface {
 	StoreByPage(ctx context.Context, page int) error
 	StoreByID(ctx context.Context, id int) error
+	RefreshByPage(ctx context.Context, page int) error
+	RefreshByID(ctx context.Context, id int) error
+}
+
+// PopularityRepository counts the lookups of the cached characters and pages.
+// Top returns up to limit characters or pages, by kind, looked up at least
+// minHits times within window, the most looked up first. Windows are kept up
+// to Retention.
+type PopularityRepository interface {
+	Top(ctx context.Context, kind string, window time.Duration, limit int, minHits int64) ([]Hit, error)
+	Retention() time.Duration
 }
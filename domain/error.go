@@ -2,10 +2,72 @@ package domain
 
 import "errors"
 
+// ErrorCode tells the kind of a domain error apart, independently of its
+// message.
+type ErrorCode string
+
+const (
+	CodeInternal     ErrorCode = "internal"
+	CodeNotFound     ErrorCode = "not_found"
+	CodeBadRequest   ErrorCode = "bad_request"
+	CodeNotCached    ErrorCode = "not_cached"
+	CodeCacheExists  ErrorCode = "cache_exists"
+	CodeUnauthorized ErrorCode = "unauthorized"
+	CodeRateLimited  ErrorCode = "rate_limited"
+	CodeForbidden    ErrorCode = "forbidden"
+)
+
+// Error is a domain error. Errors with the same code match with errors.Is,
+// so the sentinels below still match once wrapped or given another message.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
 var (
-	ErrInternalServerError = errors.New("Internal Server Error")
-	ErrNotFound            = errors.New("Resource not found")
-	ErrBadRequest          = errors.New("Bad request error")
-	ErrCacheKeyEmpty       = errors.New("Resource not found")
-	ErrCacheKeyExists      = errors.New("Cache exists. Not writing to cache")
+	ErrInternalServerError = NewError(CodeInternal, "Internal Server Error")
+	ErrNotFound            = NewError(CodeNotFound, "Resource not found")
+	ErrBadRequest          = NewError(CodeBadRequest, "Bad request error")
+	ErrCacheKeyEmpty       = NewError(CodeNotCached, "Resource not cached yet")
+	ErrCacheKeyExists      = NewError(CodeCacheExists, "Cache exists. Not writing to cache")
+	ErrUnauthorized        = NewError(CodeUnauthorized, "Missing or invalid credentials")
+	ErrForbidden           = NewError(CodeForbidden, "Missing the scope of this resource")
+	ErrRateLimited         = NewError(CodeRateLimited, "Rate limit exceeded")
+	ErrQuotaExceeded       = NewError(CodeRateLimited, "Daily quota exceeded")
 )
+
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	return &Error{Code: e.Code, Message: e.Message, Err: err}
+}
+
+// CodeOf returns the code of the first domain error in the chain of err, and
+// CodeInternal for any other error.
+func CodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,42 @@
+package domain_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+)
+
+type ErrorTestSuite struct {
+	suite.Suite
+}
+
+func TestError(t *testing.T) {
+	suite.Run(t, new(ErrorTestSuite))
+}
+
+func (s *ErrorTestSuite) TestIsByCode() {
+	err := fmt.Errorf("GetByID: %w", domain.NewError(domain.CodeNotFound, "Character 1 not found"))
+
+	s.Assert().True(errors.Is(err, domain.ErrNotFound))
+	s.Assert().False(errors.Is(err, domain.ErrCacheKeyEmpty))
+	s.Assert().False(errors.Is(domain.ErrCacheKeyEmpty, domain.ErrNotFound))
+}
+
+func (s *ErrorTestSuite) TestWrap() {
+	cause := errors.New("connection refused")
+	err := domain.ErrInternalServerError.Wrap(cause)
+
+	s.Assert().True(errors.Is(err, domain.ErrInternalServerError))
+	s.Assert().True(errors.Is(err, cause))
+	s.Assert().Equal("Internal Server Error: connection refused", err.Error())
+	s.Assert().Equal("Internal Server Error", domain.ErrInternalServerError.Error())
+}
+
+func (s *ErrorTestSuite) TestCodeOf() {
+	s.Assert().Equal(domain.CodeNotCached, domain.CodeOf(fmt.Errorf("wrapped: %w", domain.ErrCacheKeyEmpty)))
+	s.Assert().Equal(domain.CodeInternal, domain.CodeOf(errors.New("SomeError")))
+}
@@ -57,3 +57,47 @@ func (_m *CharacterReadRepository) GetByID(ctx context.Context, id int) (domain.
 
 	return r0, r1
 }
+
+// GetByIDs provides a mock function with given fields: ctx, ids
+func (_m *CharacterReadRepository) GetByIDs(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 domain.CharacterBatch
+	if rf, ok := ret.Get(0).(func(context.Context, []int) domain.CharacterBatch); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Get(0).(domain.CharacterBatch)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Range provides a mock function with given fields: ctx, order, offset, limit
+func (_m *CharacterReadRepository) Range(ctx context.Context, order string, offset int, limit int) ([]int, error) {
+	ret := _m.Called(ctx, order, offset, limit)
+
+	var r0 []int
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []int); ok {
+		r0 = rf(ctx, order, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) error); ok {
+		r1 = rf(ctx, order, offset, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
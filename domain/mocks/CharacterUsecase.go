@@ -7,6 +7,8 @@ import (
 
 	domain "github.com/hezbymuhammad/golang-marvel-demo/domain"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // CharacterUsecase is an autogenerated mock type for the CharacterUsecase type
@@ -37,6 +39,27 @@ func (_m *CharacterUsecase) Fetch(ctx context.Context, page int) ([]int, error)
 	return r0, r1
 }
 
+// FetchCharacters provides a mock function with given fields: ctx, page
+func (_m *CharacterUsecase) FetchCharacters(ctx context.Context, page int) (domain.CharacterPage, error) {
+	ret := _m.Called(ctx, page)
+
+	var r0 domain.CharacterPage
+	if rf, ok := ret.Get(0).(func(context.Context, int) domain.CharacterPage); ok {
+		r0 = rf(ctx, page)
+	} else {
+		r0 = ret.Get(0).(domain.CharacterPage)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, page)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByID provides a mock function with given fields: ctx, id
 func (_m *CharacterUsecase) GetByID(ctx context.Context, id int) (domain.Character, error) {
 	ret := _m.Called(ctx, id)
@@ -57,3 +80,89 @@ func (_m *CharacterUsecase) GetByID(ctx context.Context, id int) (domain.Charact
 
 	return r0, r1
 }
+
+// GetByIDs provides a mock function with given fields: ctx, ids
+func (_m *CharacterUsecase) GetByIDs(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 domain.CharacterBatch
+	if rf, ok := ret.Get(0).(func(context.Context, []int) domain.CharacterBatch); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Get(0).(domain.CharacterBatch)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Popular provides a mock function with given fields: ctx, window, limit
+func (_m *CharacterUsecase) Popular(ctx context.Context, window time.Duration, limit int) (domain.PopularCharacters, error) {
+	ret := _m.Called(ctx, window, limit)
+
+	var r0 domain.PopularCharacters
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration, int) domain.PopularCharacters); ok {
+		r0 = rf(ctx, window, limit)
+	} else {
+		r0 = ret.Get(0).(domain.PopularCharacters)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Duration, int) error); ok {
+		r1 = rf(ctx, window, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Range provides a mock function with given fields: ctx, order, offset, limit
+func (_m *CharacterUsecase) Range(ctx context.Context, order string, offset int, limit int) ([]int, error) {
+	ret := _m.Called(ctx, order, offset, limit)
+
+	var r0 []int
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []int); ok {
+		r0 = rf(ctx, order, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) error); ok {
+		r1 = rf(ctx, order, offset, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RangeCharacters provides a mock function with given fields: ctx, order, offset, limit
+func (_m *CharacterUsecase) RangeCharacters(ctx context.Context, order string, offset int, limit int) (domain.CharacterBatch, error) {
+	ret := _m.Called(ctx, order, offset, limit)
+
+	var r0 domain.CharacterBatch
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) domain.CharacterBatch); ok {
+		r0 = rf(ctx, order, offset, limit)
+	} else {
+		r0 = ret.Get(0).(domain.CharacterBatch)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) error); ok {
+		r1 = rf(ctx, order, offset, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
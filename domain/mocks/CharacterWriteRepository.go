@@ -13,6 +13,34 @@ type CharacterWriteRepository struct {
 	mock.Mock
 }
 
+// RefreshByID provides a mock function with given fields: ctx, id
+func (_m *CharacterWriteRepository) RefreshByID(ctx context.Context, id int) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefreshByPage provides a mock function with given fields: ctx, page
+func (_m *CharacterWriteRepository) RefreshByPage(ctx context.Context, page int) error {
+	ret := _m.Called(ctx, page)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, page)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // StoreByID provides a mock function with given fields: ctx, id
 func (_m *CharacterWriteRepository) StoreByID(ctx context.Context, id int) error {
 	ret := _m.Called(ctx, id)
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type APIKeyHandler struct {
+	Usecase domain.APIKeyUsecase
+	Logger  *slog.Logger
+}
+
+// IssueRequest is the body of POST /admin/api-keys. Limits left at 0 take
+// the configured defaults.
+type IssueRequest struct {
+	Name       string `json:"name"`
+	RateLimit  int64  `json:"rateLimit"`
+	DailyQuota int64  `json:"dailyQuota"`
+}
+
+// APIKeyResponse is a key with its usage.
+type APIKeyResponse struct {
+	domain.APIKey
+	Usage domain.APIKeyUsage `json:"usage"`
+}
+
+// NewAPIKeyHandler registers the endpoints managing API keys under
+// /admin/api-keys, behind middleware, which is expected to authenticate the
+// caller.
+func NewAPIKeyHandler(e *echo.Echo, u domain.APIKeyUsecase, logger *slog.Logger, middleware ...echo.MiddlewareFunc) *APIKeyHandler {
+	handler := &APIKeyHandler{
+		Usecase: u,
+		Logger:  logger,
+	}
+	g := e.Group("/admin/api-keys", middleware...)
+	g.POST("", handler.Issue)
+	g.GET("", handler.List)
+	g.GET("/:id", handler.Get)
+	g.DELETE("/:id", handler.Revoke)
+
+	return handler
+}
+
+func (h *APIKeyHandler) Issue(c echo.Context) error {
+	var req IssueRequest
+	if err := c.Bind(&req); err != nil {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "body must be a JSON object with a name"))
+	}
+
+	issued, err := h.Usecase.Issue(c.Request().Context(), req.Name, req.RateLimit, req.DailyQuota)
+	if err != nil {
+		return h.error(c, err, slog.String("name", req.Name))
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusCreated, issued)
+}
+
+func (h *APIKeyHandler) List(c echo.Context) error {
+	keys, err := h.Usecase.List(c.Request().Context())
+	if err != nil {
+		return h.error(c, err)
+	}
+
+	return h.json(c, keys)
+}
+
+func (h *APIKeyHandler) Get(c echo.Context) error {
+	id := c.Param("id")
+
+	key, err := h.Usecase.Get(c.Request().Context(), id)
+	if err != nil {
+		return h.error(c, err, slog.String("id", id))
+	}
+	usage, err := h.Usecase.Usage(c.Request().Context(), id)
+	if err != nil {
+		return h.error(c, err, slog.String("id", id))
+	}
+
+	return h.json(c, APIKeyResponse{APIKey: key, Usage: usage})
+}
+
+// Revoke disables the key. Revoking a revoked key changes nothing.
+func (h *APIKeyHandler) Revoke(c echo.Context) error {
+	id := c.Param("id")
+
+	key, err := h.Usecase.Revoke(c.Request().Context(), id)
+	if err != nil {
+		return h.error(c, err, slog.String("id", id))
+	}
+
+	return h.json(c, key)
+}
+
+func (h *APIKeyHandler) json(c echo.Context, v interface{}) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, v)
+}
+
+// error logs server side failures, which the response does not detail, then
+// writes err as a problem.
+func (h *APIKeyHandler) error(c echo.Context, err error, attrs ...slog.Attr) error {
+	if problem.StatusOf(err) == http.StatusInternalServerError {
+		h.Logger.LogAttrs(c.Request().Context(), slog.LevelError, "Request failed", append(attrs, slog.Any("error", err))...)
+	}
+
+	return problem.Error(c, err)
+}
@@ -0,0 +1,94 @@
+package http_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	apikeyHttp "github.com/hezbymuhammad/golang-marvel-demo/model/apikey/delivery/http"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type APIKeyHandlerTestSuite struct {
+	suite.Suite
+	e       *echo.Echo
+	usecase *mocks.APIKeyUsecase
+}
+
+func TestAPIKeyHandler(t *testing.T) {
+	suite.Run(t, new(APIKeyHandlerTestSuite))
+}
+
+func (s *APIKeyHandlerTestSuite) SetupTest() {
+	s.e = echo.New()
+	s.e.HTTPErrorHandler = problem.HTTPErrorHandler
+	s.usecase = new(mocks.APIKeyUsecase)
+	apikeyHttp.NewAPIKeyHandler(s.e, s.usecase, logger.Discard(), auth.AdminToken("s3cret"))
+}
+
+func (s *APIKeyHandlerTestSuite) serve(method, target string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	if body != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s *APIKeyHandlerTestSuite) TestSuccessIssue() {
+	createdAt := time.Date(2021, 7, 21, 0, 0, 0, 0, time.UTC)
+	issued := domain.IssuedAPIKey{
+		APIKey: domain.APIKey{ID: "abc", Name: "lorem", SecretHash: "hash", RateLimit: 60, DailyQuota: 10, CreatedAt: createdAt},
+		Token:  "mk_abc.secret",
+	}
+	s.usecase.On("Issue", mock.Anything, "lorem", int64(0), int64(10)).Return(issued, nil).Once()
+
+	rec := s.serve(echo.POST, "/admin/api-keys", strings.NewReader(`{"name":"lorem","dailyQuota":10}`))
+	s.Assert().Equal(http.StatusCreated, rec.Code)
+	s.Assert().JSONEq(`{"id":"abc","name":"lorem","rateLimit":60,"dailyQuota":10,"createdAt":"2021-07-21T00:00:00Z","token":"mk_abc.secret"}`, rec.Body.String())
+}
+
+func (s *APIKeyHandlerTestSuite) TestFailedIssue() {
+	rec := s.serve(echo.POST, "/admin/api-keys", strings.NewReader(`[`))
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+	s.usecase.AssertNotCalled(s.T(), "Issue", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *APIKeyHandlerTestSuite) TestSuccessGet() {
+	s.usecase.On("Get", mock.Anything, "abc").Return(domain.APIKey{ID: "abc", Name: "lorem"}, nil).Once()
+	s.usecase.On("Usage", mock.Anything, "abc").Return(domain.APIKeyUsage{Minute: 1, Today: 2, Total: 3}, nil).Once()
+
+	rec := s.serve(echo.GET, "/admin/api-keys/abc", nil)
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Contains(rec.Body.String(), `"usage":{"minute":1,"today":2,"total":3}`)
+}
+
+func (s *APIKeyHandlerTestSuite) TestFailedRevokeNotFound() {
+	s.usecase.On("Revoke", mock.Anything, "missing").Return(domain.APIKey{}, domain.ErrNotFound).Once()
+
+	rec := s.serve(echo.DELETE, "/admin/api-keys/missing", nil)
+	s.Assert().Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *APIKeyHandlerTestSuite) TestUnauthorized() {
+	req := httptest.NewRequest(echo.GET, "/admin/api-keys", nil)
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	s.Assert().Equal(http.StatusUnauthorized, rec.Code)
+	s.usecase.AssertNotCalled(s.T(), "List", mock.Anything)
+}
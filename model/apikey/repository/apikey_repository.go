@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+)
+
+const (
+	dayKeyLayout  = "2006-01-02"
+	minuteKeepFor = 2 * time.Minute
+	dayKeepFor    = 8 * 24 * time.Hour
+)
+
+// APIKeyRepository stores API keys under the key prefix of the cache, apart
+// from its versions so that purging or upgrading the cache keeps them.
+type APIKeyRepository struct {
+	Client redis.Cmdable
+	Keys   cachekey.Builder
+	Logger *slog.Logger
+}
+
+// record is how a key is stored. Unlike the domain.APIKey JSON, it keeps the
+// hash of the secret.
+type record struct {
+	domain.APIKey
+	SecretHash string `json:"secretHash"`
+}
+
+func NewAPIKeyRepository(Conn redis.Cmdable, keys cachekey.Builder, logger *slog.Logger) domain.APIKeyRepository {
+	return &APIKeyRepository{
+		Client: Conn,
+		Keys:   keys,
+		Logger: logger,
+	}
+}
+
+// Store creates or replaces key.
+func (r *APIKeyRepository) Store(ctx context.Context, key domain.APIKey) error {
+	data, err := json.Marshal(record{APIKey: key, SecretHash: key.SecretHash})
+	if err != nil {
+		return domain.ErrInternalServerError.Wrap(err)
+	}
+
+	pipe := r.Client.TxPipeline()
+	pipe.Set(ctx, r.Keys.APIKey(key.ID), data, 0)
+	pipe.SAdd(ctx, r.Keys.APIKeyIndex(), key.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.Logger.ErrorContext(ctx, "Storing API key failed", "id", key.ID, "error", err)
+		return domain.ErrInternalServerError.Wrap(err)
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) Get(ctx context.Context, id string) (domain.APIKey, error) {
+	val, err := r.Client.Get(ctx, r.Keys.APIKey(id)).Result()
+	if err == redis.Nil {
+		return domain.APIKey{}, domain.ErrNotFound
+	}
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Reading API key failed", "id", id, "error", err)
+		return domain.APIKey{}, domain.ErrInternalServerError.Wrap(err)
+	}
+
+	return decode(val)
+}
+
+// List returns every key, revoked ones included, in no particular order.
+func (r *APIKeyRepository) List(ctx context.Context) ([]domain.APIKey, error) {
+	ids, err := r.Client.SMembers(ctx, r.Keys.APIKeyIndex()).Result()
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Listing API keys failed", "error", err)
+		return nil, domain.ErrInternalServerError.Wrap(err)
+	}
+	if len(ids) == 0 {
+		return []domain.APIKey{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.Keys.APIKey(id)
+	}
+	vals, err := r.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Listing API keys failed", "error", err)
+		return nil, domain.ErrInternalServerError.Wrap(err)
+	}
+
+	apiKeys := make([]domain.APIKey, 0, len(vals))
+	for _, val := range vals {
+		s, ok := val.(string)
+		if !ok {
+			continue
+		}
+		key, err := decode(s)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, key)
+	}
+
+	return apiKeys, nil
+}
+
+// Count records a request made with key id at now and returns the updated
+// usage. The minute and day counters expire on their own.
+func (r *APIKeyRepository) Count(ctx context.Context, id string, now time.Time) (domain.APIKeyUsage, error) {
+	minuteKey, dayKey := r.windowKeys(id, now)
+
+	pipe := r.Client.TxPipeline()
+	minute := pipe.Incr(ctx, minuteKey)
+	pipe.Expire(ctx, minuteKey, minuteKeepFor)
+	today := pipe.Incr(ctx, dayKey)
+	pipe.Expire(ctx, dayKey, dayKeepFor)
+	total := pipe.Incr(ctx, r.Keys.APIKeyUsage("total", id))
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.Logger.ErrorContext(ctx, "Counting API key usage failed", "id", id, "error", err)
+		return domain.APIKeyUsage{}, domain.ErrInternalServerError.Wrap(err)
+	}
+
+	return domain.APIKeyUsage{Minute: minute.Val(), Today: today.Val(), Total: total.Val()}, nil
+}
+
+// Usage returns the usage of key id at now without counting a request.
+func (r *APIKeyRepository) Usage(ctx context.Context, id string, now time.Time) (domain.APIKeyUsage, error) {
+	minuteKey, dayKey := r.windowKeys(id, now)
+
+	vals, err := r.Client.MGet(ctx, minuteKey, dayKey, r.Keys.APIKeyUsage("total", id)).Result()
+	if err != nil {
+		r.Logger.ErrorContext(ctx, "Reading API key usage failed", "id", id, "error", err)
+		return domain.APIKeyUsage{}, domain.ErrInternalServerError.Wrap(err)
+	}
+
+	counts := make([]int64, len(vals))
+	for i, val := range vals {
+		if s, ok := val.(string); ok {
+			counts[i], _ = strconv.ParseInt(s, 10, 64)
+		}
+	}
+
+	return domain.APIKeyUsage{Minute: counts[0], Today: counts[1], Total: counts[2]}, nil
+}
+
+func (r *APIKeyRepository) windowKeys(id string, now time.Time) (string, string) {
+	now = now.UTC()
+	minute := r.Keys.APIKeyUsage("minute", id) + "-" + strconv.FormatInt(now.Unix()/60, 10)
+	day := r.Keys.APIKeyUsage("day", id) + "-" + now.Format(dayKeyLayout)
+
+	return minute, day
+}
+
+func decode(val string) (domain.APIKey, error) {
+	var r record
+	if err := json.Unmarshal([]byte(val), &r); err != nil {
+		return domain.APIKey{}, domain.ErrInternalServerError.Wrap(err)
+	}
+	r.APIKey.SecretHash = r.SecretHash
+
+	return r.APIKey, nil
+}
@@ -0,0 +1,81 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/apikey/repository"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+type APIKeyRepositoryTestSuite struct {
+	suite.Suite
+	mr   *miniredis.Miniredis
+	repo domain.APIKeyRepository
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	suite.Run(t, new(APIKeyRepositoryTestSuite))
+}
+
+func (s *APIKeyRepositoryTestSuite) SetupTest() {
+	mr, err := miniredis.Run()
+	s.Require().NoError(err)
+	s.mr = mr
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s.repo = repository.NewAPIKeyRepository(client, cachekey.New(cachekey.DefaultPrefix), logger.Discard())
+}
+
+func (s *APIKeyRepositoryTestSuite) TearDownTest() {
+	s.mr.Close()
+}
+
+func (s *APIKeyRepositoryTestSuite) TestSuccessStoreAndGet() {
+	key := domain.APIKey{ID: "abc", Name: "lorem", SecretHash: "hash", RateLimit: 60, DailyQuota: 1000, CreatedAt: time.Date(2021, 7, 21, 0, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.repo.Store(context.Background(), key))
+
+	got, err := s.repo.Get(context.Background(), "abc")
+	s.Require().NoError(err)
+	s.Assert().Equal(key, got)
+
+	all, err := s.repo.List(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Equal([]domain.APIKey{key}, all)
+}
+
+func (s *APIKeyRepositoryTestSuite) TestFailedGetNotFound() {
+	_, err := s.repo.Get(context.Background(), "missing")
+	s.Assert().ErrorIs(err, domain.ErrNotFound)
+
+	all, err := s.repo.List(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Empty(all)
+}
+
+func (s *APIKeyRepositoryTestSuite) TestSuccessCount() {
+	now := time.Date(2021, 7, 21, 10, 8, 56, 0, time.UTC)
+
+	_, err := s.repo.Count(context.Background(), "abc", now)
+	s.Require().NoError(err)
+	usage, err := s.repo.Count(context.Background(), "abc", now)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.APIKeyUsage{Minute: 2, Today: 2, Total: 2}, usage)
+
+	usage, err = s.repo.Usage(context.Background(), "abc", now.Add(time.Minute))
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.APIKeyUsage{Minute: 0, Today: 2, Total: 2}, usage)
+
+	usage, err = s.repo.Usage(context.Background(), "abc", now.Add(24*time.Hour))
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.APIKeyUsage{Minute: 0, Today: 0, Total: 2}, usage)
+
+	s.Assert().True(s.mr.TTL("marvel-auth-apikey-day-abc-2021-07-21") > 0)
+}
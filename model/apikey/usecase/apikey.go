@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+)
+
+// TokenPrefix starts every API key token, which reads TokenPrefix, the key
+// ID, a dot and the secret.
+const TokenPrefix = "mk_"
+
+const (
+	idBytes     = 8
+	secretBytes = 32
+)
+
+type apiKeyUsecase struct {
+	apiKeyRepo        domain.APIKeyRepository
+	defaultRateLimit  int64
+	defaultDailyQuota int64
+	contextTimeout    time.Duration
+	logger            *slog.Logger
+}
+
+// NewAPIKeyUsecase issues keys with rateLimit requests per minute and
+// dailyQuota requests per day unless told otherwise.
+func NewAPIKeyUsecase(ar domain.APIKeyRepository, rateLimit, dailyQuota int64, timeout time.Duration, logger *slog.Logger) domain.APIKeyUsecase {
+	return &apiKeyUsecase{
+		apiKeyRepo:        ar,
+		defaultRateLimit:  rateLimit,
+		defaultDailyQuota: dailyQuota,
+		contextTimeout:    timeout,
+		logger:            logger,
+	}
+}
+
+// Issue creates a key. Limits of 0 take the defaults. The returned token is
+// the only copy of the secret.
+func (au *apiKeyUsecase) Issue(c context.Context, name string, rateLimit, dailyQuota int64) (domain.IssuedAPIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return domain.IssuedAPIKey{}, domain.NewError(domain.CodeBadRequest, "name is required")
+	}
+	if rateLimit < 0 || dailyQuota < 0 {
+		return domain.IssuedAPIKey{}, domain.NewError(domain.CodeBadRequest, "rateLimit and dailyQuota must not be negative")
+	}
+	if rateLimit == 0 {
+		rateLimit = au.defaultRateLimit
+	}
+	if dailyQuota == 0 {
+		dailyQuota = au.defaultDailyQuota
+	}
+
+	id, err := random(idBytes, hex.EncodeToString)
+	if err != nil {
+		return domain.IssuedAPIKey{}, domain.ErrInternalServerError.Wrap(err)
+	}
+	secret, err := random(secretBytes, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return domain.IssuedAPIKey{}, domain.ErrInternalServerError.Wrap(err)
+	}
+
+	key := domain.APIKey{
+		ID:         id,
+		Name:       name,
+		SecretHash: hash(secret),
+		RateLimit:  rateLimit,
+		DailyQuota: dailyQuota,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	if err := au.apiKeyRepo.Store(ctx, key); err != nil {
+		return domain.IssuedAPIKey{}, err
+	}
+	au.logger.InfoContext(ctx, "API key issued", "id", id, "name", name)
+
+	return domain.IssuedAPIKey{APIKey: key, Token: TokenPrefix + id + "." + secret}, nil
+}
+
+// Revoke disables a key for good. The key is kept, with its usage, so that
+// it still shows up in listings.
+func (au *apiKeyUsecase) Revoke(c context.Context, id string) (domain.APIKey, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	key, err := au.apiKeyRepo.Get(ctx, id)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+	if err := au.apiKeyRepo.Store(ctx, key); err != nil {
+		return domain.APIKey{}, err
+	}
+	au.logger.InfoContext(ctx, "API key revoked", "id", id, "name", key.Name)
+
+	return key, nil
+}
+
+func (au *apiKeyUsecase) Get(c context.Context, id string) (domain.APIKey, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	return au.apiKeyRepo.Get(ctx, id)
+}
+
+func (au *apiKeyUsecase) List(c context.Context) ([]domain.APIKey, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	return au.apiKeyRepo.List(ctx)
+}
+
+func (au *apiKeyUsecase) Usage(c context.Context, id string) (domain.APIKeyUsage, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	if _, err := au.apiKeyRepo.Get(ctx, id); err != nil {
+		return domain.APIKeyUsage{}, err
+	}
+
+	return au.apiKeyRepo.Usage(ctx, id, time.Now())
+}
+
+// Authenticate returns the key of token. Malformed, unknown and revoked
+// tokens all fail with ErrUnauthorized so that callers cannot tell them
+// apart.
+func (au *apiKeyUsecase) Authenticate(c context.Context, token string) (domain.APIKey, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, TokenPrefix), ".")
+	if !strings.HasPrefix(token, TokenPrefix) || !ok || id == "" || secret == "" {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	key, err := au.apiKeyRepo.Get(ctx, id)
+	if domain.CodeOf(err) == domain.CodeNotFound {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(key.SecretHash)) != 1 || key.RevokedAt != nil {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+
+	return key, nil
+}
+
+// Consume counts a request made with key and fails it once the rate limit of
+// the minute or the quota of the day is used up. Rejected requests count
+// too, so a client retrying in a loop stays rejected.
+func (au *apiKeyUsecase) Consume(c context.Context, key domain.APIKey) (domain.APIKeyUsage, error) {
+	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	usage, err := au.apiKeyRepo.Count(ctx, key.ID, now)
+	if err != nil {
+		return domain.APIKeyUsage{}, err
+	}
+
+	switch {
+	case key.DailyQuota > 0 && usage.Today > key.DailyQuota:
+		usage.RetryAfter = now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+		return usage, domain.ErrQuotaExceeded
+	case key.RateLimit > 0 && usage.Minute > key.RateLimit:
+		usage.RetryAfter = now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+		return usage, domain.ErrRateLimited
+	}
+
+	return usage, nil
+}
+
+func random(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encode(b), nil
+}
+
+// hash is enough for secrets of 256 random bits, which cannot be guessed
+// whatever the speed of the hash.
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,120 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/apikey/usecase"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+type APIKeyUsecaseTestSuite struct {
+	suite.Suite
+	usecase domain.APIKeyUsecase
+	repo    *mocks.APIKeyRepository
+}
+
+func TestAPIKeyUsecase(t *testing.T) {
+	suite.Run(t, new(APIKeyUsecaseTestSuite))
+}
+
+func (s *APIKeyUsecaseTestSuite) SetupTest() {
+	s.repo = new(mocks.APIKeyRepository)
+	s.usecase = usecase.NewAPIKeyUsecase(s.repo, 60, 1000, time.Second*2, logger.Discard())
+}
+
+func (s *APIKeyUsecaseTestSuite) TestSuccessIssueAndAuthenticate() {
+	var stored domain.APIKey
+	s.repo.On("Store", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(domain.APIKey)
+	}).Return(nil).Once()
+
+	issued, err := s.usecase.Issue(context.Background(), "lorem", 0, 10)
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(60), issued.RateLimit)
+	s.Assert().Equal(int64(10), issued.DailyQuota)
+	s.Assert().True(strings.HasPrefix(issued.Token, usecase.TokenPrefix+issued.ID+"."))
+	s.Assert().NotContains(stored.SecretHash, strings.SplitN(issued.Token, ".", 2)[1])
+
+	s.repo.On("Get", mock.Anything, issued.ID).Return(stored, nil)
+
+	key, err := s.usecase.Authenticate(context.Background(), issued.Token)
+	s.Require().NoError(err)
+	s.Assert().Equal(issued.ID, key.ID)
+
+	_, err = s.usecase.Authenticate(context.Background(), issued.Token+"x")
+	s.Assert().ErrorIs(err, domain.ErrUnauthorized)
+}
+
+func (s *APIKeyUsecaseTestSuite) TestFailedIssue() {
+	_, err := s.usecase.Issue(context.Background(), " ", 0, 0)
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+
+	_, err = s.usecase.Issue(context.Background(), "lorem", -1, 0)
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+
+	s.repo.AssertNotCalled(s.T(), "Store", mock.Anything, mock.Anything)
+}
+
+func (s *APIKeyUsecaseTestSuite) TestFailedAuthenticate() {
+	s.repo.On("Get", mock.Anything, "missing").Return(domain.APIKey{}, domain.ErrNotFound).Once()
+
+	for _, token := range []string{"", "lorem", "mk_", "mk_abc", "mk_.secret", "mk_missing.secret"} {
+		_, err := s.usecase.Authenticate(context.Background(), token)
+		s.Assert().ErrorIs(err, domain.ErrUnauthorized, token)
+	}
+}
+
+func (s *APIKeyUsecaseTestSuite) TestSuccessRevoke() {
+	s.repo.On("Get", mock.Anything, "abc").Return(domain.APIKey{ID: "abc"}, nil).Once()
+	s.repo.On("Store", mock.Anything, mock.MatchedBy(func(key domain.APIKey) bool { return key.RevokedAt != nil })).Return(nil).Once()
+
+	key, err := s.usecase.Revoke(context.Background(), "abc")
+	s.Require().NoError(err)
+	s.Assert().NotNil(key.RevokedAt)
+	s.repo.AssertExpectations(s.T())
+}
+
+func (s *APIKeyUsecaseTestSuite) TestFailedAuthenticateRevoked() {
+	s.repo.On("Store", mock.Anything, mock.Anything).Return(nil).Once()
+	issued, err := s.usecase.Issue(context.Background(), "lorem", 0, 0)
+	s.Require().NoError(err)
+
+	revokedAt := time.Now()
+	issued.APIKey.RevokedAt = &revokedAt
+	s.repo.On("Get", mock.Anything, issued.ID).Return(issued.APIKey, nil).Once()
+
+	_, err = s.usecase.Authenticate(context.Background(), issued.Token)
+	s.Assert().ErrorIs(err, domain.ErrUnauthorized)
+}
+
+func (s *APIKeyUsecaseTestSuite) TestSuccessConsume() {
+	s.repo.On("Count", mock.Anything, "abc", mock.Anything).Return(domain.APIKeyUsage{Minute: 60, Today: 1000, Total: 5000}, nil).Once()
+
+	usage, err := s.usecase.Consume(context.Background(), domain.APIKey{ID: "abc", RateLimit: 60, DailyQuota: 1000})
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(5000), usage.Total)
+	s.Assert().Zero(usage.RetryAfter)
+}
+
+func (s *APIKeyUsecaseTestSuite) TestFailedConsume() {
+	key := domain.APIKey{ID: "abc", RateLimit: 60, DailyQuota: 1000}
+	s.repo.On("Count", mock.Anything, "abc", mock.Anything).Return(domain.APIKeyUsage{Minute: 61, Today: 10}, nil).Once()
+
+	usage, err := s.usecase.Consume(context.Background(), key)
+	s.Assert().ErrorIs(err, domain.ErrRateLimited)
+	s.Assert().True(usage.RetryAfter > 0 && usage.RetryAfter <= time.Minute)
+
+	s.repo.On("Count", mock.Anything, "abc", mock.Anything).Return(domain.APIKeyUsage{Minute: 61, Today: 1001}, nil).Once()
+
+	usage, err = s.usecase.Consume(context.Background(), key)
+	s.Assert().Equal("Daily quota exceeded", err.Error())
+	s.Assert().True(usage.RetryAfter > 0 && usage.RetryAfter <= 24*time.Hour)
+}
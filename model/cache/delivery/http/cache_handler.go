@@ -0,0 +1,148 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type CacheHandler struct {
+	Usecase domain.CacheUsecase
+	Logger  *slog.Logger
+}
+
+// NewCacheHandler registers the admin cache endpoints under /admin/cache,
+// behind middleware, which is expected to authenticate the caller.
+func NewCacheHandler(e *echo.Echo, u domain.CacheUsecase, logger *slog.Logger, middleware ...echo.MiddlewareFunc) *CacheHandler {
+	handler := &CacheHandler{
+		Usecase: u,
+		Logger:  logger,
+	}
+	g := e.Group("/admin/cache", middleware...)
+	g.GET("/keys", handler.List)
+	g.DELETE("/keys", handler.Purge)
+	g.GET("/keys/:key", handler.Get)
+	g.POST("/characters/:id/refresh", handler.RefreshCharacter)
+	g.POST("/pages/:page/refresh", handler.RefreshPage)
+	g.GET("/stats", handler.Stats)
+
+	return handler
+}
+
+func (h *CacheHandler) List(c echo.Context) error {
+	match := c.QueryParam("match")
+	if match == "" {
+		match = "marvel-*"
+	}
+
+	cursor, err := uintParam(c, "cursor")
+	if err != nil {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "cursor must be a positive integer"))
+	}
+	count, err := uintParam(c, "count")
+	if err != nil {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "count must be a positive integer"))
+	}
+
+	keys, err := h.Usecase.List(c.Request().Context(), match, cursor, int64(count))
+	if err != nil {
+		return h.error(c, err, slog.String("match", match))
+	}
+
+	return h.json(c, keys)
+}
+
+func (h *CacheHandler) Get(c echo.Context) error {
+	key := c.Param("key")
+
+	entry, err := h.Usecase.Get(c.Request().Context(), key)
+	if err != nil {
+		return h.error(c, err, slog.String("key", key))
+	}
+
+	return h.json(c, entry)
+}
+
+// Purge deletes the keys matching the required match parameter. With
+// dryRun=true it only lists them.
+func (h *CacheHandler) Purge(c echo.Context) error {
+	match := c.QueryParam("match")
+	if match == "" {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "match is required"))
+	}
+	dryRun := c.QueryParam("dryRun") == "true"
+
+	purge, err := h.Usecase.Purge(c.Request().Context(), match, dryRun)
+	if err != nil {
+		return h.error(c, err, slog.String("match", match))
+	}
+
+	return h.json(c, purge)
+}
+
+func (h *CacheHandler) RefreshCharacter(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "id must be an integer"))
+	}
+
+	entry, err := h.Usecase.RefreshCharacter(c.Request().Context(), id)
+	if err != nil {
+		return h.error(c, err, slog.Int("id", id))
+	}
+
+	return h.json(c, entry)
+}
+
+func (h *CacheHandler) RefreshPage(c echo.Context) error {
+	page, err := strconv.Atoi(c.Param("page"))
+	if err != nil {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "page must be an integer"))
+	}
+
+	entry, err := h.Usecase.RefreshPage(c.Request().Context(), page)
+	if err != nil {
+		return h.error(c, err, slog.Int("page", page))
+	}
+
+	return h.json(c, entry)
+}
+
+func (h *CacheHandler) Stats(c echo.Context) error {
+	stats, err := h.Usecase.Stats(c.Request().Context())
+	if err != nil {
+		return h.error(c, err)
+	}
+
+	return h.json(c, stats)
+}
+
+// json writes v, which reflects the cache right now and is never cached.
+func (h *CacheHandler) json(c echo.Context, v interface{}) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, v)
+}
+
+// error logs server side failures, which the response does not detail, then
+// writes err as a problem.
+func (h *CacheHandler) error(c echo.Context, err error, attrs ...slog.Attr) error {
+	if problem.StatusOf(err) == http.StatusInternalServerError {
+		h.Logger.LogAttrs(c.Request().Context(), slog.LevelError, "Request failed", append(attrs, slog.Any("error", err))...)
+	}
+
+	return problem.Error(c, err)
+}
+
+func uintParam(c echo.Context, name string) (uint64, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(raw, 10, 64)
+}
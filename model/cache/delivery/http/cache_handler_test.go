@@ -0,0 +1,140 @@
+package http_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	cacheHttp "github.com/hezbymuhammad/golang-marvel-demo/model/cache/delivery/http"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type CacheHandlerTestSuite struct {
+	suite.Suite
+	e       *echo.Echo
+	usecase *mocks.CacheUsecase
+}
+
+func TestCacheHandler(t *testing.T) {
+	suite.Run(t, new(CacheHandlerTestSuite))
+}
+
+func (s *CacheHandlerTestSuite) SetupTest() {
+	s.e = echo.New()
+	s.e.HTTPErrorHandler = problem.HTTPErrorHandler
+	s.usecase = new(mocks.CacheUsecase)
+	cacheHttp.NewCacheHandler(s.e, s.usecase, logger.Discard(), auth.AdminToken("s3cret"))
+}
+
+func (s *CacheHandlerTestSuite) serve(method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s *CacheHandlerTestSuite) TestSuccessList() {
+	keys := domain.CacheKeys{Keys: []domain.CacheEntry{{Key: "marvel-v2-character-id-1", Kind: "character", TTL: 60, Size: 10}}, Cursor: "0"}
+	s.usecase.On("List", mock.Anything, "marvel-v2-character-id-*", uint64(3), int64(10)).Return(keys, nil).Once()
+
+	rec := s.serve(echo.GET, "/admin/cache/keys?match=marvel-v2-character-id-*&cursor=3&count=10")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("no-store", rec.Header().Get("Cache-Control"))
+	s.Assert().JSONEq(`{"keys":[{"key":"marvel-v2-character-id-1","kind":"character","ttl":60,"size":10}],"cursor":"0"}`, rec.Body.String())
+}
+
+func (s *CacheHandlerTestSuite) TestFailedList() {
+	rec := s.serve(echo.GET, "/admin/cache/keys?cursor=lorem")
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+
+	s.usecase.On("List", mock.Anything, "marvel-*", uint64(0), int64(0)).Return(domain.CacheKeys{}, errors.New("redis down")).Once()
+	rec = s.serve(echo.GET, "/admin/cache/keys")
+	s.Assert().Equal(http.StatusInternalServerError, rec.Code)
+	s.Assert().NotContains(rec.Body.String(), "redis down")
+}
+
+func (s *CacheHandlerTestSuite) TestUnauthorized() {
+	req := httptest.NewRequest(echo.GET, "/admin/cache/stats", nil)
+	req.Header.Set("Authorization", "Bearer lorem")
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	s.Assert().Equal(http.StatusUnauthorized, rec.Code)
+	s.usecase.AssertNotCalled(s.T(), "Stats", mock.Anything)
+}
+
+func (s *CacheHandlerTestSuite) TestSuccessGet() {
+	entry := domain.CacheEntry{Key: "marvel-v2-characters-page-1", Kind: "page", TTL: -1, Size: 5, Value: []byte("[1,2]")}
+	s.usecase.On("Get", mock.Anything, "marvel-v2-characters-page-1").Return(entry, nil).Once()
+
+	rec := s.serve(echo.GET, "/admin/cache/keys/marvel-v2-characters-page-1")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().JSONEq(`{"key":"marvel-v2-characters-page-1","kind":"page","ttl":-1,"size":5,"value":[1,2]}`, rec.Body.String())
+}
+
+func (s *CacheHandlerTestSuite) TestFailedGet() {
+	s.usecase.On("Get", mock.Anything, "marvel-v2-characters-page-9").Return(domain.CacheEntry{}, domain.ErrNotFound).Once()
+
+	rec := s.serve(echo.GET, "/admin/cache/keys/marvel-v2-characters-page-9")
+	s.Assert().Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *CacheHandlerTestSuite) TestSuccessPurge() {
+	purge := domain.CachePurge{Keys: []string{"marvel-v2-character-id-1"}, DryRun: true}
+	s.usecase.On("Purge", mock.Anything, "marvel-v2-character-id-*", true).Return(purge, nil).Once()
+
+	rec := s.serve(echo.DELETE, "/admin/cache/keys?match=marvel-v2-character-id-*&dryRun=true")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().JSONEq(`{"keys":["marvel-v2-character-id-1"],"deleted":0,"dryRun":true}`, rec.Body.String())
+}
+
+func (s *CacheHandlerTestSuite) TestFailedPurge() {
+	rec := s.serve(echo.DELETE, "/admin/cache/keys")
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+	s.usecase.AssertNotCalled(s.T(), "Purge", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CacheHandlerTestSuite) TestSuccessRefresh() {
+	character := domain.CacheEntry{Key: "marvel-v2-character-id-1", Kind: "character"}
+	page := domain.CacheEntry{Key: "marvel-v2-characters-page-2", Kind: "page"}
+	s.usecase.On("RefreshCharacter", mock.Anything, 1).Return(character, nil).Once()
+	s.usecase.On("RefreshPage", mock.Anything, 2).Return(page, nil).Once()
+
+	rec := s.serve(echo.POST, "/admin/cache/characters/1/refresh")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Contains(rec.Body.String(), "marvel-v2-character-id-1")
+
+	rec = s.serve(echo.POST, "/admin/cache/pages/2/refresh")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Contains(rec.Body.String(), "marvel-v2-characters-page-2")
+}
+
+func (s *CacheHandlerTestSuite) TestFailedRefresh() {
+	s.usecase.On("RefreshCharacter", mock.Anything, 1).Return(domain.CacheEntry{}, domain.ErrNotFound).Once()
+
+	rec := s.serve(echo.POST, "/admin/cache/characters/1/refresh")
+	s.Assert().Equal(http.StatusNotFound, rec.Code)
+
+	rec = s.serve(echo.POST, "/admin/cache/pages/lorem/refresh")
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *CacheHandlerTestSuite) TestSuccessStats() {
+	stats := domain.CacheStats{Characters: domain.CacheKindStats{Keys: 2}, Pages: domain.CacheKindStats{Keys: 1}}
+	s.usecase.On("Stats", mock.Anything).Return(stats, nil).Once()
+
+	rec := s.serve(echo.GET, "/admin/cache/stats")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().JSONEq(`{"characters":{"keys":2,"empty":0,"bytes":0,"minTtl":0,"maxTtl":0},"pages":{"keys":1,"empty":0,"bytes":0,"minTtl":0,"maxTtl":0}}`, rec.Body.String())
+}
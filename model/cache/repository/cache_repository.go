@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+)
+
+type CacheRepository struct {
+	Client redis.Cmdable
+	Keys   cachekey.Builder
+	Logger *slog.Logger
+}
+
+func NewCacheRepository(Conn redis.Cmdable, keys cachekey.Builder, logger *slog.Logger) domain.CacheRepository {
+	return &CacheRepository{
+		Client: Conn,
+		Keys:   keys,
+		Logger: logger,
+	}
+}
+
+// Scan returns a SCAN batch of the keys matching match, with the cursor of
+// the next one. SCAN may return a key more than once across batches.
+func (c *CacheRepository) Scan(ctx context.Context, match string, cursor uint64, count int64) ([]domain.CacheEntry, uint64, error) {
+	keys, next, err := c.Client.Scan(ctx, cursor, match, count).Result()
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "Scan failed", "match", match, "error", err)
+		return nil, 0, domain.ErrInternalServerError
+	}
+
+	entries, err := c.entries(ctx, keys, false)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, next, nil
+}
+
+func (c *CacheRepository) Get(ctx context.Context, key string) (domain.CacheEntry, error) {
+	entries, err := c.entries(ctx, []string{key}, true)
+	if err != nil {
+		return domain.CacheEntry{}, err
+	}
+	if len(entries) == 0 {
+		return domain.CacheEntry{}, domain.ErrNotFound
+	}
+
+	return entries[0], nil
+}
+
+// Delete deletes keys, and drops the characters of the hash layout among
+// them from the ByModified index. They keep their position in the ByName
+// index, to be reported missing by their page.
+func (c *CacheRepository) Delete(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	hash := c.Keys.WithLayout(cachekey.LayoutHash)
+	pipe := c.Client.TxPipeline()
+	del := pipe.Del(ctx, keys...)
+	if members := indexMembers(hash, keys); len(members) > 0 {
+		pipe.ZRem(ctx, hash.ByModified(), members...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.Logger.ErrorContext(ctx, "Delete failed", "keys", len(keys), "error", err)
+		return 0, domain.ErrInternalServerError
+	}
+
+	return del.Val(), nil
+}
+
+// indexMembers returns the members of the indexes of keys laid out as hash
+// characters.
+func indexMembers(hash cachekey.Builder, keys []string) []interface{} {
+	var members []interface{}
+	for _, key := range keys {
+		if kind, id, ok := hash.Parse(key); ok && kind == cachekey.KindCharacter {
+			members = append(members, strconv.Itoa(id))
+		}
+	}
+
+	return members
+}
+
+// entries reads keys in two pipelines, their types then their values,
+// leaving out the ones that expired in the meantime. Characters and pages
+// are decoded whatever their encoding, and so are the hashes and indexes of
+// the hash layout. Keys of other types are left out.
+func (c *CacheRepository) entries(ctx context.Context, keys []string, withValue bool) ([]domain.CacheEntry, error) {
+	if len(keys) == 0 {
+		return []domain.CacheEntry{}, nil
+	}
+
+	pipe := c.Client.Pipeline()
+	types := make([]*redis.StatusCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		types[i] = pipe.Type(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.Logger.ErrorContext(ctx, "entries Exec failed", "keys", len(keys), "error", err)
+		return nil, domain.ErrInternalServerError
+	}
+
+	pipe = c.Client.Pipeline()
+	vals := make([]redis.Cmder, len(keys))
+	for i, key := range keys {
+		switch types[i].Val() {
+		case "string":
+			vals[i] = pipe.Get(ctx, key)
+		case "hash":
+			vals[i] = pipe.HGetAll(ctx, key)
+		case "zset":
+			if withValue {
+				vals[i] = pipe.ZRangeWithScores(ctx, key, 0, -1)
+			} else {
+				vals[i] = pipe.ZCard(ctx, key)
+			}
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		c.Logger.ErrorContext(ctx, "entries Exec failed", "keys", len(keys), "error", err)
+		return nil, domain.ErrInternalServerError
+	}
+
+	entries := make([]domain.CacheEntry, 0, len(keys))
+	for i, key := range keys {
+		if vals[i] == nil || vals[i].Err() != nil {
+			continue
+		}
+
+		entry := domain.CacheEntry{Key: key, Kind: c.Keys.Kind(key), TTL: -1}
+		if ttl := ttls[i].Val(); ttl > 0 {
+			entry.TTL = int64(ttl / time.Second)
+		}
+		switch cmd := vals[i].(type) {
+		case *redis.StringCmd:
+			stringEntry(&entry, cmd.Val(), withValue)
+		case *redis.StringStringMapCmd:
+			hashEntry(&entry, cmd.Val(), withValue)
+		case *redis.ZSliceCmd:
+			entry.Encoding = "zset"
+			entry.Size = len(cmd.Val())
+			entry.Value = indexValue(cmd.Val())
+		case *redis.IntCmd:
+			entry.Encoding = "zset"
+			entry.Size = int(cmd.Val())
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func stringEntry(entry *domain.CacheEntry, val string, withValue bool) {
+	entry.Size = len(val)
+	if entry.Kind != "" && val != "" {
+		entry.Encoding = codec.Describe([]byte(val))
+	}
+
+	var decoded interface{}
+	switch {
+	case entry.Kind == cachekey.KindCharacter && val != "":
+		var cached domain.Character
+		if codec.Decode([]byte(val), &cached) == nil {
+			decoded = cached
+			if !cached.FetchedAt.IsZero() {
+				entry.FetchedAt = &cached.FetchedAt
+			}
+		}
+	case entry.Kind == cachekey.KindPage && val != "" && withValue:
+		var ids []int
+		if codec.Decode([]byte(val), &ids) == nil {
+			decoded = ids
+		}
+	}
+	if withValue {
+		switch {
+		case json.Valid([]byte(val)):
+			entry.Value = json.RawMessage(val)
+		case decoded != nil:
+			entry.Value, _ = json.Marshal(decoded)
+		case val != "":
+			entry.Value, _ = json.Marshal(val)
+		}
+	}
+}
+
+// hashEntry describes a character of the hash layout. Its size is the bytes
+// of its fields and values, 0 for a tombstone like the empty values of the
+// string layout.
+func hashEntry(entry *domain.CacheEntry, fields map[string]string, withValue bool) {
+	entry.Encoding = "hash"
+	cached, tombstone, err := codec.DecodeHash(fields)
+	if tombstone {
+		return
+	}
+	for field, val := range fields {
+		entry.Size += len(field) + len(val)
+	}
+	if err != nil {
+		return
+	}
+
+	if !cached.FetchedAt.IsZero() {
+		entry.FetchedAt = &cached.FetchedAt
+	}
+	if withValue {
+		entry.Value, _ = json.Marshal(cached)
+	}
+}
+
+// indexValue lists the members of an index with their scores, in order.
+func indexValue(members []redis.Z) json.RawMessage {
+	type member struct {
+		Member interface{} `json:"member"`
+		Score  float64     `json:"score"`
+	}
+	value := make([]member, len(members))
+	for i, z := range members {
+		value[i] = member{Member: z.Member, Score: z.Score}
+	}
+
+	data, _ := json.Marshal(value)
+	return data
+}
@@ -0,0 +1,170 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/cache/repository"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+type CacheRepositoryTestSuite struct {
+	suite.Suite
+	mr   *miniredis.Miniredis
+	repo domain.CacheRepository
+}
+
+func TestCacheRepository(t *testing.T) {
+	suite.Run(t, new(CacheRepositoryTestSuite))
+}
+
+func (s *CacheRepositoryTestSuite) SetupTest() {
+	mr, err := miniredis.Run()
+	s.Require().NoError(err)
+	s.mr = mr
+	s.mr.Set("marvel-v2-character-id-1", `{"id":1,"name":"Hulk","fetchedAt":"2021-07-21T10:08:56Z"}`)
+	s.mr.SetTTL("marvel-v2-character-id-1", time.Hour)
+	s.mr.Set("marvel-v2-character-id-2", "")
+	s.mr.Set("marvel-v2-characters-page-1", "[1,2]")
+	s.mr.Set("other", "lorem")
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s.repo = repository.NewCacheRepository(client, cachekey.New(cachekey.DefaultPrefix), logger.Discard())
+}
+
+func (s *CacheRepositoryTestSuite) TearDownTest() {
+	s.mr.Close()
+}
+
+func (s *CacheRepositoryTestSuite) TestSuccessScan() {
+	entries, cursor, err := s.repo.Scan(context.Background(), "marvel-*", 0, 100)
+	s.Require().NoError(err)
+	s.Assert().Equal(uint64(0), cursor)
+	s.Require().Len(entries, 3)
+
+	byKey := map[string]domain.CacheEntry{}
+	for _, entry := range entries {
+		s.Assert().Nil(entry.Value)
+		byKey[entry.Key] = entry
+	}
+
+	hulk := byKey["marvel-v2-character-id-1"]
+	s.Assert().Equal("character", hulk.Kind)
+	s.Assert().Equal(int64(3600), hulk.TTL)
+	s.Require().NotNil(hulk.FetchedAt)
+	s.Assert().Equal(time.Date(2021, 7, 21, 10, 8, 56, 0, time.UTC), hulk.FetchedAt.UTC())
+
+	empty := byKey["marvel-v2-character-id-2"]
+	s.Assert().Equal(0, empty.Size)
+	s.Assert().Equal(int64(-1), empty.TTL)
+	s.Assert().Nil(empty.FetchedAt)
+
+	s.Assert().Equal("page", byKey["marvel-v2-characters-page-1"].Kind)
+}
+
+func (s *CacheRepositoryTestSuite) TestSuccessGet() {
+	entry, err := s.repo.Get(context.Background(), "marvel-v2-characters-page-1")
+	s.Require().NoError(err)
+	s.Assert().Equal("page", entry.Kind)
+	s.Assert().Equal(5, entry.Size)
+	s.Assert().JSONEq("[1,2]", string(entry.Value))
+}
+
+func (s *CacheRepositoryTestSuite) TestSuccessGetEncoded() {
+	fetchedAt := time.Date(2021, 7, 21, 10, 8, 56, 0, time.UTC)
+	val, err := codec.NewEncoder(codec.MsgPack, codec.Zstd, 0).Encode(domain.Character{ID: 3, Name: "Thor", FetchedAt: fetchedAt})
+	s.Require().NoError(err)
+	s.mr.Set("marvel-v2-character-id-3", string(val))
+
+	entry, err := s.repo.Get(context.Background(), "marvel-v2-character-id-3")
+	s.Require().NoError(err)
+	s.Assert().Equal("msgpack+zstd", entry.Encoding)
+	s.Assert().Equal(len(val), entry.Size)
+	s.Require().NotNil(entry.FetchedAt)
+	s.Assert().True(fetchedAt.Equal(*entry.FetchedAt))
+	s.Assert().JSONEq(`{"id":3,"name":"Thor","description":"","fetchedAt":"2021-07-21T10:08:56Z"}`, string(entry.Value))
+}
+
+func (s *CacheRepositoryTestSuite) TestSuccessScanHashLayout() {
+	s.mr.HSet("marvel-v2-hash-character-id-1", "id", "1")
+	s.mr.HSet("marvel-v2-hash-character-id-1", "name", "Hulk")
+	s.mr.HSet("marvel-v2-hash-character-id-1", "fetchedAt", "2021-07-21T10:08:56Z")
+	s.mr.HSet("marvel-v2-hash-character-id-2", "tombstone", "1")
+	s.mr.ZAdd("marvel-v2-hash-characters-by-name", 10, "1")
+	s.mr.Set("marvel-v2-hash-characters-page-1", `{"offset":10,"count":1}`)
+	client := redis.NewClient(&redis.Options{Addr: s.mr.Addr()})
+	repo := repository.NewCacheRepository(client, cachekey.New(cachekey.DefaultPrefix).WithLayout(cachekey.LayoutHash), logger.Discard())
+
+	entries, _, err := repo.Scan(context.Background(), "marvel-*", 0, 100)
+	s.Require().NoError(err)
+	s.Require().Len(entries, 7)
+	byKey := map[string]domain.CacheEntry{}
+	for _, entry := range entries {
+		byKey[entry.Key] = entry
+	}
+
+	hulk := byKey["marvel-v2-hash-character-id-1"]
+	s.Assert().Equal("character", hulk.Kind)
+	s.Assert().Equal("hash", hulk.Encoding)
+	s.Require().NotNil(hulk.FetchedAt)
+	s.Assert().Equal(0, byKey["marvel-v2-hash-character-id-2"].Size)
+	s.Assert().Equal("index", byKey["marvel-v2-hash-characters-by-name"].Kind)
+	s.Assert().Equal(1, byKey["marvel-v2-hash-characters-by-name"].Size)
+	s.Assert().Equal("page", byKey["marvel-v2-hash-characters-page-1"].Kind)
+	// Keys of the string layout are still listed, without kind.
+	s.Assert().Empty(byKey["marvel-v2-character-id-1"].Kind)
+
+	entry, err := repo.Get(context.Background(), "marvel-v2-hash-character-id-1")
+	s.Require().NoError(err)
+	s.Assert().JSONEq(`{"id":1,"name":"Hulk","description":"","fetchedAt":"2021-07-21T10:08:56Z"}`, string(entry.Value))
+
+	entry, err = repo.Get(context.Background(), "marvel-v2-hash-characters-by-name")
+	s.Require().NoError(err)
+	s.Assert().JSONEq(`[{"member":"1","score":10}]`, string(entry.Value))
+}
+
+func (s *CacheRepositoryTestSuite) TestFailedGet() {
+	_, err := s.repo.Get(context.Background(), "marvel-v2-characters-page-2")
+	s.Assert().Equal(domain.ErrNotFound, err)
+}
+
+func (s *CacheRepositoryTestSuite) TestSuccessDelete() {
+	deleted, err := s.repo.Delete(context.Background(), "marvel-v2-character-id-1", "marvel-v2-character-id-3")
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(1), deleted)
+	s.Assert().False(s.mr.Exists("marvel-v2-character-id-1"))
+
+	deleted, err = s.repo.Delete(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(0), deleted)
+}
+
+func (s *CacheRepositoryTestSuite) TestSuccessDeleteHashLayout() {
+	s.mr.HSet("marvel-v2-hash-character-id-1", "name", "Hulk")
+	s.mr.ZAdd("marvel-v2-hash-characters-by-modified", 10, "1")
+	s.mr.ZAdd("marvel-v2-hash-characters-by-modified", 20, "2")
+	s.mr.ZAdd("marvel-v2-hash-characters-by-name", 0, "1")
+
+	deleted, err := s.repo.Delete(context.Background(), "marvel-v2-hash-character-id-1", "marvel-v2-character-id-2")
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(2), deleted)
+	members, _ := s.mr.ZMembers("marvel-v2-hash-characters-by-modified")
+	s.Assert().Equal([]string{"2"}, members)
+	members, _ = s.mr.ZMembers("marvel-v2-hash-characters-by-name")
+	s.Assert().Equal([]string{"1"}, members)
+}
+
+func (s *CacheRepositoryTestSuite) TestFailedScan() {
+	s.mr.Close()
+
+	_, _, err := s.repo.Scan(context.Background(), "marvel-*", 0, 100)
+	s.Assert().Equal(domain.ErrInternalServerError, err)
+}
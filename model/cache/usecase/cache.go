@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+)
+
+const (
+	defaultCount = 100
+	maxCount     = 1000
+)
+
+// cacheUsecase only lists, shows or purges keys starting with the prefix of
+// keys, so that other data in the same Redis is left alone.
+type cacheUsecase struct {
+	cacheRepo          domain.CacheRepository
+	characterWriteRepo domain.CharacterWriteRepository
+	keys               cachekey.Builder
+	contextTimeout     time.Duration
+	logger             *slog.Logger
+}
+
+func NewCacheUsecase(cr domain.CacheRepository, cwr domain.CharacterWriteRepository, keys cachekey.Builder, timeout time.Duration, logger *slog.Logger) domain.CacheUsecase {
+	return &cacheUsecase{
+		cacheRepo:          cr,
+		characterWriteRepo: cwr,
+		keys:               keys,
+		contextTimeout:     timeout,
+		logger:             logger,
+	}
+}
+
+// List returns a SCAN batch of the keys matching match. A count of 0 asks
+// for the default batch size. API keys and rate limits are left out, since
+// they are not cache.
+func (cu *cacheUsecase) List(c context.Context, match string, cursor uint64, count int64) (domain.CacheKeys, error) {
+	if err := cu.checkMatch(match); err != nil {
+		return domain.CacheKeys{}, err
+	}
+	if count == 0 {
+		count = defaultCount
+	}
+	if count < 0 || count > maxCount {
+		return domain.CacheKeys{}, domain.NewError(domain.CodeBadRequest, "count must be between 1 and "+strconv.Itoa(maxCount))
+	}
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	entries, next, err := cu.cacheRepo.Scan(ctx, match, cursor, count)
+	if err != nil {
+		return domain.CacheKeys{}, err
+	}
+
+	return domain.CacheKeys{Keys: cu.cached(entries), Cursor: strconv.FormatUint(next, 10)}, nil
+}
+
+func (cu *cacheUsecase) Get(c context.Context, key string) (domain.CacheEntry, error) {
+	if !strings.HasPrefix(key, cu.keys.Prefix()) {
+		return domain.CacheEntry{}, domain.NewError(domain.CodeBadRequest, "key must start with "+cu.keys.Prefix())
+	}
+	if cu.keys.Reserved(key) {
+		return domain.CacheEntry{}, domain.NewError(domain.CodeBadRequest, "key holds API keys or rate limits, not cache")
+	}
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	return cu.cacheRepo.Get(ctx, key)
+}
+
+// RefreshCharacter fetches the character from Marvel API, overwriting the
+// cached copy, and returns the new entry.
+func (cu *cacheUsecase) RefreshCharacter(c context.Context, id int) (domain.CacheEntry, error) {
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	if err := cu.characterWriteRepo.RefreshByID(ctx, id); err != nil {
+		return domain.CacheEntry{}, err
+	}
+	cu.logger.InfoContext(ctx, "Character refreshed", "id", id)
+
+	return cu.cacheRepo.Get(ctx, cu.keys.Character(id))
+}
+
+// RefreshPage fetches the page and its characters from Marvel API,
+// overwriting the cached copies, and returns the new page entry.
+func (cu *cacheUsecase) RefreshPage(c context.Context, page int) (domain.CacheEntry, error) {
+	if page < 1 {
+		return domain.CacheEntry{}, domain.NewError(domain.CodeBadRequest, "page must be positive")
+	}
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	if err := cu.characterWriteRepo.RefreshByPage(ctx, page); err != nil {
+		return domain.CacheEntry{}, err
+	}
+	cu.logger.InfoContext(ctx, "Page refreshed", "page", page)
+
+	return cu.cacheRepo.Get(ctx, cu.keys.Page(page))
+}
+
+// Purge deletes the keys matching match, or only lists them on a dry run.
+func (cu *cacheUsecase) Purge(c context.Context, match string, dryRun bool) (domain.CachePurge, error) {
+	if err := cu.checkMatch(match); err != nil {
+		return domain.CachePurge{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	purge := domain.CachePurge{Keys: []string{}, DryRun: dryRun}
+	err := cu.scan(ctx, match, func(entries []domain.CacheEntry) error {
+		keys := make([]string, len(entries))
+		for i, entry := range entries {
+			keys[i] = entry.Key
+		}
+		purge.Keys = append(purge.Keys, keys...)
+		if dryRun {
+			return nil
+		}
+
+		deleted, err := cu.cacheRepo.Delete(ctx, keys...)
+		purge.Deleted += deleted
+		return err
+	})
+	if err != nil {
+		return domain.CachePurge{}, err
+	}
+	if !dryRun {
+		cu.logger.InfoContext(ctx, "Cache purged", "match", match, "deleted", purge.Deleted)
+	}
+
+	return purge, nil
+}
+
+// Stats aggregates the characters and pages of the current schema version.
+func (cu *cacheUsecase) Stats(c context.Context) (domain.CacheStats, error) {
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	var stats domain.CacheStats
+	err := cu.scan(ctx, cu.keys.Prefix()+"*", func(entries []domain.CacheEntry) error {
+		for _, entry := range entries {
+			switch entry.Kind {
+			case cachekey.KindCharacter:
+				add(&stats.Characters, entry)
+			case cachekey.KindPage:
+				add(&stats.Pages, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.CacheStats{}, err
+	}
+
+	return stats, nil
+}
+
+// scan calls fn with every cached key matching match, a SCAN batch at a
+// time.
+func (cu *cacheUsecase) scan(ctx context.Context, match string, fn func([]domain.CacheEntry) error) error {
+	seen := map[string]bool{}
+
+	var cursor uint64
+	for {
+		entries, next, err := cu.cacheRepo.Scan(ctx, match, cursor, defaultCount)
+		if err != nil {
+			return err
+		}
+
+		// SCAN may return a key more than once.
+		fresh := make([]domain.CacheEntry, 0, len(entries))
+		for _, entry := range cu.cached(entries) {
+			if !seen[entry.Key] {
+				seen[entry.Key] = true
+				fresh = append(fresh, entry)
+			}
+		}
+		if len(fresh) > 0 {
+			if err := fn(fresh); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// cached leaves out the entries of API keys and rate limits.
+func (cu *cacheUsecase) cached(entries []domain.CacheEntry) []domain.CacheEntry {
+	kept := make([]domain.CacheEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !cu.keys.Reserved(entry.Key) {
+			kept = append(kept, entry)
+		}
+	}
+
+	return kept
+}
+
+func add(stats *domain.CacheKindStats, entry domain.CacheEntry) {
+	stats.Keys++
+	stats.Bytes += int64(entry.Size)
+	if entry.Size == 0 {
+		stats.Empty++
+	}
+	if entry.TTL >= 0 {
+		if stats.MinTTL == 0 || entry.TTL < stats.MinTTL {
+			stats.MinTTL = entry.TTL
+		}
+		if entry.TTL > stats.MaxTTL {
+			stats.MaxTTL = entry.TTL
+		}
+	}
+	if entry.FetchedAt != nil {
+		if stats.OldestFetchedAt == nil || entry.FetchedAt.Before(*stats.OldestFetchedAt) {
+			stats.OldestFetchedAt = entry.FetchedAt
+		}
+		if stats.NewestFetchedAt == nil || entry.FetchedAt.After(*stats.NewestFetchedAt) {
+			stats.NewestFetchedAt = entry.FetchedAt
+		}
+	}
+}
+
+func (cu *cacheUsecase) checkMatch(match string) error {
+	if !strings.HasPrefix(match, cu.keys.Prefix()) {
+		return domain.NewError(domain.CodeBadRequest, "match must start with "+cu.keys.Prefix())
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/cache/usecase"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+type CacheUsecaseTestSuite struct {
+	suite.Suite
+	usecase   domain.CacheUsecase
+	cacheRepo *mocks.CacheRepository
+	writeRepo *mocks.CharacterWriteRepository
+}
+
+func TestCacheUsecase(t *testing.T) {
+	suite.Run(t, new(CacheUsecaseTestSuite))
+}
+
+func (s *CacheUsecaseTestSuite) SetupTest() {
+	s.cacheRepo = new(mocks.CacheRepository)
+	s.writeRepo = new(mocks.CharacterWriteRepository)
+	s.usecase = usecase.NewCacheUsecase(s.cacheRepo, s.writeRepo, cachekey.New(cachekey.DefaultPrefix), time.Second*2, logger.Discard())
+}
+
+func (s *CacheUsecaseTestSuite) TestSuccessList() {
+	entries := []domain.CacheEntry{{Key: "marvel-v2-character-id-1"}}
+	s.cacheRepo.On("Scan", mock.Anything, "marvel-*", uint64(7), int64(100)).Return(entries, uint64(9), nil).Once()
+
+	keys, err := s.usecase.List(context.Background(), "marvel-*", 7, 0)
+	s.Require().NoError(err)
+	s.Assert().Equal(entries, keys.Keys)
+	s.Assert().Equal("9", keys.Cursor)
+}
+
+func (s *CacheUsecaseTestSuite) TestFailedList() {
+	_, err := s.usecase.List(context.Background(), "*", 0, 0)
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+
+	_, err = s.usecase.List(context.Background(), "marvel-*", 0, 1001)
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+
+	s.cacheRepo.AssertNotCalled(s.T(), "Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CacheUsecaseTestSuite) TestFailedGet() {
+	_, err := s.usecase.Get(context.Background(), "session-1")
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+
+	_, err = s.usecase.Get(context.Background(), "marvel-auth-apikey-id-lorem")
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+	s.cacheRepo.AssertNotCalled(s.T(), "Get", mock.Anything, mock.Anything)
+}
+
+func (s *CacheUsecaseTestSuite) TestSuccessRefreshCharacter() {
+	entry := domain.CacheEntry{Key: "marvel-v2-character-id-1", Kind: "character"}
+	s.writeRepo.On("RefreshByID", mock.Anything, 1).Return(nil).Once()
+	s.cacheRepo.On("Get", mock.Anything, "marvel-v2-character-id-1").Return(entry, nil).Once()
+
+	res, err := s.usecase.RefreshCharacter(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Assert().Equal(entry, res)
+	s.writeRepo.AssertNotCalled(s.T(), "StoreByID", mock.Anything, mock.Anything)
+}
+
+func (s *CacheUsecaseTestSuite) TestFailedRefreshCharacter() {
+	s.writeRepo.On("RefreshByID", mock.Anything, 1).Return(domain.ErrNotFound).Once()
+
+	_, err := s.usecase.RefreshCharacter(context.Background(), 1)
+	s.Assert().Equal(domain.ErrNotFound, err)
+	s.cacheRepo.AssertNotCalled(s.T(), "Get", mock.Anything, mock.Anything)
+}
+
+func (s *CacheUsecaseTestSuite) TestSuccessRefreshPage() {
+	entry := domain.CacheEntry{Key: "marvel-v2-characters-page-2", Kind: "page"}
+	s.writeRepo.On("RefreshByPage", mock.Anything, 2).Return(nil).Once()
+	s.cacheRepo.On("Get", mock.Anything, "marvel-v2-characters-page-2").Return(entry, nil).Once()
+
+	res, err := s.usecase.RefreshPage(context.Background(), 2)
+	s.Require().NoError(err)
+	s.Assert().Equal(entry, res)
+
+	_, err = s.usecase.RefreshPage(context.Background(), 0)
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+}
+
+func (s *CacheUsecaseTestSuite) TestSuccessPurge() {
+	first := []domain.CacheEntry{{Key: "marvel-v2-character-id-1"}, {Key: "marvel-v2-character-id-2"}}
+	second := []domain.CacheEntry{{Key: "marvel-v2-character-id-2"}, {Key: "marvel-v2-character-id-3"}}
+	s.cacheRepo.On("Scan", mock.Anything, "marvel-v2-character-id-*", uint64(0), int64(100)).Return(first, uint64(5), nil).Once()
+	s.cacheRepo.On("Scan", mock.Anything, "marvel-v2-character-id-*", uint64(5), int64(100)).Return(second, uint64(0), nil).Once()
+	s.cacheRepo.On("Delete", mock.Anything, "marvel-v2-character-id-1", "marvel-v2-character-id-2").Return(int64(2), nil).Once()
+	s.cacheRepo.On("Delete", mock.Anything, "marvel-v2-character-id-3").Return(int64(1), nil).Once()
+
+	purge, err := s.usecase.Purge(context.Background(), "marvel-v2-character-id-*", false)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"marvel-v2-character-id-1", "marvel-v2-character-id-2", "marvel-v2-character-id-3"}, purge.Keys)
+	s.Assert().Equal(int64(3), purge.Deleted)
+	s.Assert().False(purge.DryRun)
+}
+
+func (s *CacheUsecaseTestSuite) TestSuccessPurgeDryRun() {
+	entries := []domain.CacheEntry{{Key: "marvel-v2-character-id-1"}}
+	s.cacheRepo.On("Scan", mock.Anything, "marvel-v2-character-id-*", uint64(0), int64(100)).Return(entries, uint64(0), nil).Once()
+
+	purge, err := s.usecase.Purge(context.Background(), "marvel-v2-character-id-*", true)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"marvel-v2-character-id-1"}, purge.Keys)
+	s.Assert().Equal(int64(0), purge.Deleted)
+	s.Assert().True(purge.DryRun)
+	s.cacheRepo.AssertNotCalled(s.T(), "Delete", mock.Anything)
+}
+
+func (s *CacheUsecaseTestSuite) TestSuccessPurgeKeepsAPIKeys() {
+	entries := []domain.CacheEntry{{Key: "marvel-auth-apikeys"}, {Key: "marvel-auth-apikey-id-lorem"}, {Key: "marvel-ratelimit-global"}, {Key: "marvel-v2-character-id-1"}}
+	s.cacheRepo.On("Scan", mock.Anything, "marvel-*", uint64(0), int64(100)).Return(entries, uint64(0), nil).Once()
+	s.cacheRepo.On("Delete", mock.Anything, "marvel-v2-character-id-1").Return(int64(1), nil).Once()
+
+	purge, err := s.usecase.Purge(context.Background(), "marvel-*", false)
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"marvel-v2-character-id-1"}, purge.Keys)
+	s.Assert().Equal(int64(1), purge.Deleted)
+}
+
+func (s *CacheUsecaseTestSuite) TestFailedPurge() {
+	_, err := s.usecase.Purge(context.Background(), "*", false)
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+}
+
+func (s *CacheUsecaseTestSuite) TestSuccessStats() {
+	older := time.Date(2021, 7, 20, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2021, 7, 21, 0, 0, 0, 0, time.UTC)
+	entries := []domain.CacheEntry{
+		{Key: "marvel-v2-character-id-1", Kind: "character", TTL: 100, Size: 50, FetchedAt: &newer},
+		{Key: "marvel-v2-character-id-2", Kind: "character", TTL: 30, Size: 70, FetchedAt: &older},
+		{Key: "marvel-v2-character-id-3", Kind: "character", TTL: -1, Size: 0},
+		{Key: "marvel-v2-characters-page-1", Kind: "page", TTL: 60, Size: 5},
+	}
+	s.cacheRepo.On("Scan", mock.Anything, "marvel-*", uint64(0), int64(100)).Return(entries, uint64(0), nil).Once()
+
+	stats, err := s.usecase.Stats(context.Background())
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.CacheKindStats{
+		Keys:            3,
+		Empty:           1,
+		Bytes:           120,
+		MinTTL:          30,
+		MaxTTL:          100,
+		OldestFetchedAt: &older,
+		NewestFetchedAt: &newer,
+	}, stats.Characters)
+	s.Assert().Equal(domain.CacheKindStats{Keys: 1, Bytes: 5, MinTTL: 60, MaxTTL: 60}, stats.Pages)
+}
@@ -0,0 +1,107 @@
+// Package graphql serves the characters over GraphQL on /graphql.
+package graphql
+
+import (
+	_ "embed"
+	"log/slog"
+	"net/http"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// maxQueryLength bounds the query text, which is parsed before any limit
+// applies.
+const maxQueryLength = 10000
+
+// Options bounds the cost of a query. MaxDepth is the deepest selection
+// allowed, MaxComplexity the number of characters a query may resolve.
+// Playground serves GraphiQL on GET /graphql.
+type Options struct {
+	MaxDepth      int
+	MaxComplexity int
+	Playground    bool
+}
+
+type CharacterHandler struct {
+	Usecase domain.CharacterUsecase
+	Logger  *slog.Logger
+	schema  *gql.Schema
+	opts    Options
+}
+
+// Request is the body of POST /graphql.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// NewCharacterHandler registers POST /graphql behind middleware, such as API
+// key authentication. The playground page itself is left open.
+func NewCharacterHandler(e *echo.Echo, u domain.CharacterUsecase, logger *slog.Logger, opts Options, middleware ...echo.MiddlewareFunc) *CharacterHandler {
+	handler := &CharacterHandler{
+		Usecase: u,
+		Logger:  logger,
+		schema: gql.MustParseSchema(schema, &resolver{usecase: u},
+			gql.MaxDepth(opts.MaxDepth),
+			gql.UseStringDescriptions(),
+		),
+		opts: opts,
+	}
+	e.POST("/graphql", handler.Query, middleware...)
+	if opts.Playground {
+		e.GET("/graphql", handler.Playground)
+	}
+
+	return handler
+}
+
+func (h *CharacterHandler) Query(c echo.Context) error {
+	var req Request
+	if err := c.Bind(&req); err != nil || req.Query == "" {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "body must be a JSON object with a query"))
+	}
+	if len(req.Query) > maxQueryLength {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "query is too long"))
+	}
+
+	ctx := withRequest(c.Request().Context(), h.Usecase, h.opts.MaxComplexity)
+	res := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	for _, err := range res.Errors {
+		if err.Extensions["code"] == string(domain.CodeInternal) {
+			h.Logger.ErrorContext(ctx, "Query failed", "path", err.Path, "error", err.ResolverError)
+		}
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *CharacterHandler) Playground(c echo.Context) error {
+	return c.HTML(http.StatusOK, playground)
+}
+
+const playground = `<!DOCTYPE html>
+<html>
+<head>
+	<title>GraphiQL</title>
+	<link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css" />
+</head>
+<body style="margin: 0;">
+	<div id="graphiql" style="height: 100vh;"></div>
+	<script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+	<script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+	<script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+	<script>
+		const fetcher = GraphiQL.createFetcher({ url: window.location.pathname });
+		ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, { fetcher }));
+	</script>
+</body>
+</html>
+`
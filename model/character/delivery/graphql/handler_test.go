@@ -0,0 +1,141 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	characterGraphQL "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/graphql"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+type CharacterGraphQLTestSuite struct {
+	suite.Suite
+	e       *echo.Echo
+	usecase *mocks.CharacterUsecase
+}
+
+func TestCharacterGraphQL(t *testing.T) {
+	suite.Run(t, new(CharacterGraphQLTestSuite))
+}
+
+func (s *CharacterGraphQLTestSuite) SetupTest() {
+	s.e = echo.New()
+	s.usecase = new(mocks.CharacterUsecase)
+	characterGraphQL.NewCharacterHandler(s.e, s.usecase, logger.Discard(), characterGraphQL.Options{
+		MaxDepth:      3,
+		MaxComplexity: 5,
+		Playground:    true,
+	})
+}
+
+func (s *CharacterGraphQLTestSuite) query(query string) (*httptest.ResponseRecorder, response) {
+	body, _ := json.Marshal(characterGraphQL.Request{Query: query})
+	req := httptest.NewRequest(echo.POST, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	var res response
+	_ = json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec, res
+}
+
+func (s *CharacterGraphQLTestSuite) TestSuccessCharactersBatched() {
+	batch := domain.CharacterBatch{
+		Characters: []domain.Character{{ID: 1, Name: "Lorem"}, {ID: 2, Name: "Ipsum"}},
+		Missing:    []int{},
+	}
+	s.usecase.On("GetByIDs", mock.Anything, mock.Anything).Return(batch, nil).Once()
+
+	rec, res := s.query(`{ a: character(id: 1) { id name } b: character(id: 2) { name } }`)
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Empty(res.Errors)
+	s.Assert().JSONEq(`{"id":1,"name":"Lorem"}`, string(res.Data["a"]))
+	s.Assert().JSONEq(`{"name":"Ipsum"}`, string(res.Data["b"]))
+	s.usecase.AssertNumberOfCalls(s.T(), "GetByIDs", 1)
+}
+
+func (s *CharacterGraphQLTestSuite) TestNotCachedCharacter() {
+	batch := domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{1}}
+	s.usecase.On("GetByIDs", mock.Anything, []int{1}).Return(batch, nil).Once()
+
+	_, res := s.query(`{ character(id: 1) { name } }`)
+	s.Assert().Equal("null", string(res.Data["character"]))
+	s.Require().Len(res.Errors, 1)
+	s.Assert().Equal("Resource not cached yet", res.Errors[0].Message)
+	s.Assert().Equal("not_cached", res.Errors[0].Extensions["code"])
+	s.Assert().NotNil(res.Errors[0].Extensions["retryAfter"])
+}
+
+func (s *CharacterGraphQLTestSuite) TestNotFoundCharacter() {
+	batch := domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{}, NotFound: []int{1}}
+	s.usecase.On("GetByIDs", mock.Anything, []int{1}).Return(batch, nil).Twice()
+
+	_, res := s.query(`{ a: character(id: 1) { name } b: characters(ids: [1]) { missing notFound } }`)
+	s.Assert().Equal("null", string(res.Data["a"]))
+	s.Require().Len(res.Errors, 1)
+	s.Assert().Equal("not_found", res.Errors[0].Extensions["code"])
+	s.Assert().JSONEq(`{"missing":[],"notFound":[1]}`, string(res.Data["b"]))
+}
+
+func (s *CharacterGraphQLTestSuite) TestSuccessPage() {
+	batch := domain.CharacterBatch{Characters: []domain.Character{{ID: 1, Name: "Lorem"}}, Missing: []int{2}}
+	s.usecase.On("Fetch", mock.Anything, 2).Return([]int{1, 2}, nil).Once()
+	s.usecase.On("GetByIDs", mock.Anything, []int{1, 2}).Return(batch, nil).Once()
+
+	_, res := s.query(`{ page(number: 2) { number ids characters { name } missing partial } }`)
+	s.Assert().Empty(res.Errors)
+	s.Assert().JSONEq(`{"number":2,"ids":[1,2],"characters":[{"name":"Lorem"}],"missing":[2],"partial":true}`, string(res.Data["page"]))
+}
+
+func (s *CharacterGraphQLTestSuite) TestFailedComplexity() {
+	_, res := s.query(`{ characters(ids: [1, 2, 3, 4, 5, 6]) { missing } }`)
+	s.Require().Len(res.Errors, 1)
+	s.Assert().Equal("too_complex", res.Errors[0].Extensions["code"])
+	s.usecase.AssertNotCalled(s.T(), "GetByIDs", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterGraphQLTestSuite) TestFailedComplexityPages() {
+	s.usecase.On("Fetch", mock.Anything, 1).Return([]int{}, nil)
+
+	_, res := s.query(`{ a: page(number: 1) { number } b: page(number: 1) { number } c: page(number: 1) { number }
+		d: page(number: 1) { number } e: page(number: 1) { number } f: page(number: 1) { number } }`)
+	s.Require().Len(res.Errors, 1)
+	s.Assert().Equal("too_complex", res.Errors[0].Extensions["code"])
+	s.usecase.AssertNumberOfCalls(s.T(), "Fetch", 5)
+}
+
+func (s *CharacterGraphQLTestSuite) TestFailedDepth() {
+	_, res := s.query(`{ page { characters { name } } __schema { types { fields { type { name } } } } }`)
+	s.Assert().NotEmpty(res.Errors)
+	s.usecase.AssertNotCalled(s.T(), "Fetch", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterGraphQLTestSuite) TestFailedBody() {
+	rec, _ := s.query(``)
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *CharacterGraphQLTestSuite) TestPlayground() {
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/graphql", nil))
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Contains(rec.Body.String(), "GraphiQL")
+}
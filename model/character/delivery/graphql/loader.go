@@ -0,0 +1,124 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+)
+
+const (
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 100
+)
+
+// characterLoader gathers the characters asked for by the resolvers of one
+// request during a short window, so that they are looked up with a single
+// batch call instead of one call each.
+type characterLoader struct {
+	usecase domain.CharacterUsecase
+	mu      sync.Mutex
+	batch   *loaderBatch
+}
+
+type loaderBatch struct {
+	ids      []int
+	once     sync.Once
+	done     chan struct{}
+	found    map[int]domain.Character
+	notFound map[int]bool
+	err      error
+}
+
+func newCharacterLoader(u domain.CharacterUsecase) *characterLoader {
+	return &characterLoader{usecase: u}
+}
+
+// Load returns the characters of ids that are cached, in order, the ids that
+// are not and those Marvel API does not know.
+func (l *characterLoader) Load(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	var batches []*loaderBatch
+
+	l.mu.Lock()
+	for _, id := range ids {
+		if l.batch == nil {
+			b := &loaderBatch{done: make(chan struct{})}
+			l.batch = b
+			time.AfterFunc(loaderWait, func() { l.run(ctx, b) })
+		}
+		b := l.batch
+		b.ids = append(b.ids, id)
+		if len(batches) == 0 || batches[len(batches)-1] != b {
+			batches = append(batches, b)
+		}
+		if len(b.ids) >= loaderMaxBatch {
+			l.batch = nil
+			go l.run(ctx, b)
+		}
+	}
+	l.mu.Unlock()
+
+	found := map[int]domain.Character{}
+	notFound := map[int]bool{}
+	for _, b := range batches {
+		select {
+		case <-b.done:
+		case <-ctx.Done():
+			return domain.CharacterBatch{}, ctx.Err()
+		}
+		if b.err != nil {
+			return domain.CharacterBatch{}, b.err
+		}
+		for id, character := range b.found {
+			found[id] = character
+		}
+		for id := range b.notFound {
+			notFound[id] = true
+		}
+	}
+
+	batch := domain.CharacterBatch{
+		Characters: make([]domain.Character, 0, len(ids)),
+		Missing:    []int{},
+		NotFound:   []int{},
+	}
+	for _, id := range ids {
+		if character, ok := found[id]; ok {
+			batch.Characters = append(batch.Characters, character)
+		} else if notFound[id] {
+			batch.NotFound = append(batch.NotFound, id)
+		} else {
+			batch.Missing = append(batch.Missing, id)
+		}
+	}
+
+	return batch, nil
+}
+
+func (l *characterLoader) run(ctx context.Context, b *loaderBatch) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		ids := b.ids
+		l.mu.Unlock()
+
+		defer close(b.done)
+		res, err := l.usecase.GetByIDs(ctx, ids)
+		if err != nil {
+			b.err = err
+			return
+		}
+
+		b.found = make(map[int]domain.Character, len(res.Characters))
+		for _, character := range res.Characters {
+			b.found[int(character.ID)] = character
+		}
+		b.notFound = make(map[int]bool, len(res.NotFound))
+		for _, id := range res.NotFound {
+			b.notFound[id] = true
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	gql "github.com/graph-gophers/graphql-go"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type ctxKey int
+
+const (
+	loaderKey ctxKey = iota
+	budgetKey
+)
+
+// budget is the number of characters and pages a single query may still
+// resolve.
+type budget struct {
+	left int64
+	max  int
+}
+
+func withRequest(ctx context.Context, u domain.CharacterUsecase, maxComplexity int) context.Context {
+	ctx = context.WithValue(ctx, loaderKey, newCharacterLoader(u))
+	return context.WithValue(ctx, budgetKey, &budget{left: int64(maxComplexity), max: maxComplexity})
+}
+
+// charge spends n of the query budget, failing once it is exhausted.
+func charge(ctx context.Context, n int) error {
+	b, ok := ctx.Value(budgetKey).(*budget)
+	if !ok {
+		return nil
+	}
+	if atomic.AddInt64(&b.left, -int64(n)) < 0 {
+		return &resolverError{
+			err:  domain.NewError(domain.CodeBadRequest, "Query resolves more than "+strconv.Itoa(b.max)+" characters and pages"),
+			code: "too_complex",
+		}
+	}
+	return nil
+}
+
+func load(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	batch, err := ctx.Value(loaderKey).(*characterLoader).Load(ctx, ids)
+	if err != nil {
+		return domain.CharacterBatch{}, newResolverError(err)
+	}
+	return batch, nil
+}
+
+// resolverError exposes the code of a domain error in the GraphQL error
+// extensions. Like problem responses, it never discloses the wrapped cause.
+type resolverError struct {
+	err  error
+	code string
+}
+
+func newResolverError(err error) *resolverError {
+	return &resolverError{err: err, code: string(domain.CodeOf(err))}
+}
+
+func (e *resolverError) Error() string {
+	return problem.FromError(e.err, "").Detail
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.code}
+	if p := problem.FromError(e.err, ""); p.RetryAfter > 0 {
+		ext["retryAfter"] = p.RetryAfter
+	}
+	return ext
+}
+
+type resolver struct {
+	usecase domain.CharacterUsecase
+}
+
+func (r *resolver) Character(ctx context.Context, args struct{ ID int32 }) (*characterResolver, error) {
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	batch, err := load(ctx, []int{int(args.ID)})
+	if err != nil {
+		return nil, err
+	}
+	if len(batch.NotFound) > 0 {
+		return nil, newResolverError(domain.ErrNotFound)
+	}
+	if len(batch.Characters) == 0 {
+		return nil, newResolverError(domain.ErrCacheKeyEmpty)
+	}
+
+	return &characterResolver{batch.Characters[0]}, nil
+}
+
+func (r *resolver) Characters(ctx context.Context, args struct{ IDs []int32 }) (*batchResolver, error) {
+	if err := charge(ctx, len(args.IDs)); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(args.IDs))
+	for i, id := range args.IDs {
+		ids[i] = int(id)
+	}
+	batch, err := r.usecase.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, newResolverError(err)
+	}
+
+	return &batchResolver{batch}, nil
+}
+
+func (r *resolver) Page(ctx context.Context, args struct{ Number int32 }) (*pageResolver, error) {
+	if err := charge(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	ids, err := r.usecase.Fetch(ctx, int(args.Number))
+	if err != nil {
+		return nil, newResolverError(err)
+	}
+
+	return &pageResolver{number: args.Number, ids: ids}, nil
+}
+
+type characterResolver struct {
+	c domain.Character
+}
+
+func (r *characterResolver) ID() int32 {
+	return int32(r.c.ID)
+}
+
+func (r *characterResolver) Name() string {
+	return r.c.Name
+}
+
+func (r *characterResolver) Description() string {
+	return r.c.Description
+}
+
+func (r *characterResolver) FetchedAt() gql.Time {
+	return gql.Time{Time: r.c.FetchedAt}
+}
+
+type batchResolver struct {
+	batch domain.CharacterBatch
+}
+
+func (r *batchResolver) Characters() []*characterResolver {
+	return characterResolvers(r.batch.Characters)
+}
+
+func (r *batchResolver) Missing() []int32 {
+	return int32s(r.batch.Missing)
+}
+
+func (r *batchResolver) NotFound() []int32 {
+	return int32s(r.batch.NotFound)
+}
+
+// pageResolver only looks the characters of the page up when they are
+// selected, once, through the request loader.
+type pageResolver struct {
+	number int32
+	ids    []int
+	once   sync.Once
+	batch  domain.CharacterBatch
+	err    error
+}
+
+func (r *pageResolver) Number() int32 {
+	return r.number
+}
+
+func (r *pageResolver) IDs() []int32 {
+	return int32s(r.ids)
+}
+
+func (r *pageResolver) load(ctx context.Context) error {
+	r.once.Do(func() {
+		if r.err = charge(ctx, len(r.ids)); r.err != nil {
+			return
+		}
+		r.batch, r.err = load(ctx, r.ids)
+	})
+	return r.err
+}
+
+func (r *pageResolver) Characters(ctx context.Context) ([]*characterResolver, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+	return characterResolvers(r.batch.Characters), nil
+}
+
+func (r *pageResolver) Missing(ctx context.Context) ([]int32, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+	return int32s(r.batch.Missing), nil
+}
+
+func (r *pageResolver) NotFound(ctx context.Context) ([]int32, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+	return int32s(r.batch.NotFound), nil
+}
+
+func (r *pageResolver) Partial(ctx context.Context) (bool, error) {
+	if err := r.load(ctx); err != nil {
+		return false, err
+	}
+	return len(r.batch.Missing) > 0, nil
+}
+
+func characterResolvers(characters []domain.Character) []*characterResolver {
+	res := make([]*characterResolver, len(characters))
+	for i, c := range characters {
+		res[i] = &characterResolver{c}
+	}
+	return res
+}
+
+func int32s(ids []int) []int32 {
+	res := make([]int32, len(ids))
+	for i, id := range ids {
+		res[i] = int32(id)
+	}
+	return res
+}
@@ -0,0 +1,542 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: character.proto
+
+package characterpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Character struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	FetchedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=fetched_at,json=fetchedAt,proto3" json:"fetched_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Character) Reset() {
+	*x = Character{}
+	mi := &file_character_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Character) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Character) ProtoMessage() {}
+
+func (x *Character) ProtoReflect() protoreflect.Message {
+	mi := &file_character_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Character.ProtoReflect.Descriptor instead.
+func (*Character) Descriptor() ([]byte, []int) {
+	return file_character_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Character) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Character) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Character) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Character) GetFetchedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FetchedAt
+	}
+	return nil
+}
+
+type GetCharacterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCharacterRequest) Reset() {
+	*x = GetCharacterRequest{}
+	mi := &file_character_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCharacterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCharacterRequest) ProtoMessage() {}
+
+func (x *GetCharacterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_character_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCharacterRequest.ProtoReflect.Descriptor instead.
+func (*GetCharacterRequest) Descriptor() ([]byte, []int) {
+	return file_character_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetCharacterRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListCharactersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Page defaults to 1.
+	Page int32 `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	// Expand fills characters, missing and not_found rather than ids.
+	Expand        bool `protobuf:"varint,2,opt,name=expand,proto3" json:"expand,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCharactersRequest) Reset() {
+	*x = ListCharactersRequest{}
+	mi := &file_character_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCharactersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCharactersRequest) ProtoMessage() {}
+
+func (x *ListCharactersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_character_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCharactersRequest.ProtoReflect.Descriptor instead.
+func (*ListCharactersRequest) Descriptor() ([]byte, []int) {
+	return file_character_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListCharactersRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListCharactersRequest) GetExpand() bool {
+	if x != nil {
+		return x.Expand
+	}
+	return false
+}
+
+type ListCharactersResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Page       int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	Ids        []int64                `protobuf:"varint,2,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	Characters []*Character           `protobuf:"bytes,3,rep,name=characters,proto3" json:"characters,omitempty"`
+	Missing    []int64                `protobuf:"varint,4,rep,packed,name=missing,proto3" json:"missing,omitempty"`
+	Partial    bool                   `protobuf:"varint,5,opt,name=partial,proto3" json:"partial,omitempty"`
+	// IDs Marvel API does not know, which are not fetched again.
+	NotFound      []int64 `protobuf:"varint,6,rep,packed,name=not_found,json=notFound,proto3" json:"not_found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCharactersResponse) Reset() {
+	*x = ListCharactersResponse{}
+	mi := &file_character_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCharactersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCharactersResponse) ProtoMessage() {}
+
+func (x *ListCharactersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_character_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCharactersResponse.ProtoReflect.Descriptor instead.
+func (*ListCharactersResponse) Descriptor() ([]byte, []int) {
+	return file_character_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListCharactersResponse) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListCharactersResponse) GetIds() []int64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *ListCharactersResponse) GetCharacters() []*Character {
+	if x != nil {
+		return x.Characters
+	}
+	return nil
+}
+
+func (x *ListCharactersResponse) GetMissing() []int64 {
+	if x != nil {
+		return x.Missing
+	}
+	return nil
+}
+
+func (x *ListCharactersResponse) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *ListCharactersResponse) GetNotFound() []int64 {
+	if x != nil {
+		return x.NotFound
+	}
+	return nil
+}
+
+type BatchGetCharactersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Between 1 and 100 IDs.
+	Ids           []int64 `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetCharactersRequest) Reset() {
+	*x = BatchGetCharactersRequest{}
+	mi := &file_character_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetCharactersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetCharactersRequest) ProtoMessage() {}
+
+func (x *BatchGetCharactersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_character_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetCharactersRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetCharactersRequest) Descriptor() ([]byte, []int) {
+	return file_character_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchGetCharactersRequest) GetIds() []int64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type BatchGetCharactersResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Characters []*Character           `protobuf:"bytes,1,rep,name=characters,proto3" json:"characters,omitempty"`
+	Missing    []int64                `protobuf:"varint,2,rep,packed,name=missing,proto3" json:"missing,omitempty"`
+	// IDs Marvel API does not know, which are not fetched again.
+	NotFound      []int64 `protobuf:"varint,3,rep,packed,name=not_found,json=notFound,proto3" json:"not_found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetCharactersResponse) Reset() {
+	*x = BatchGetCharactersResponse{}
+	mi := &file_character_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetCharactersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetCharactersResponse) ProtoMessage() {}
+
+func (x *BatchGetCharactersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_character_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetCharactersResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetCharactersResponse) Descriptor() ([]byte, []int) {
+	return file_character_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchGetCharactersResponse) GetCharacters() []*Character {
+	if x != nil {
+		return x.Characters
+	}
+	return nil
+}
+
+func (x *BatchGetCharactersResponse) GetMissing() []int64 {
+	if x != nil {
+		return x.Missing
+	}
+	return nil
+}
+
+func (x *BatchGetCharactersResponse) GetNotFound() []int64 {
+	if x != nil {
+		return x.NotFound
+	}
+	return nil
+}
+
+type StreamCharactersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// From page defaults to 1, to page to from page. At most 20 pages are
+	// streamed at once.
+	FromPage      int32 `protobuf:"varint,1,opt,name=from_page,json=fromPage,proto3" json:"from_page,omitempty"`
+	ToPage        int32 `protobuf:"varint,2,opt,name=to_page,json=toPage,proto3" json:"to_page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamCharactersRequest) Reset() {
+	*x = StreamCharactersRequest{}
+	mi := &file_character_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamCharactersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamCharactersRequest) ProtoMessage() {}
+
+func (x *StreamCharactersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_character_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamCharactersRequest.ProtoReflect.Descriptor instead.
+func (*StreamCharactersRequest) Descriptor() ([]byte, []int) {
+	return file_character_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StreamCharactersRequest) GetFromPage() int32 {
+	if x != nil {
+		return x.FromPage
+	}
+	return 0
+}
+
+func (x *StreamCharactersRequest) GetToPage() int32 {
+	if x != nil {
+		return x.ToPage
+	}
+	return 0
+}
+
+var File_character_proto protoreflect.FileDescriptor
+
+const file_character_proto_rawDesc = "" +
+	"\n" +
+	"\x0fcharacter.proto\x12\x13marvel.character.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8c\x01\n" +
+	"\tCharacter\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x129\n" +
+	"\n" +
+	"fetched_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tfetchedAt\"%\n" +
+	"\x13GetCharacterRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"C\n" +
+	"\x15ListCharactersRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x16\n" +
+	"\x06expand\x18\x02 \x01(\bR\x06expand\"\xcf\x01\n" +
+	"\x16ListCharactersResponse\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\x03R\x03ids\x12>\n" +
+	"\n" +
+	"characters\x18\x03 \x03(\v2\x1e.marvel.character.v1.CharacterR\n" +
+	"characters\x12\x18\n" +
+	"\amissing\x18\x04 \x03(\x03R\amissing\x12\x18\n" +
+	"\apartial\x18\x05 \x01(\bR\apartial\x12\x1b\n" +
+	"\tnot_found\x18\x06 \x03(\x03R\bnotFound\"-\n" +
+	"\x19BatchGetCharactersRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x03R\x03ids\"\x93\x01\n" +
+	"\x1aBatchGetCharactersResponse\x12>\n" +
+	"\n" +
+	"characters\x18\x01 \x03(\v2\x1e.marvel.character.v1.CharacterR\n" +
+	"characters\x12\x18\n" +
+	"\amissing\x18\x02 \x03(\x03R\amissing\x12\x1b\n" +
+	"\tnot_found\x18\x03 \x03(\x03R\bnotFound\"O\n" +
+	"\x17StreamCharactersRequest\x12\x1b\n" +
+	"\tfrom_page\x18\x01 \x01(\x05R\bfromPage\x12\x17\n" +
+	"\ato_page\x18\x02 \x01(\x05R\x06toPage2\xb2\x03\n" +
+	"\x10CharacterService\x12X\n" +
+	"\fGetCharacter\x12(.marvel.character.v1.GetCharacterRequest\x1a\x1e.marvel.character.v1.Character\x12i\n" +
+	"\x0eListCharacters\x12*.marvel.character.v1.ListCharactersRequest\x1a+.marvel.character.v1.ListCharactersResponse\x12u\n" +
+	"\x12BatchGetCharacters\x12..marvel.character.v1.BatchGetCharactersRequest\x1a/.marvel.character.v1.BatchGetCharactersResponse\x12b\n" +
+	"\x10StreamCharacters\x12,.marvel.character.v1.StreamCharactersRequest\x1a\x1e.marvel.character.v1.Character0\x01BWZUgithub.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/grpc/characterpbb\x06proto3"
+
+var (
+	file_character_proto_rawDescOnce sync.Once
+	file_character_proto_rawDescData []byte
+)
+
+func file_character_proto_rawDescGZIP() []byte {
+	file_character_proto_rawDescOnce.Do(func() {
+		file_character_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_character_proto_rawDesc), len(file_character_proto_rawDesc)))
+	})
+	return file_character_proto_rawDescData
+}
+
+var file_character_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_character_proto_goTypes = []any{
+	(*Character)(nil),                  // 0: marvel.character.v1.Character
+	(*GetCharacterRequest)(nil),        // 1: marvel.character.v1.GetCharacterRequest
+	(*ListCharactersRequest)(nil),      // 2: marvel.character.v1.ListCharactersRequest
+	(*ListCharactersResponse)(nil),     // 3: marvel.character.v1.ListCharactersResponse
+	(*BatchGetCharactersRequest)(nil),  // 4: marvel.character.v1.BatchGetCharactersRequest
+	(*BatchGetCharactersResponse)(nil), // 5: marvel.character.v1.BatchGetCharactersResponse
+	(*StreamCharactersRequest)(nil),    // 6: marvel.character.v1.StreamCharactersRequest
+	(*timestamppb.Timestamp)(nil),      // 7: google.protobuf.Timestamp
+}
+var file_character_proto_depIdxs = []int32{
+	7, // 0: marvel.character.v1.Character.fetched_at:type_name -> google.protobuf.Timestamp
+	0, // 1: marvel.character.v1.ListCharactersResponse.characters:type_name -> marvel.character.v1.Character
+	0, // 2: marvel.character.v1.BatchGetCharactersResponse.characters:type_name -> marvel.character.v1.Character
+	1, // 3: marvel.character.v1.CharacterService.GetCharacter:input_type -> marvel.character.v1.GetCharacterRequest
+	2, // 4: marvel.character.v1.CharacterService.ListCharacters:input_type -> marvel.character.v1.ListCharactersRequest
+	4, // 5: marvel.character.v1.CharacterService.BatchGetCharacters:input_type -> marvel.character.v1.BatchGetCharactersRequest
+	6, // 6: marvel.character.v1.CharacterService.StreamCharacters:input_type -> marvel.character.v1.StreamCharactersRequest
+	0, // 7: marvel.character.v1.CharacterService.GetCharacter:output_type -> marvel.character.v1.Character
+	3, // 8: marvel.character.v1.CharacterService.ListCharacters:output_type -> marvel.character.v1.ListCharactersResponse
+	5, // 9: marvel.character.v1.CharacterService.BatchGetCharacters:output_type -> marvel.character.v1.BatchGetCharactersResponse
+	0, // 10: marvel.character.v1.CharacterService.StreamCharacters:output_type -> marvel.character.v1.Character
+	7, // [7:11] is the sub-list for method output_type
+	3, // [3:7] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_character_proto_init() }
+func file_character_proto_init() {
+	if File_character_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_character_proto_rawDesc), len(file_character_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_character_proto_goTypes,
+		DependencyIndexes: file_character_proto_depIdxs,
+		MessageInfos:      file_character_proto_msgTypes,
+	}.Build()
+	File_character_proto = out.File
+	file_character_proto_goTypes = nil
+	file_character_proto_depIdxs = nil
+}
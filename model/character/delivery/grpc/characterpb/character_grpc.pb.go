@@ -0,0 +1,253 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: character.proto
+
+package characterpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CharacterService_GetCharacter_FullMethodName       = "/marvel.character.v1.CharacterService/GetCharacter"
+	CharacterService_ListCharacters_FullMethodName     = "/marvel.character.v1.CharacterService/ListCharacters"
+	CharacterService_BatchGetCharacters_FullMethodName = "/marvel.character.v1.CharacterService/BatchGetCharacters"
+	CharacterService_StreamCharacters_FullMethodName   = "/marvel.character.v1.CharacterService/StreamCharacters"
+)
+
+// CharacterServiceClient is the client API for CharacterService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CharacterService serves the cached Marvel characters. Failures carry a
+// google.rpc.ErrorInfo whose reason is the error code of the HTTP API, and a
+// google.rpc.RetryInfo when the resource is not cached yet.
+type CharacterServiceClient interface {
+	GetCharacter(ctx context.Context, in *GetCharacterRequest, opts ...grpc.CallOption) (*Character, error)
+	ListCharacters(ctx context.Context, in *ListCharactersRequest, opts ...grpc.CallOption) (*ListCharactersResponse, error)
+	BatchGetCharacters(ctx context.Context, in *BatchGetCharactersRequest, opts ...grpc.CallOption) (*BatchGetCharactersResponse, error)
+	// StreamCharacters sends the cached characters of a range of pages, page
+	// by page. The IDs of the characters not cached yet are sent in the
+	// missing-ids trailer, those Marvel API does not know in not-found-ids.
+	StreamCharacters(ctx context.Context, in *StreamCharactersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Character], error)
+}
+
+type characterServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCharacterServiceClient(cc grpc.ClientConnInterface) CharacterServiceClient {
+	return &characterServiceClient{cc}
+}
+
+func (c *characterServiceClient) GetCharacter(ctx context.Context, in *GetCharacterRequest, opts ...grpc.CallOption) (*Character, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Character)
+	err := c.cc.Invoke(ctx, CharacterService_GetCharacter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *characterServiceClient) ListCharacters(ctx context.Context, in *ListCharactersRequest, opts ...grpc.CallOption) (*ListCharactersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCharactersResponse)
+	err := c.cc.Invoke(ctx, CharacterService_ListCharacters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *characterServiceClient) BatchGetCharacters(ctx context.Context, in *BatchGetCharactersRequest, opts ...grpc.CallOption) (*BatchGetCharactersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetCharactersResponse)
+	err := c.cc.Invoke(ctx, CharacterService_BatchGetCharacters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *characterServiceClient) StreamCharacters(ctx context.Context, in *StreamCharactersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Character], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CharacterService_ServiceDesc.Streams[0], CharacterService_StreamCharacters_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamCharactersRequest, Character]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CharacterService_StreamCharactersClient = grpc.ServerStreamingClient[Character]
+
+// CharacterServiceServer is the server API for CharacterService service.
+// All implementations must embed UnimplementedCharacterServiceServer
+// for forward compatibility.
+//
+// CharacterService serves the cached Marvel characters. Failures carry a
+// google.rpc.ErrorInfo whose reason is the error code of the HTTP API, and a
+// google.rpc.RetryInfo when the resource is not cached yet.
+type CharacterServiceServer interface {
+	GetCharacter(context.Context, *GetCharacterRequest) (*Character, error)
+	ListCharacters(context.Context, *ListCharactersRequest) (*ListCharactersResponse, error)
+	BatchGetCharacters(context.Context, *BatchGetCharactersRequest) (*BatchGetCharactersResponse, error)
+	// StreamCharacters sends the cached characters of a range of pages, page
+	// by page. The IDs of the characters not cached yet are sent in the
+	// missing-ids trailer, those Marvel API does not know in not-found-ids.
+	StreamCharacters(*StreamCharactersRequest, grpc.ServerStreamingServer[Character]) error
+	mustEmbedUnimplementedCharacterServiceServer()
+}
+
+// UnimplementedCharacterServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCharacterServiceServer struct{}
+
+func (UnimplementedCharacterServiceServer) GetCharacter(context.Context, *GetCharacterRequest) (*Character, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCharacter not implemented")
+}
+func (UnimplementedCharacterServiceServer) ListCharacters(context.Context, *ListCharactersRequest) (*ListCharactersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCharacters not implemented")
+}
+func (UnimplementedCharacterServiceServer) BatchGetCharacters(context.Context, *BatchGetCharactersRequest) (*BatchGetCharactersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetCharacters not implemented")
+}
+func (UnimplementedCharacterServiceServer) StreamCharacters(*StreamCharactersRequest, grpc.ServerStreamingServer[Character]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamCharacters not implemented")
+}
+func (UnimplementedCharacterServiceServer) mustEmbedUnimplementedCharacterServiceServer() {}
+func (UnimplementedCharacterServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeCharacterServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CharacterServiceServer will
+// result in compilation errors.
+type UnsafeCharacterServiceServer interface {
+	mustEmbedUnimplementedCharacterServiceServer()
+}
+
+func RegisterCharacterServiceServer(s grpc.ServiceRegistrar, srv CharacterServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCharacterServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CharacterService_ServiceDesc, srv)
+}
+
+func _CharacterService_GetCharacter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCharacterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CharacterServiceServer).GetCharacter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CharacterService_GetCharacter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CharacterServiceServer).GetCharacter(ctx, req.(*GetCharacterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CharacterService_ListCharacters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCharactersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CharacterServiceServer).ListCharacters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CharacterService_ListCharacters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CharacterServiceServer).ListCharacters(ctx, req.(*ListCharactersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CharacterService_BatchGetCharacters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetCharactersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CharacterServiceServer).BatchGetCharacters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CharacterService_BatchGetCharacters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CharacterServiceServer).BatchGetCharacters(ctx, req.(*BatchGetCharactersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CharacterService_StreamCharacters_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamCharactersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CharacterServiceServer).StreamCharacters(m, &grpc.GenericServerStream[StreamCharactersRequest, Character]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CharacterService_StreamCharactersServer = grpc.ServerStreamingServer[Character]
+
+// CharacterService_ServiceDesc is the grpc.ServiceDesc for CharacterService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CharacterService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "marvel.character.v1.CharacterService",
+	HandlerType: (*CharacterServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCharacter",
+			Handler:    _CharacterService_GetCharacter_Handler,
+		},
+		{
+			MethodName: "ListCharacters",
+			Handler:    _CharacterService_ListCharacters_Handler,
+		},
+		{
+			MethodName: "BatchGetCharacters",
+			Handler:    _CharacterService_BatchGetCharacters_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamCharacters",
+			Handler:       _CharacterService_StreamCharacters_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "character.proto",
+}
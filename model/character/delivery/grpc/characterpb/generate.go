@@ -0,0 +1,5 @@
+// Package characterpb holds the protobuf messages and the gRPC stubs of the
+// character service, generated from character.proto.
+package characterpb
+
+//go:generate buf generate
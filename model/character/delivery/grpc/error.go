@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+// ErrorDomain is the domain of the google.rpc.ErrorInfo attached to every
+// failure. Its reason is the domain error code.
+const ErrorDomain = "golang-marvel-demo"
+
+// CodeOf maps the code of err to a gRPC status code.
+func CodeOf(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+
+	switch domain.CodeOf(err) {
+	case domain.CodeNotFound:
+		return codes.NotFound
+	case domain.CodeBadRequest:
+		return codes.InvalidArgument
+	case domain.CodeNotCached:
+		return codes.Unavailable
+	case domain.CodeUnauthorized:
+		return codes.Unauthenticated
+	case domain.CodeForbidden:
+		return codes.PermissionDenied
+	case domain.CodeRateLimited:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// toStatus converts err to a status error. Like problem responses, only the
+// message of the domain error is disclosed, never the error it wraps.
+func toStatus(err error) error {
+	p := problem.FromError(err, "")
+	message := p.Detail
+	if message == "" {
+		message = domain.ErrInternalServerError.Message
+	}
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: p.Code, Domain: ErrorDomain}}
+	if p.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(time.Duration(p.RetryAfter) * time.Second),
+		})
+	}
+
+	st, detailErr := status.New(CodeOf(err), message).WithDetails(details...)
+	if detailErr != nil {
+		return status.Error(CodeOf(err), message)
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/ratelimit"
+)
+
+// Guard holds what calls are checked against, like the HTTP requests for
+// characters. Methods authenticate calls by their metadata, and any is then
+// required. Limits are enforced when Limiter is set.
+type Guard struct {
+	Methods []auth.Method
+	Limiter ratelimit.Limiter
+	Limits  *ratelimit.DynamicLimits
+}
+
+type guard struct {
+	Guard
+	echo   *echo.Echo
+	logger *slog.Logger
+}
+
+// Interceptors returns the server options checking unary calls and streams
+// against g.
+func Interceptors(g Guard, logger *slog.Logger) []rpc.ServerOption {
+	gd := &guard{Guard: g, echo: echo.New(), logger: logger}
+
+	return []rpc.ServerOption{
+		rpc.ChainUnaryInterceptor(gd.unary),
+		rpc.ChainStreamInterceptor(gd.stream),
+	}
+}
+
+func (g *guard) unary(ctx context.Context, req interface{}, info *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+	ctx, err := g.check(ctx, info.FullMethod)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return handler(ctx, req)
+}
+
+func (g *guard) stream(srv interface{}, ss rpc.ServerStream, info *rpc.StreamServerInfo, handler rpc.StreamHandler) error {
+	ctx, err := g.check(ss.Context(), info.FullMethod)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	return handler(srv, &guardedStream{ServerStream: ss, ctx: ctx})
+}
+
+// check authenticates and limits the call, returning the context the call
+// runs with: with its identity and the miss limit of its client. The auth
+// methods and client keys read HTTP requests, so the metadata and peer of the
+// call are handed to them as one.
+func (g *guard) check(ctx context.Context, method string) (context.Context, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+	if err != nil {
+		return ctx, err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		// Pseudo-headers such as :authority are not headers.
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+	}
+	c := g.echo.NewContext(req, nil)
+
+	if len(g.Methods) > 0 {
+		id, err := auth.Authenticate(c, auth.ScopeCharactersRead, g.Methods...)
+		if err != nil {
+			return ctx, err
+		}
+		ctx = auth.WithIdentity(ctx, id)
+		c.SetRequest(req.WithContext(ctx))
+	}
+
+	if g.Limiter != nil {
+		ctx, _, err = ratelimit.Check(ctx, g.Limiter, g.Limits.Load(), ratelimit.ClientKey(c), g.logger)
+		if err != nil {
+			return ctx, err
+		}
+	}
+
+	return ctx, nil
+}
+
+type guardedStream struct {
+	rpc.ServerStream
+	ctx context.Context
+}
+
+func (s *guardedStream) Context() context.Context {
+	return s.ctx
+}
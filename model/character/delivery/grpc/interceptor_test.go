@@ -0,0 +1,116 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	characterGrpc "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/grpc"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/grpc/characterpb"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/ratelimit"
+)
+
+type GuardTestSuite struct {
+	suite.Suite
+	server  *rpc.Server
+	conn    *rpc.ClientConn
+	client  characterpb.CharacterServiceClient
+	usecase *mocks.CharacterUsecase
+	apiKeys *mocks.APIKeyUsecase
+}
+
+func TestGuard(t *testing.T) {
+	suite.Run(t, new(GuardTestSuite))
+}
+
+func (s *GuardTestSuite) SetupTest() {
+	s.usecase = new(mocks.CharacterUsecase)
+	s.apiKeys = new(mocks.APIKeyUsecase)
+	key := domain.APIKey{ID: "lorem"}
+	s.apiKeys.On("Authenticate", mock.Anything, "secret").Return(key, nil)
+	s.apiKeys.On("Authenticate", mock.Anything, mock.Anything).Return(domain.APIKey{}, domain.ErrUnauthorized)
+	s.apiKeys.On("Consume", mock.Anything, key).Return(domain.APIKeyUsage{}, nil)
+
+	lis := bufconn.Listen(1 << 20)
+	s.server = rpc.NewServer(characterGrpc.Interceptors(characterGrpc.Guard{
+		Methods: []auth.Method{auth.APIKeyMethod(s.apiKeys)},
+		Limiter: ratelimit.NewMemoryLimiter(),
+		Limits: ratelimit.NewDynamicLimits(ratelimit.Limits{
+			Client: ratelimit.Limit{PerMinute: 1, Burst: 2},
+			Miss:   ratelimit.Limit{PerMinute: 1, Burst: 1},
+		}),
+	}, logger.Discard())...)
+	characterGrpc.NewCharacterServer(s.server, s.usecase, logger.Discard())
+	go s.server.Serve(lis)
+
+	conn, err := rpc.NewClient("passthrough:///bufconn",
+		rpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		rpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+	s.conn = conn
+	s.client = characterpb.NewCharacterServiceClient(conn)
+}
+
+func (s *GuardTestSuite) TearDownTest() {
+	s.conn.Close()
+	s.server.Stop()
+}
+
+func (s *GuardTestSuite) withKey(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", token)
+}
+
+func (s *GuardTestSuite) TestFailedUnauthenticated() {
+	_, err := s.client.GetCharacter(context.Background(), &characterpb.GetCharacterRequest{Id: 1})
+	s.Assert().Equal(codes.Unauthenticated, status.Code(err))
+
+	_, err = s.client.GetCharacter(s.withKey("wrong"), &characterpb.GetCharacterRequest{Id: 1})
+	s.Assert().Equal(codes.Unauthenticated, status.Code(err))
+
+	stream, err := s.client.StreamCharacters(context.Background(), &characterpb.StreamCharactersRequest{FromPage: 1})
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Assert().Equal(codes.Unauthenticated, status.Code(err))
+
+	s.usecase.AssertNotCalled(s.T(), "GetByID", mock.Anything, mock.Anything)
+	s.usecase.AssertNotCalled(s.T(), "FetchCharacters", mock.Anything, mock.Anything)
+}
+
+func (s *GuardTestSuite) TestSuccessAuthenticatedAndLimited() {
+	var calls []context.Context
+	s.usecase.On("GetByID", mock.Anything, 1).Return(domain.Character{ID: 1}, nil).Run(func(args mock.Arguments) {
+		calls = append(calls, args.Get(0).(context.Context))
+	})
+
+	_, err := s.client.GetCharacter(s.withKey("secret"), &characterpb.GetCharacterRequest{Id: 1})
+	s.Require().NoError(err)
+	s.Require().Len(calls, 1)
+	id, ok := auth.IdentityFrom(calls[0])
+	s.Assert().True(ok)
+	s.Assert().Equal("lorem", id.Subject)
+	s.apiKeys.AssertNumberOfCalls(s.T(), "Consume", 1)
+
+	// Misses are charged to the client of the call.
+	s.Assert().NoError(ratelimit.AllowMiss(calls[0]))
+	s.Assert().ErrorIs(ratelimit.AllowMiss(calls[0]), domain.ErrRateLimited)
+
+	_, err = s.client.GetCharacter(s.withKey("secret"), &characterpb.GetCharacterRequest{Id: 1})
+	s.Require().NoError(err)
+	_, err = s.client.GetCharacter(s.withKey("secret"), &characterpb.GetCharacterRequest{Id: 1})
+	s.Assert().Equal(codes.ResourceExhausted, status.Code(err))
+	s.Assert().Len(calls, 2)
+}
@@ -0,0 +1,203 @@
+// Package grpc serves the characters over gRPC with the CharacterService of
+// characterpb/character.proto.
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/grpc/characterpb"
+)
+
+// maxStreamPages bounds the pages a single StreamCharacters call reads.
+const maxStreamPages = 20
+
+// MissingIDsTrailer is the trailer StreamCharacters lists the characters not
+// cached yet in, and NotFoundIDsTrailer the one it lists those Marvel API does
+// not know in.
+const (
+	MissingIDsTrailer  = "missing-ids"
+	NotFoundIDsTrailer = "not-found-ids"
+)
+
+type CharacterServer struct {
+	characterpb.UnimplementedCharacterServiceServer
+	Usecase domain.CharacterUsecase
+	Logger  *slog.Logger
+}
+
+func NewCharacterServer(s *rpc.Server, u domain.CharacterUsecase, logger *slog.Logger) *CharacterServer {
+	server := &CharacterServer{
+		Usecase: u,
+		Logger:  logger,
+	}
+	characterpb.RegisterCharacterServiceServer(s, server)
+
+	return server
+}
+
+func (s *CharacterServer) GetCharacter(ctx context.Context, req *characterpb.GetCharacterRequest) (*characterpb.Character, error) {
+	character, err := s.Usecase.GetByID(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, s.error(ctx, err, "GetCharacter", slog.Int64("id", req.GetId()))
+	}
+
+	return toCharacter(character), nil
+}
+
+func (s *CharacterServer) ListCharacters(ctx context.Context, req *characterpb.ListCharactersRequest) (*characterpb.ListCharactersResponse, error) {
+	page := int(req.GetPage())
+	if page == 0 {
+		page = 1
+	}
+
+	if req.GetExpand() {
+		characters, err := s.Usecase.FetchCharacters(ctx, page)
+		if err != nil {
+			return nil, s.error(ctx, err, "ListCharacters", slog.Int("page", page))
+		}
+
+		return &characterpb.ListCharactersResponse{
+			Page:       int32(characters.Page),
+			Characters: toCharacters(characters.Characters),
+			Missing:    int64s(characters.Missing),
+			NotFound:   int64s(characters.NotFound),
+			Partial:    characters.Partial,
+		}, nil
+	}
+
+	ids, err := s.Usecase.Fetch(ctx, page)
+	if err != nil {
+		return nil, s.error(ctx, err, "ListCharacters", slog.Int("page", page))
+	}
+
+	return &characterpb.ListCharactersResponse{
+		Page: int32(page),
+		Ids:  int64s(ids),
+	}, nil
+}
+
+func (s *CharacterServer) BatchGetCharacters(ctx context.Context, req *characterpb.BatchGetCharactersRequest) (*characterpb.BatchGetCharactersResponse, error) {
+	ids := make([]int, 0, len(req.GetIds()))
+	for _, id := range req.GetIds() {
+		ids = append(ids, int(id))
+	}
+
+	batch, err := s.Usecase.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, s.error(ctx, err, "BatchGetCharacters", slog.Int("ids", len(ids)))
+	}
+
+	return &characterpb.BatchGetCharactersResponse{
+		Characters: toCharacters(batch.Characters),
+		Missing:    int64s(batch.Missing),
+		NotFound:   int64s(batch.NotFound),
+	}, nil
+}
+
+// StreamCharacters sends the characters of the pages from FromPage to
+// ToPage. It stops early, without failing, at the first page past the end of
+// the list.
+func (s *CharacterServer) StreamCharacters(req *characterpb.StreamCharactersRequest, stream characterpb.CharacterService_StreamCharactersServer) error {
+	from := int(req.GetFromPage())
+	if from == 0 {
+		from = 1
+	}
+	to := int(req.GetToPage())
+	if to == 0 {
+		to = from
+	}
+	if to < from || to-from >= maxStreamPages {
+		return toStatus(domain.NewError(domain.CodeBadRequest, "to_page must be within "+strconv.Itoa(maxStreamPages)+" pages after from_page"))
+	}
+
+	ctx := stream.Context()
+
+	var missing, notFound []string
+	defer func() {
+		trailer := metadata.MD{}
+		if len(missing) > 0 {
+			trailer.Set(MissingIDsTrailer, missing...)
+		}
+		if len(notFound) > 0 {
+			trailer.Set(NotFoundIDsTrailer, notFound...)
+		}
+		if len(trailer) > 0 {
+			stream.SetTrailer(trailer)
+		}
+	}()
+
+	for page := from; page <= to; page++ {
+		characters, err := s.Usecase.FetchCharacters(ctx, page)
+		if err != nil {
+			if page > from && domain.CodeOf(err) == domain.CodeNotFound {
+				return nil
+			}
+			return s.error(ctx, err, "StreamCharacters", slog.Int("page", page))
+		}
+
+		for _, character := range characters.Characters {
+			if err := stream.Send(toCharacter(character)); err != nil {
+				return err
+			}
+		}
+		for _, id := range characters.Missing {
+			missing = append(missing, strconv.Itoa(id))
+		}
+		for _, id := range characters.NotFound {
+			notFound = append(notFound, strconv.Itoa(id))
+		}
+	}
+
+	return nil
+}
+
+// error logs server side failures, which the status does not detail, then
+// converts err to a status.
+func (s *CharacterServer) error(ctx context.Context, err error, method string, attrs ...slog.Attr) error {
+	st := toStatus(err)
+	if status.Code(st) == codes.Internal {
+		s.Logger.LogAttrs(ctx, slog.LevelError, "Call failed", append(attrs, slog.String("method", method), slog.Any("error", err))...)
+	}
+
+	return st
+}
+
+func toCharacter(character domain.Character) *characterpb.Character {
+	c := &characterpb.Character{
+		Id:          int64(character.ID),
+		Name:        character.Name,
+		Description: character.Description,
+	}
+	if !character.FetchedAt.IsZero() {
+		c.FetchedAt = timestamppb.New(character.FetchedAt)
+	}
+
+	return c
+}
+
+func toCharacters(characters []domain.Character) []*characterpb.Character {
+	out := make([]*characterpb.Character, 0, len(characters))
+	for _, character := range characters {
+		out = append(out, toCharacter(character))
+	}
+
+	return out
+}
+
+func int64s(ids []int) []int64 {
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, int64(id))
+	}
+
+	return out
+}
@@ -0,0 +1,209 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	characterGrpc "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/grpc"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/grpc/characterpb"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+type CharacterServerTestSuite struct {
+	suite.Suite
+	server  *rpc.Server
+	conn    *rpc.ClientConn
+	client  characterpb.CharacterServiceClient
+	usecase *mocks.CharacterUsecase
+}
+
+func TestCharacterServer(t *testing.T) {
+	suite.Run(t, new(CharacterServerTestSuite))
+}
+
+func (s *CharacterServerTestSuite) SetupTest() {
+	s.usecase = new(mocks.CharacterUsecase)
+
+	lis := bufconn.Listen(1 << 20)
+	s.server = rpc.NewServer()
+	characterGrpc.NewCharacterServer(s.server, s.usecase, logger.Discard())
+	go s.server.Serve(lis)
+
+	conn, err := rpc.NewClient("passthrough:///bufconn",
+		rpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		rpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+	s.conn = conn
+	s.client = characterpb.NewCharacterServiceClient(conn)
+}
+
+func (s *CharacterServerTestSuite) TearDownTest() {
+	s.conn.Close()
+	s.server.Stop()
+}
+
+func (s *CharacterServerTestSuite) TestSuccessGetCharacter() {
+	fetchedAt := time.Date(2021, 7, 21, 10, 8, 56, 0, time.UTC)
+	character := domain.Character{ID: 1, Name: "Lorem", Description: "Ipsum", FetchedAt: fetchedAt}
+	s.usecase.On("GetByID", mock.Anything, 1).Return(character, nil).Once()
+
+	res, err := s.client.GetCharacter(context.Background(), &characterpb.GetCharacterRequest{Id: 1})
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(1), res.GetId())
+	s.Assert().Equal("Lorem", res.GetName())
+	s.Assert().Equal("Ipsum", res.GetDescription())
+	s.Assert().Equal(fetchedAt, res.GetFetchedAt().AsTime())
+}
+
+func (s *CharacterServerTestSuite) TestFailedGetCharacter() {
+	cases := []struct {
+		err  error
+		code codes.Code
+	}{
+		{domain.ErrNotFound, codes.NotFound},
+		{domain.ErrBadRequest, codes.InvalidArgument},
+		{domain.ErrCacheKeyEmpty, codes.Unavailable},
+		{domain.ErrInternalServerError.Wrap(errors.New("redis: connection refused")), codes.Internal},
+		{errors.New("unexpected"), codes.Internal},
+	}
+
+	for _, c := range cases {
+		s.usecase.On("GetByID", mock.Anything, 1).Return(domain.Character{}, c.err).Once()
+
+		_, err := s.client.GetCharacter(context.Background(), &characterpb.GetCharacterRequest{Id: 1})
+		st := status.Convert(err)
+		s.Assert().Equal(c.code, st.Code(), c.err.Error())
+		s.Assert().NotContains(st.Message(), "redis")
+
+		s.Require().NotEmpty(st.Details())
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		s.Require().True(ok)
+		s.Assert().Equal(characterGrpc.ErrorDomain, info.GetDomain())
+		s.Assert().Equal(string(domain.CodeOf(c.err)), info.GetReason())
+	}
+}
+
+func (s *CharacterServerTestSuite) TestNotCachedRetryInfo() {
+	s.usecase.On("GetByID", mock.Anything, 1).Return(domain.Character{}, domain.ErrCacheKeyEmpty).Once()
+
+	_, err := s.client.GetCharacter(context.Background(), &characterpb.GetCharacterRequest{Id: 1})
+	st := status.Convert(err)
+	s.Assert().Equal("Resource not cached yet", st.Message())
+	s.Require().Len(st.Details(), 2)
+	retry, ok := st.Details()[1].(*errdetails.RetryInfo)
+	s.Require().True(ok)
+	s.Assert().Equal(5*time.Second, retry.GetRetryDelay().AsDuration())
+}
+
+func (s *CharacterServerTestSuite) TestSuccessListCharacters() {
+	s.usecase.On("Fetch", mock.Anything, 1).Return([]int{1, 2}, nil).Once()
+
+	res, err := s.client.ListCharacters(context.Background(), &characterpb.ListCharactersRequest{})
+	s.Require().NoError(err)
+	s.Assert().Equal(int32(1), res.GetPage())
+	s.Assert().Equal([]int64{1, 2}, res.GetIds())
+	s.Assert().Empty(res.GetCharacters())
+}
+
+func (s *CharacterServerTestSuite) TestSuccessListCharactersExpand() {
+	page := domain.CharacterPage{
+		Page:           2,
+		CharacterBatch: domain.CharacterBatch{Characters: []domain.Character{{ID: 1, Name: "Lorem"}}, Missing: []int{2}},
+		Partial:        true,
+	}
+	s.usecase.On("FetchCharacters", mock.Anything, 2).Return(page, nil).Once()
+
+	res, err := s.client.ListCharacters(context.Background(), &characterpb.ListCharactersRequest{Page: 2, Expand: true})
+	s.Require().NoError(err)
+	s.Assert().Equal(int32(2), res.GetPage())
+	s.Require().Len(res.GetCharacters(), 1)
+	s.Assert().Equal("Lorem", res.GetCharacters()[0].GetName())
+	s.Assert().Nil(res.GetCharacters()[0].GetFetchedAt())
+	s.Assert().Equal([]int64{2}, res.GetMissing())
+	s.Assert().True(res.GetPartial())
+}
+
+func (s *CharacterServerTestSuite) TestSuccessBatchGetCharacters() {
+	batch := domain.CharacterBatch{Characters: []domain.Character{{ID: 1, Name: "Lorem"}}, Missing: []int{2}, NotFound: []int{3}}
+	s.usecase.On("GetByIDs", mock.Anything, []int{1, 2, 3}).Return(batch, nil).Once()
+
+	res, err := s.client.BatchGetCharacters(context.Background(), &characterpb.BatchGetCharactersRequest{Ids: []int64{1, 2, 3}})
+	s.Require().NoError(err)
+	s.Require().Len(res.GetCharacters(), 1)
+	s.Assert().Equal(int64(1), res.GetCharacters()[0].GetId())
+	s.Assert().Equal([]int64{2}, res.GetMissing())
+	s.Assert().Equal([]int64{3}, res.GetNotFound())
+}
+
+func (s *CharacterServerTestSuite) TestFailedBatchGetCharacters() {
+	fail := domain.NewError(domain.CodeBadRequest, "between 1 and 100 ids are required")
+	s.usecase.On("GetByIDs", mock.Anything, []int{}).Return(domain.CharacterBatch{}, fail).Once()
+
+	_, err := s.client.BatchGetCharacters(context.Background(), &characterpb.BatchGetCharactersRequest{})
+	s.Assert().Equal(codes.InvalidArgument, status.Code(err))
+	s.Assert().Equal("between 1 and 100 ids are required", status.Convert(err).Message())
+}
+
+func (s *CharacterServerTestSuite) TestSuccessStreamCharacters() {
+	first := domain.CharacterPage{Page: 1, CharacterBatch: domain.CharacterBatch{
+		Characters: []domain.Character{{ID: 1}, {ID: 2}},
+		Missing:    []int{3},
+	}}
+	second := domain.CharacterPage{Page: 2, CharacterBatch: domain.CharacterBatch{
+		Characters: []domain.Character{{ID: 4}},
+		Missing:    []int{5},
+	}}
+	s.usecase.On("FetchCharacters", mock.Anything, 1).Return(first, nil).Once()
+	s.usecase.On("FetchCharacters", mock.Anything, 2).Return(second, nil).Once()
+	s.usecase.On("FetchCharacters", mock.Anything, 3).Return(domain.CharacterPage{}, domain.ErrNotFound).Once()
+
+	stream, err := s.client.StreamCharacters(context.Background(), &characterpb.StreamCharactersRequest{FromPage: 1, ToPage: 5})
+	s.Require().NoError(err)
+
+	var ids []int64
+	for {
+		character, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		s.Require().NoError(err)
+		ids = append(ids, character.GetId())
+	}
+	s.Assert().Equal([]int64{1, 2, 4}, ids)
+	s.Assert().Equal([]string{"3", "5"}, stream.Trailer().Get(characterGrpc.MissingIDsTrailer))
+	s.usecase.AssertNotCalled(s.T(), "FetchCharacters", mock.Anything, 4)
+}
+
+func (s *CharacterServerTestSuite) TestFailedStreamCharacters() {
+	s.usecase.On("FetchCharacters", mock.Anything, 7).Return(domain.CharacterPage{}, domain.ErrNotFound).Once()
+
+	stream, err := s.client.StreamCharacters(context.Background(), &characterpb.StreamCharactersRequest{FromPage: 7})
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Assert().Equal(codes.NotFound, status.Code(err))
+	s.Assert().Empty(stream.Trailer().Get(characterGrpc.MissingIDsTrailer))
+}
+
+func (s *CharacterServerTestSuite) TestFailedStreamCharactersRange() {
+	stream, err := s.client.StreamCharacters(context.Background(), &characterpb.StreamCharactersRequest{FromPage: 1, ToPage: 21})
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Assert().Equal(codes.InvalidArgument, status.Code(err))
+	s.usecase.AssertNotCalled(s.T(), "FetchCharacters", mock.Anything, mock.Anything)
+}
@@ -1,53 +1,54 @@
 package http
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo"
 
 	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/httpcache"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
 )
 
-type ResponseError struct {
-	Message string `json:"message"`
-}
-
-func getStatusCode(err error) int {
-	if err == nil {
-		return http.StatusOK
-	}
-
-	fmt.Errorf("HTTP Error: %v", err)
-	switch err {
-	case domain.ErrInternalServerError:
-		return http.StatusInternalServerError
-	case domain.ErrNotFound:
-		return http.StatusNotFound
-	case domain.ErrCacheKeyEmpty:
-		return http.StatusNotFound
-	default:
-		return http.StatusInternalServerError
-	}
-}
-
 type CharacterHandler struct {
 	Usecase domain.CharacterUsecase
+	Logger  *slog.Logger
 }
 
-func NewCharacterHandler(e *echo.Echo, u domain.CharacterUsecase) *CharacterHandler {
+// NewCharacterHandler registers the character endpoints, behind middleware
+// when given, such as API key authentication.
+func NewCharacterHandler(e *echo.Echo, u domain.CharacterUsecase, logger *slog.Logger, middleware ...echo.MiddlewareFunc) *CharacterHandler {
 	handler := &CharacterHandler{
 		Usecase: u,
+		Logger:  logger,
 	}
-	e.GET("/characters", handler.Fetch)
-	e.GET("/characters/", handler.Fetch)
-	e.GET("/characters/:id", handler.GetByID)
+	e.GET("/characters", handler.Fetch, middleware...)
+	e.GET("/characters/", handler.Fetch, middleware...)
+	e.GET("/characters/popular", handler.Popular, middleware...)
+	e.GET("/characters/:id", handler.GetByID, middleware...)
+	// The router reads the colon as a parameter, the action is checked by
+	// the handler.
+	e.POST("/characters:action", handler.Action, middleware...)
 
 	return handler
 }
 
 func (h *CharacterHandler) Fetch(c echo.Context) error {
+	if idsRaw := c.QueryParam("ids"); idsRaw != "" {
+		ids, err := parseIDs(idsRaw)
+		if err != nil {
+			return problem.Error(c, domain.NewError(domain.CodeBadRequest, "ids must be comma separated integers"))
+		}
+		return h.batchGet(c, ids)
+	}
+	if order := c.QueryParam("order"); order != "" {
+		return h.rangeCharacters(c, order)
+	}
+
 	pageRaw := c.QueryParam("page")
 	if pageRaw == "" {
 		pageRaw = "1"
@@ -55,31 +56,165 @@ func (h *CharacterHandler) Fetch(c echo.Context) error {
 
 	page, err := strconv.Atoi(pageRaw)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{Message: "Bad request param"})
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "page must be an integer"))
 	}
 
 	ctx := c.Request().Context()
 
+	switch c.QueryParam("expand") {
+	case "":
+	case "characters":
+		characters, err := h.Usecase.FetchCharacters(ctx, page)
+		if err != nil {
+			return h.error(c, err, slog.Int("page", page))
+		}
+		return c.JSON(http.StatusOK, characters)
+	default:
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "expand only supports characters"))
+	}
+
 	IDs, err := h.Usecase.Fetch(ctx, page)
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return h.error(c, err, slog.Int("page", page))
 	}
 
-	return c.JSON(getStatusCode(err), IDs)
+	return c.JSON(http.StatusOK, IDs)
 }
 
 func (h *CharacterHandler) GetByID(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{Message: "Bad request param"})
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "id must be an integer"))
 	}
 
 	ctx := c.Request().Context()
 
 	character, err := h.Usecase.GetByID(ctx, id)
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return h.error(c, err, slog.Int("id", id))
+	}
+
+	return httpcache.JSON(c, character, character.FetchedAt, character.ExpiresAt)
+}
+
+// Popular lists the characters looked up the most within the window query
+// parameter, a Go duration like 24h, the default.
+func (h *CharacterHandler) Popular(c echo.Context) error {
+	windowRaw := c.QueryParam("window")
+	if windowRaw == "" {
+		windowRaw = "24h"
+	}
+	window, err := time.ParseDuration(windowRaw)
+	if err != nil {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "window must be a duration like 24h"))
+	}
+
+	limitRaw := c.QueryParam("limit")
+	if limitRaw == "" {
+		limitRaw = "10"
+	}
+	limit, err := strconv.Atoi(limitRaw)
+	if err != nil {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "limit must be an integer"))
+	}
+
+	popular, err := h.Usecase.Popular(c.Request().Context(), window, limit)
+	if err != nil {
+		return h.error(c, err, slog.String("window", windowRaw))
+	}
+
+	return c.JSON(http.StatusOK, popular)
+}
+
+// rangeCharacters lists the cached characters by order, from the offset
+// query parameter on, 0 by default, and up to limit of them, 10 by default.
+func (h *CharacterHandler) rangeCharacters(c echo.Context, order string) error {
+	offset, limit := 0, 10
+	if raw := c.QueryParam("offset"); raw != "" {
+		var err error
+		if offset, err = strconv.Atoi(raw); err != nil {
+			return problem.Error(c, domain.NewError(domain.CodeBadRequest, "offset must be an integer"))
+		}
+	}
+	if raw := c.QueryParam("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			return problem.Error(c, domain.NewError(domain.CodeBadRequest, "limit must be an integer"))
+		}
+	}
+
+	ctx := c.Request().Context()
+
+	switch c.QueryParam("expand") {
+	case "":
+	case "characters":
+		batch, err := h.Usecase.RangeCharacters(ctx, order, offset, limit)
+		if err != nil {
+			return h.error(c, err, slog.String("order", order))
+		}
+		return c.JSON(http.StatusOK, batch)
+	default:
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "expand only supports characters"))
+	}
+
+	IDs, err := h.Usecase.Range(ctx, order, offset, limit)
+	if err != nil {
+		return h.error(c, err, slog.String("order", order))
+	}
+
+	return c.JSON(http.StatusOK, IDs)
+}
+
+// BatchGetRequest is the body of POST /characters:batchGet.
+type BatchGetRequest struct {
+	IDs []int `json:"ids"`
+}
+
+// Action serves the custom methods on the characters collection.
+func (h *CharacterHandler) Action(c echo.Context) error {
+	if c.Param("action") != ":batchGet" {
+		return problem.Error(c, domain.ErrNotFound)
+	}
+
+	var req BatchGetRequest
+	if err := c.Bind(&req); err != nil {
+		return problem.Error(c, domain.NewError(domain.CodeBadRequest, "body must be a JSON object with an ids array"))
+	}
+
+	return h.batchGet(c, req.IDs)
+}
+
+func (h *CharacterHandler) batchGet(c echo.Context, ids []int) error {
+	ctx := c.Request().Context()
+
+	batch, err := h.Usecase.GetByIDs(ctx, ids)
+	if err != nil {
+		return h.error(c, err, slog.Int("ids", len(ids)))
+	}
+
+	return c.JSON(http.StatusOK, batch)
+}
+
+func parseIDs(raw string) ([]int, error) {
+	parts := strings.Split(raw, ",")
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// error logs server side failures, which the response does not detail, then
+// writes err as a problem.
+func (h *CharacterHandler) error(c echo.Context, err error, attrs ...slog.Attr) error {
+	if problem.StatusOf(err) == http.StatusInternalServerError {
+		h.Logger.LogAttrs(c.Request().Context(), slog.LevelError, "Request failed", append(attrs, slog.Any("error", err))...)
 	}
 
-	return c.JSON(getStatusCode(err), character)
+	return problem.Error(c, err)
 }
@@ -17,6 +17,8 @@ import (
 	"github.com/hezbymuhammad/golang-marvel-demo/domain"
 	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
 	characterHttp "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/http"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
 )
 
 type CharacterHandlerTestSuite struct {
@@ -31,7 +33,7 @@ func TestCharacterHandler(t *testing.T) {
 
 func (s *CharacterHandlerTestSuite) SetupTest() {
 	s.usecase = new(mocks.CharacterUsecase)
-	s.handler = characterHttp.NewCharacterHandler(echo.New(), s.usecase)
+	s.handler = characterHttp.NewCharacterHandler(echo.New(), s.usecase, logger.Discard())
 
 }
 
@@ -72,7 +74,8 @@ func (s *CharacterHandlerTestSuite) TestWrongPageFetch() {
 	err = s.handler.Fetch(ctx)
 	s.Assert().Equal(err, nil)
 	s.Assert().Equal(http.StatusBadRequest, rec.Code)
-	s.Assert().Equal("{\"message\":\"Bad request param\"}\n", rec.Body.String())
+	s.Assert().Equal(problem.ContentType, rec.Header().Get(echo.HeaderContentType))
+	s.Assert().Equal(`{"type":"urn:golang-marvel-demo:problem:bad_request","title":"Bad Request","status":400,"detail":"page must be an integer","instance":"/characters?page=aaaa","code":"bad_request"}`, rec.Body.String())
 }
 
 func (s *CharacterHandlerTestSuite) TestFailedFetch() {
@@ -87,7 +90,7 @@ func (s *CharacterHandlerTestSuite) TestFailedFetch() {
 	err = s.handler.Fetch(ctx)
 	s.Assert().Equal(err, nil)
 	s.Assert().Equal(http.StatusInternalServerError, rec.Code)
-	s.Assert().Equal("{\"message\":\"SomeError\"}\n", rec.Body.String())
+	s.Assert().Equal(`{"type":"urn:golang-marvel-demo:problem:internal","title":"Internal Server Error","status":500,"instance":"/characters?page=1","code":"internal"}`, rec.Body.String())
 }
 
 func (s *CharacterHandlerTestSuite) TestSuccessGetByID() {
@@ -116,6 +119,45 @@ func (s *CharacterHandlerTestSuite) TestSuccessGetByID() {
 	s.Assert().Equal(string(json_data)+"\n", rec.Body.String())
 }
 
+func (s *CharacterHandlerTestSuite) TestNotModifiedGetByID() {
+	e := echo.New()
+	record := domain.Character{
+		ID:        5,
+		Name:      "Lorem",
+		FetchedAt: time.Date(2021, 7, 21, 10, 8, 56, 0, time.UTC),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	s.usecase.On("GetByID", mock.Anything, 5).Return(record, nil)
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(echo.GET, "/characters/5", strings.NewReader(""))
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		ctx := e.NewContext(req, rec)
+		ctx.SetPath("characters/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("5")
+
+		err := s.handler.GetByID(ctx)
+		s.Assert().Equal(err, nil)
+		return rec
+	}
+
+	rec := get("", "")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("Wed, 21 Jul 2021 10:08:56 GMT", rec.Header().Get("Last-Modified"))
+	s.Assert().Regexp(`^public, max-age=(3599|3600)$`, rec.Header().Get("Cache-Control"))
+
+	rec = get("If-None-Match", rec.Header().Get("ETag"))
+	s.Assert().Equal(http.StatusNotModified, rec.Code)
+	s.Assert().Empty(rec.Body.String())
+
+	rec = get("If-Modified-Since", "Wed, 21 Jul 2021 10:08:56 GMT")
+	s.Assert().Equal(http.StatusNotModified, rec.Code)
+}
+
 func (s *CharacterHandlerTestSuite) TestFailedGetByID() {
 	e := echo.New()
 	rec := httptest.NewRecorder()
@@ -138,7 +180,7 @@ func (s *CharacterHandlerTestSuite) TestFailedGetByID() {
 	err = s.handler.GetByID(ctx)
 	s.Assert().Equal(err, nil)
 	s.Assert().Equal(http.StatusInternalServerError, rec.Code)
-	s.Assert().Equal("{\"message\":\"SomeError\"}\n", rec.Body.String())
+	s.Assert().Equal(`{"type":"urn:golang-marvel-demo:problem:internal","title":"Internal Server Error","status":500,"instance":"/characters/2","code":"internal"}`, rec.Body.String())
 }
 
 func (s *CharacterHandlerTestSuite) TestNotFoundGetByID() {
@@ -163,7 +205,31 @@ func (s *CharacterHandlerTestSuite) TestNotFoundGetByID() {
 	err = s.handler.GetByID(ctx)
 	s.Assert().Equal(err, nil)
 	s.Assert().Equal(http.StatusNotFound, rec.Code)
-	s.Assert().Equal("{\"message\":\"Resource not found\"}\n", rec.Body.String())
+	s.Assert().Equal(`{"type":"urn:golang-marvel-demo:problem:not_found","title":"Not Found","status":404,"detail":"Resource not found","instance":"/characters/3","code":"not_found"}`, rec.Body.String())
+}
+
+func (s *CharacterHandlerTestSuite) TestNotCachedGetByID() {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+
+	req, err := http.NewRequest(echo.GET, "/characters/4", strings.NewReader(""))
+	ctx := e.NewContext(req, rec)
+	ctx.SetPath("characters/:id")
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("4")
+
+	s.usecase.On("GetByID", mock.Anything, 4).Return(domain.Character{}, domain.ErrCacheKeyEmpty)
+
+	err = s.handler.GetByID(ctx)
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal(http.StatusServiceUnavailable, rec.Code)
+	s.Assert().Equal(strconv.Itoa(problem.NotCachedRetryAfter), rec.Header().Get("Retry-After"))
+
+	var body problem.Problem
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal(string(domain.CodeNotCached), body.Code)
+	s.Assert().Equal(problem.NotCachedRetryAfter, body.RetryAfter)
 }
 
 func (s *CharacterHandlerTestSuite) TestWrongIDGetByID() {
@@ -176,5 +242,139 @@ func (s *CharacterHandlerTestSuite) TestWrongIDGetByID() {
 	err = s.handler.GetByID(ctx)
 	s.Assert().Equal(err, nil)
 	s.Assert().Equal(http.StatusBadRequest, rec.Code)
-	s.Assert().Equal("{\"message\":\"Bad request param\"}\n", rec.Body.String())
+	s.Assert().Equal(`{"type":"urn:golang-marvel-demo:problem:bad_request","title":"Bad Request","status":400,"detail":"id must be an integer","instance":"/characters/","code":"bad_request"}`, rec.Body.String())
+}
+
+func (s *CharacterHandlerTestSuite) serve(req *http.Request) *httptest.ResponseRecorder {
+	e := echo.New()
+	characterHttp.NewCharacterHandler(e, s.usecase, logger.Discard())
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *CharacterHandlerTestSuite) TestSuccessBatchGetQuery() {
+	batch := domain.CharacterBatch{
+		Characters: []domain.Character{{ID: 1, Name: "Lorem"}},
+		Missing:    []int{2},
+	}
+	s.usecase.On("GetByIDs", mock.Anything, []int{1, 2}).Return(batch, nil)
+
+	rec := s.serve(httptest.NewRequest(echo.GET, "/characters?ids=1,%202", nil))
+	s.Assert().Equal(http.StatusOK, rec.Code)
+
+	var res domain.CharacterBatch
+	err := json.Unmarshal(rec.Body.Bytes(), &res)
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal([]int{2}, res.Missing)
+	s.Assert().Equal("Lorem", res.Characters[0].Name)
+}
+
+func (s *CharacterHandlerTestSuite) TestWrongIDsBatchGetQuery() {
+	rec := s.serve(httptest.NewRequest(echo.GET, "/characters?ids=1,a", nil))
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+	s.usecase.AssertNotCalled(s.T(), "GetByIDs", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterHandlerTestSuite) TestSuccessBatchGetAction() {
+	batch := domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{3}}
+	s.usecase.On("GetByIDs", mock.Anything, []int{3}).Return(batch, nil)
+
+	req := httptest.NewRequest(echo.POST, "/characters:batchGet", strings.NewReader(`{"ids":[3]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := s.serve(req)
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("{\"characters\":[],\"missing\":[3]}\n", rec.Body.String())
+}
+
+func (s *CharacterHandlerTestSuite) TestFailedBatchGetAction() {
+	s.usecase.On("GetByIDs", mock.Anything, []int(nil)).Return(domain.CharacterBatch{}, domain.NewError(domain.CodeBadRequest, "between 1 and 100 ids are required"))
+
+	req := httptest.NewRequest(echo.POST, "/characters:batchGet", strings.NewReader(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := s.serve(req)
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(echo.POST, "/characters:batchGet", strings.NewReader(`{"ids":"1"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = s.serve(req)
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *CharacterHandlerTestSuite) TestUnknownAction() {
+	rec := s.serve(httptest.NewRequest(echo.POST, "/characters:delete", strings.NewReader(`{}`)))
+	s.Assert().Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *CharacterHandlerTestSuite) TestSuccessFetchExpand() {
+	page := domain.CharacterPage{
+		Page: 2,
+		CharacterBatch: domain.CharacterBatch{
+			Characters: []domain.Character{},
+			Missing:    []int{1},
+		},
+		Partial: true,
+	}
+	s.usecase.On("FetchCharacters", mock.Anything, 2).Return(page, nil)
+
+	rec := s.serve(httptest.NewRequest(echo.GET, "/characters?page=2&expand=characters", nil))
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("{\"page\":2,\"characters\":[],\"missing\":[1],\"partial\":true}\n", rec.Body.String())
+	s.usecase.AssertNotCalled(s.T(), "Fetch", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterHandlerTestSuite) TestWrongExpandFetch() {
+	rec := s.serve(httptest.NewRequest(echo.GET, "/characters?expand=comics", nil))
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (s *CharacterHandlerTestSuite) TestSuccessRange() {
+	s.usecase.On("Range", mock.Anything, domain.OrderByModified, 20, 5).Return([]int{3, 1}, nil)
+	s.usecase.On("RangeCharacters", mock.Anything, domain.OrderByName, 0, 10).Return(domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{1}}, nil)
+
+	rec := s.serve(httptest.NewRequest(echo.GET, "/characters?order=modified&offset=20&limit=5", nil))
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("[3,1]\n", rec.Body.String())
+
+	rec = s.serve(httptest.NewRequest(echo.GET, "/characters?order=name&expand=characters", nil))
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("{\"characters\":[],\"missing\":[1]}\n", rec.Body.String())
+	s.usecase.AssertNotCalled(s.T(), "Fetch", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterHandlerTestSuite) TestFailedRange() {
+	s.usecase.On("Range", mock.Anything, domain.OrderByName, 0, 10).Return(nil, domain.NewError(domain.CodeNotFound, "characters are only ordered by the hash layout"))
+
+	rec := s.serve(httptest.NewRequest(echo.GET, "/characters?order=name", nil))
+	s.Assert().Equal(http.StatusNotFound, rec.Code)
+
+	rec = s.serve(httptest.NewRequest(echo.GET, "/characters?order=name&offset=a", nil))
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+	rec = s.serve(httptest.NewRequest(echo.GET, "/characters?order=name&limit=a", nil))
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+	s.usecase.AssertNumberOfCalls(s.T(), "Range", 1)
+}
+
+func (s *CharacterHandlerTestSuite) TestSuccessPopular() {
+	popular := domain.PopularCharacters{Window: "1h0m0s", Characters: []domain.Hit{{ID: 1011334, Hits: 42}}}
+	s.usecase.On("Popular", mock.Anything, time.Hour, 5).Return(popular, nil)
+
+	rec := s.serve(httptest.NewRequest(echo.GET, "/characters/popular?window=1h&limit=5", nil))
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("{\"window\":\"1h0m0s\",\"characters\":[{\"id\":1011334,\"hits\":42}]}\n", rec.Body.String())
+	s.usecase.AssertNotCalled(s.T(), "GetByID", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterHandlerTestSuite) TestFailedPopular() {
+	s.usecase.On("Popular", mock.Anything, 24*time.Hour, 10).Return(domain.PopularCharacters{}, domain.NewError(domain.CodeNotFound, "lookups are not counted"))
+
+	rec := s.serve(httptest.NewRequest(echo.GET, "/characters/popular", nil))
+	s.Assert().Equal(http.StatusNotFound, rec.Code)
+
+	rec = s.serve(httptest.NewRequest(echo.GET, "/characters/popular?window=day", nil))
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
+
+	rec = s.serve(httptest.NewRequest(echo.GET, "/characters/popular?limit=all", nil))
+	s.Assert().Equal(http.StatusBadRequest, rec.Code)
 }
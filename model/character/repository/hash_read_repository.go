@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
+)
+
+// CharacterHashReadRepository reads the hash layout: characters are hashes
+// read field by field, and pages are ranges of the ByName index. A page is
+// only answered once its key marks it as fetched.
+type CharacterHashReadRepository struct {
+	Client redis.Cmdable
+	Keys   cachekey.Builder
+	Logger *slog.Logger
+}
+
+func NewCharacterHashReadRepository(Conn redis.Cmdable, keys cachekey.Builder, logger *slog.Logger) *CharacterHashReadRepository {
+	return &CharacterHashReadRepository{
+		Client: Conn,
+		Keys:   keys,
+		Logger: logger,
+	}
+}
+
+func (c *CharacterHashReadRepository) Fetch(ctx context.Context, page int) ([]int, error) {
+	pageNorm := page
+	if pageNorm < 1 {
+		pageNorm = 1
+	}
+	key := c.Keys.Page(pageNorm)
+	offset := pageSize * pageNorm
+
+	pipe := c.Client.Pipeline()
+	marker := pipe.Get(ctx, key)
+	members := pipe.ZRangeByScore(ctx, c.Keys.ByName(), &redis.ZRangeBy{
+		Min: strconv.Itoa(offset),
+		Max: strconv.Itoa(offset + pageSize - 1),
+	})
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		c.Logger.ErrorContext(ctx, "Fetch Exec failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheError).Inc()
+		return nil, domain.ErrInternalServerError
+	}
+
+	val, err := marker.Result()
+	if err == redis.Nil {
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheMiss).Inc()
+		return nil, domain.ErrCacheKeyEmpty
+	}
+	if len(val) == 0 {
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheTombstone).Inc()
+		return nil, domain.ErrNotFound
+	}
+
+	ids, err := parseMembers(members.Val())
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "Fetch parseMembers failed", "key", c.Keys.ByName(), "error", err)
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheError).Inc()
+		return nil, domain.ErrInternalServerError
+	}
+	// The index only goes before the marker when deleted by hand.
+	if len(ids) == 0 {
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheMiss).Inc()
+		return nil, domain.ErrCacheKeyEmpty
+	}
+	metrics.CacheLookups.WithLabelValues("page", metrics.CacheHit).Inc()
+
+	return ids, nil
+}
+
+func (c *CharacterHashReadRepository) GetByID(ctx context.Context, id int) (domain.Character, error) {
+	key := c.Keys.Character(id)
+
+	pipe := c.Client.Pipeline()
+	get := pipe.HMGet(ctx, key, codec.CharacterFields...)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.Logger.ErrorContext(ctx, "GetByID Exec failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Inc()
+		return domain.Character{}, domain.ErrInternalServerError
+	}
+
+	fields, ok := codec.HashValues(get.Val())
+	if !ok {
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheMiss).Inc()
+		return domain.Character{}, domain.ErrCacheKeyEmpty
+	}
+	character, tombstone, err := codec.DecodeHash(fields)
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "GetByID DecodeHash failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Inc()
+		return domain.Character{}, domain.ErrInternalServerError
+	}
+	if tombstone {
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheTombstone).Inc()
+		return domain.Character{}, domain.ErrNotFound
+	}
+	metrics.CacheLookups.WithLabelValues("character", metrics.CacheHit).Inc()
+
+	if ttl := ttl.Val(); ttl > 0 {
+		character.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	return character, nil
+}
+
+// GetByIDs looks the characters up with an HMGET each, in a single pipeline.
+// IDs cached as not found are reported not found, those not cached or that
+// fail to decode missing.
+func (c *CharacterHashReadRepository) GetByIDs(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	batch := domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{}, NotFound: []int{}}
+	if len(ids) == 0 {
+		return batch, nil
+	}
+
+	pipe := c.Client.Pipeline()
+	gets := make([]*redis.SliceCmd, len(ids))
+	for i, id := range ids {
+		gets[i] = pipe.HMGet(ctx, c.Keys.Character(id), codec.CharacterFields...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.Logger.ErrorContext(ctx, "GetByIDs Exec failed", "keys", len(ids), "error", err)
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Add(float64(len(ids)))
+		return domain.CharacterBatch{}, domain.ErrInternalServerError
+	}
+
+	for i, get := range gets {
+		fields, ok := codec.HashValues(get.Val())
+		if !ok {
+			metrics.CacheLookups.WithLabelValues("character", metrics.CacheMiss).Inc()
+			batch.Missing = append(batch.Missing, ids[i])
+			continue
+		}
+
+		character, tombstone, err := codec.DecodeHash(fields)
+		switch {
+		case err != nil:
+			c.Logger.ErrorContext(ctx, "GetByIDs DecodeHash failed", "key", c.Keys.Character(ids[i]), "error", err)
+			metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Inc()
+			batch.Missing = append(batch.Missing, ids[i])
+		case tombstone:
+			metrics.CacheLookups.WithLabelValues("character", metrics.CacheTombstone).Inc()
+			batch.NotFound = append(batch.NotFound, ids[i])
+		default:
+			metrics.CacheLookups.WithLabelValues("character", metrics.CacheHit).Inc()
+			batch.Characters = append(batch.Characters, character)
+		}
+	}
+
+	return batch, nil
+}
+
+// Range returns limit IDs of the cached characters from offset on, by name
+// in the order of Marvel API or by modification date, the latest first. Only
+// the characters of fetched pages are ordered by name, while every cached
+// character Marvel API knows the modification date of is ordered by it.
+// Characters expire out of the ByModified index as they are found gone, so
+// a range by modification date may come back short. Those of the ByName
+// index keep the position of their page, to be reported missing.
+func (c *CharacterHashReadRepository) Range(ctx context.Context, order string, offset, limit int) ([]int, error) {
+	if offset < 0 || limit < 1 {
+		return nil, domain.NewError(domain.CodeBadRequest, "offset must not be negative and limit must be positive")
+	}
+
+	start, stop := int64(offset), int64(offset+limit-1)
+	var members *redis.StringSliceCmd
+	switch order {
+	case domain.OrderByName:
+		members = c.Client.ZRange(ctx, c.Keys.ByName(), start, stop)
+	case domain.OrderByModified:
+		members = c.Client.ZRevRange(ctx, c.Keys.ByModified(), start, stop)
+	default:
+		return nil, domain.NewError(domain.CodeBadRequest, "order must be "+domain.OrderByName+" or "+domain.OrderByModified)
+	}
+	if err := members.Err(); err != nil {
+		c.Logger.ErrorContext(ctx, "Range failed", "order", order, "error", err)
+		return nil, domain.ErrInternalServerError
+	}
+
+	ids, err := parseMembers(members.Val())
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "Range parseMembers failed", "order", order, "error", err)
+		return nil, domain.ErrInternalServerError
+	}
+	if order == domain.OrderByModified {
+		return c.prune(ctx, ids)
+	}
+
+	return ids, nil
+}
+
+// prune drops the IDs whose hashes expired from the ByModified index, and
+// returns the others.
+func (c *CharacterHashReadRepository) prune(ctx context.Context, ids []int) ([]int, error) {
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	pipe := c.Client.Pipeline()
+	exists := make([]*redis.IntCmd, len(ids))
+	for i, id := range ids {
+		exists[i] = pipe.Exists(ctx, c.Keys.Character(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.Logger.ErrorContext(ctx, "prune Exec failed", "ids", len(ids), "error", err)
+		return nil, domain.ErrInternalServerError
+	}
+
+	cached := make([]int, 0, len(ids))
+	var gone []interface{}
+	for i, id := range ids {
+		if exists[i].Val() > 0 {
+			cached = append(cached, id)
+		} else {
+			gone = append(gone, strconv.Itoa(id))
+		}
+	}
+	if len(gone) > 0 {
+		if err := c.Client.ZRem(ctx, c.Keys.ByModified(), gone...).Err(); err != nil {
+			c.Logger.ErrorContext(ctx, "prune ZRem failed", "ids", len(gone), "error", err)
+			return nil, domain.ErrInternalServerError
+		}
+	}
+
+	return cached, nil
+}
+
+func parseMembers(members []string) ([]int, error) {
+	ids := make([]int, len(members))
+	for i, member := range members {
+		id, err := strconv.Atoi(member)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,196 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/suite"
+	gock "gopkg.in/h2non/gock.v1"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/character/repository"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+const hashPage = `{"data": {"results": [
+	{"id": 1011334, "name": "3-D Man", "description": "", "modified": "2014-04-29T14:18:17-0400"},
+	{"id": 1017100, "name": "A-Bomb (HAS)", "description": "Rick Jones", "modified": "2013-09-18T15:54:04-0400"},
+	{"id": 1009144, "name": "A.I.M.", "description": "", "modified": "-0001-11-30T00:00:00-0500"}
+]}}`
+
+type CharacterHashRepositoryTestSuite struct {
+	suite.Suite
+	mr    *miniredis.Miniredis
+	read  *repository.CharacterHashReadRepository
+	write *repository.CharacterWriteRepository
+}
+
+func TestCharacterHashRepository(t *testing.T) {
+	suite.Run(t, new(CharacterHashRepositoryTestSuite))
+}
+
+func (s *CharacterHashRepositoryTestSuite) SetupTest() {
+	mr, err := miniredis.Run()
+	s.Require().NoError(err)
+	s.mr = mr
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	keys := cachekey.New(cachekey.DefaultPrefix).WithLayout(cachekey.LayoutHash)
+	s.read = repository.NewCharacterHashReadRepository(client, keys, logger.Discard())
+	s.write = repository.NewCharacterWriteRepository("http://foo.com", "asd", "asd", client, keys, codec.NewEncoder(codec.JSON, codec.None, 0), 2*time.Second, time.Hour, time.Minute, logger.Discard())
+}
+
+func (s *CharacterHashRepositoryTestSuite) TearDownTest() {
+	gock.Off()
+	s.mr.Close()
+}
+
+func (s *CharacterHashRepositoryTestSuite) TestSuccessStoreByPage() {
+	gock.New("http://foo.com").Get("/v1/public/characters").MatchParam("offset", "10").Reply(200).BodyString(hashPage)
+
+	_, err := s.read.Fetch(context.Background(), 1)
+	s.Assert().Equal(domain.ErrCacheKeyEmpty, err)
+
+	s.Require().NoError(s.write.StoreByPage(context.Background(), 1))
+	s.Assert().Equal(`{"offset":10,"count":3}`, s.mustGet("marvel-v2-hash-characters-page-1"))
+	s.Assert().Equal("A-Bomb (HAS)", s.mr.HGet("marvel-v2-hash-character-id-1017100", "name"))
+	s.Assert().Equal("2013-09-18T19:54:04Z", s.mr.HGet("marvel-v2-hash-character-id-1017100", "modified"))
+	s.Assert().Empty(s.mr.HGet("marvel-v2-hash-character-id-1009144", "modified"))
+
+	ids, err := s.read.Fetch(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1011334, 1017100, 1009144}, ids)
+
+	character, err := s.read.GetByID(context.Background(), 1017100)
+	s.Require().NoError(err)
+	s.Assert().Equal("Rick Jones", character.Description)
+	s.Assert().False(character.FetchedAt.IsZero())
+	s.Assert().False(character.ExpiresAt.IsZero())
+
+	batch, err := s.read.GetByIDs(context.Background(), []int{1011334, 1, 1009144})
+	s.Require().NoError(err)
+	s.Require().Len(batch.Characters, 2)
+	s.Assert().Equal("A.I.M.", batch.Characters[1].Name)
+	s.Assert().Equal([]int{1}, batch.Missing)
+}
+
+func (s *CharacterHashRepositoryTestSuite) TestSuccessRefreshByPageReplacesPositions() {
+	s.mr.ZAdd("marvel-v2-hash-characters-by-name", 11, "1")
+	s.mr.ZAdd("marvel-v2-hash-characters-by-name", 20, "2")
+	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString(hashPage)
+
+	s.Require().NoError(s.write.RefreshByPage(context.Background(), 1))
+
+	ids, err := s.read.Range(context.Background(), domain.OrderByName, 0, 10)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1011334, 1017100, 1009144, 2}, ids)
+}
+
+func (s *CharacterHashRepositoryTestSuite) TestSuccessRange() {
+	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString(hashPage)
+	s.Require().NoError(s.write.StoreByPage(context.Background(), 1))
+
+	ids, err := s.read.Range(context.Background(), domain.OrderByName, 1, 1)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1017100}, ids)
+
+	// A.I.M. has no modification date.
+	ids, err = s.read.Range(context.Background(), domain.OrderByModified, 0, 10)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1011334, 1017100}, ids)
+
+	_, err = s.read.Range(context.Background(), "popularity", 0, 10)
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+	_, err = s.read.Range(context.Background(), domain.OrderByName, 0, 0)
+	s.Assert().Equal(domain.CodeBadRequest, domain.CodeOf(err))
+}
+
+func (s *CharacterHashRepositoryTestSuite) TestSuccessRangePrunesExpired() {
+	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString(hashPage)
+	s.Require().NoError(s.write.StoreByPage(context.Background(), 1))
+	s.mr.Del("marvel-v2-hash-character-id-1011334")
+
+	ids, err := s.read.Range(context.Background(), domain.OrderByModified, 0, 10)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1017100}, ids)
+	members, _ := s.mr.ZMembers("marvel-v2-hash-characters-by-modified")
+	s.Assert().Equal([]string{"1017100"}, members)
+
+	// Expired characters keep their position by name, to be fetched again.
+	ids, err = s.read.Range(context.Background(), domain.OrderByName, 0, 10)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1011334, 1017100, 1009144}, ids)
+}
+
+func (s *CharacterHashRepositoryTestSuite) TestSuccessImport() {
+	modified := time.Date(2014, 4, 29, 18, 18, 17, 0, time.UTC)
+	s.Require().NoError(s.write.ImportCharacter(context.Background(), 1, &domain.Character{ID: 1, Name: "Hulk"}, modified, 0))
+	s.Require().NoError(s.write.ImportCharacter(context.Background(), 2, nil, time.Time{}, time.Hour))
+	s.Require().NoError(s.write.ImportPage(context.Background(), 1, []int{1, 2}, time.Hour))
+
+	character, err := s.read.GetByID(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("Hulk", character.Name)
+	s.Assert().Zero(s.mr.TTL("marvel-v2-hash-character-id-1"))
+	_, err = s.read.GetByID(context.Background(), 2)
+	s.Assert().Equal(domain.ErrNotFound, err)
+	s.Assert().Equal(time.Hour, s.mr.TTL("marvel-v2-hash-character-id-2"))
+
+	ids, err := s.read.Fetch(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1, 2}, ids)
+	ids, err = s.read.Range(context.Background(), domain.OrderByModified, 0, 10)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1}, ids)
+}
+
+func (s *CharacterHashRepositoryTestSuite) TestTombstoneStoreByID() {
+	s.mr.ZAdd("marvel-v2-hash-characters-by-modified", 1, "4")
+	gock.New("http://foo.com").Get("/v1/public/characters/4").Reply(404)
+
+	err := s.write.StoreByID(context.Background(), 4)
+	s.Assert().Equal(domain.ErrNotFound, err)
+	s.Assert().Equal("1", s.mr.HGet("marvel-v2-hash-character-id-4", "tombstone"))
+	s.Assert().Equal(time.Minute, s.mr.TTL("marvel-v2-hash-character-id-4"))
+	s.Assert().False(s.mr.Exists("marvel-v2-hash-characters-by-modified"))
+
+	_, err = s.read.GetByID(context.Background(), 4)
+	s.Assert().Equal(domain.ErrNotFound, err)
+
+	batch, err := s.read.GetByIDs(context.Background(), []int{4})
+	s.Require().NoError(err)
+	s.Assert().Empty(batch.Missing)
+	s.Assert().Equal([]int{4}, batch.NotFound)
+}
+
+func (s *CharacterHashRepositoryTestSuite) TestTombstoneFetch() {
+	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString(`{"data": {"results": []}}`)
+
+	err := s.write.StoreByPage(context.Background(), 9)
+	s.Assert().Equal(domain.ErrNotFound, err)
+
+	_, err = s.read.Fetch(context.Background(), 9)
+	s.Assert().Equal(domain.ErrNotFound, err)
+}
+
+func (s *CharacterHashRepositoryTestSuite) TestFailedGetByID() {
+	s.mr.HSet("marvel-v2-hash-character-id-5", "name", "Hulk")
+
+	_, err := s.read.GetByID(context.Background(), 5)
+	s.Assert().Equal(domain.ErrInternalServerError, err)
+
+	s.mr.Close()
+	_, err = s.read.GetByID(context.Background(), 5)
+	s.Assert().Equal(domain.ErrInternalServerError, err)
+}
+
+func (s *CharacterHashRepositoryTestSuite) mustGet(key string) string {
+	val, err := s.mr.Get(key)
+	s.Require().NoError(err)
+	return val
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+)
+
+// pageMarker is the value of the page keys of the hash layout. The IDs of
+// the page are in the ByName index, from offset on.
+type pageMarker struct {
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+// indexPage scores the IDs of page by their position in the listing, after
+// dropping the IDs the positions held before, and marks the page as fetched
+// for expiration. The index outlives the last page written to it.
+func (r *CharacterWriteRepository) indexPage(ctx context.Context, IDs []int, page, offset int, expiration time.Duration, force bool) error {
+	key := r.keys.Page(page)
+
+	if !force {
+		isExists, err := r.checkRedisKeyExists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if isExists {
+			return domain.ErrCacheKeyExists
+		}
+	}
+
+	marker, err := json.Marshal(pageMarker{Offset: offset, Count: len(IDs)})
+	if err != nil {
+		return err
+	}
+	members := make([]*redis.Z, len(IDs))
+	for i, id := range IDs {
+		members[i] = &redis.Z{Score: float64(offset + i), Member: strconv.Itoa(id)}
+	}
+
+	pipe := r.redisClient.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, r.keys.ByName(), strconv.Itoa(offset), strconv.Itoa(offset+pageSize-1))
+	pipe.ZAdd(ctx, r.keys.ByName(), members...)
+	if indexExpiration := r.getIndexExpiration(); indexExpiration > 0 {
+		pipe.Expire(ctx, r.keys.ByName(), indexExpiration)
+	}
+	pipe.Set(ctx, key, string(marker), expiration)
+	_, err = pipe.Exec(ctx)
+
+	return err
+}
+
+// storeCharacterHash replaces the hash of char for expiration, 0 keeping it
+// without expiry, and scores it by modified, when Marvel API knows when it
+// was modified.
+func (r *CharacterWriteRepository) storeCharacterHash(ctx context.Context, char domain.Character, modified time.Time, expiration time.Duration) error {
+	key := r.keys.Character(int(char.ID))
+
+	pipe := r.redisClient.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HMSet(ctx, key, codec.EncodeHash(char, modified))
+	if expiration > 0 {
+		pipe.Expire(ctx, key, expiration)
+	}
+	if !modified.IsZero() {
+		pipe.ZAdd(ctx, r.keys.ByModified(), &redis.Z{Score: float64(modified.Unix()), Member: strconv.Itoa(int(char.ID))})
+		if expiration := r.getIndexExpiration(); expiration > 0 {
+			pipe.Expire(ctx, r.keys.ByModified(), expiration)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// storeCharacterTombstone replaces the hash at key with a tombstone and
+// drops the character from the indexes.
+func (r *CharacterWriteRepository) storeCharacterTombstone(ctx context.Context, key string, expiration time.Duration) error {
+	_, id, _ := r.keys.Parse(key)
+
+	pipe := r.redisClient.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, codec.FieldTombstone, "1")
+	if expiration > 0 {
+		pipe.Expire(ctx, key, expiration)
+	}
+	pipe.ZRem(ctx, r.keys.ByName(), strconv.Itoa(id))
+	pipe.ZRem(ctx, r.keys.ByModified(), strconv.Itoa(id))
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+)
+
+type popularityKey struct {
+	kind string
+	id   int
+}
+
+// PopularityRepository counts the lookups of characters and pages in Redis,
+// in a sorted set per kind and bucket of time, so that the lookups of every
+// server add up and can be summed over any window up to the retention.
+// Lookups are counted in memory and flushed every so often, so that reads do
+// not wait on another write. Those not flushed yet when the server stops are
+// lost.
+type PopularityRepository struct {
+	client    redis.Cmdable
+	keys      cachekey.Builder
+	bucket    time.Duration
+	retention time.Duration
+	logger    *slog.Logger
+
+	mu      sync.Mutex
+	pending map[popularityKey]int64
+}
+
+func NewPopularityRepository(Conn redis.Cmdable, keys cachekey.Builder, bucket, retention time.Duration, logger *slog.Logger) *PopularityRepository {
+	return &PopularityRepository{
+		client:    Conn,
+		keys:      keys,
+		bucket:    bucket,
+		retention: retention,
+		logger:    logger,
+		pending:   map[popularityKey]int64{},
+	}
+}
+
+// Touch counts a lookup of the character or page id, by kind.
+func (r *PopularityRepository) Touch(kind string, id int) {
+	r.mu.Lock()
+	r.pending[popularityKey{kind: kind, id: id}]++
+	r.mu.Unlock()
+}
+
+// Flush adds the lookups counted since the last flush to the current bucket.
+// Buckets expire once they fall out of the retention.
+func (r *PopularityRepository) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = map[popularityKey]int64{}
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	start := time.Now().Truncate(r.bucket)
+	buckets := map[string]bool{}
+	pipe := r.client.Pipeline()
+	for key, hits := range pending {
+		bucket := r.keys.Popularity(key.kind, start)
+		pipe.ZIncrBy(ctx, bucket, float64(hits), strconv.Itoa(key.id))
+		buckets[bucket] = true
+	}
+	for bucket := range buckets {
+		pipe.Expire(ctx, bucket, r.retention+r.bucket)
+	}
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// Run flushes the lookups every interval until ctx is done. Lookups that
+// failed to be flushed are dropped, popularity being approximate anyway.
+func (r *PopularityRepository) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				r.logger.WarnContext(ctx, "Flushing lookups failed", "error", err)
+			}
+		}
+	}
+}
+
+func (r *PopularityRepository) Retention() time.Duration {
+	return r.retention
+}
+
+// Top sums the buckets of window, the current one included, into a sorted
+// set of its own. It is summed, read and dropped in a transaction, so that
+// concurrent lookups never see one another's sums.
+func (r *PopularityRepository) Top(ctx context.Context, kind string, window time.Duration, limit int, minHits int64) ([]domain.Hit, error) {
+	hits := []domain.Hit{}
+	if limit <= 0 {
+		return hits, nil
+	}
+
+	count := int((window + r.bucket - 1) / r.bucket)
+	if count < 1 {
+		count = 1
+	}
+	start := time.Now().Truncate(r.bucket)
+	buckets := make([]string, count)
+	for i := range buckets {
+		buckets[i] = r.keys.Popularity(kind, start.Add(-time.Duration(i)*r.bucket))
+	}
+
+	sum := r.keys.PopularityPrefix() + kind + "-window"
+	pipe := r.client.TxPipeline()
+	pipe.ZUnionStore(ctx, sum, &redis.ZStore{Keys: buckets})
+	top := pipe.ZRevRangeByScoreWithScores(ctx, sum, &redis.ZRangeBy{
+		Min:   strconv.FormatInt(minHits, 10),
+		Max:   "+inf",
+		Count: int64(limit),
+	})
+	pipe.Del(ctx, sum)
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.logger.ErrorContext(ctx, "Top failed", "kind", kind, "window", window, "error", err)
+		return nil, domain.ErrInternalServerError
+	}
+
+	for _, z := range top.Val() {
+		member, _ := z.Member.(string)
+		id, err := strconv.Atoi(member)
+		if err != nil {
+			continue
+		}
+		hits = append(hits, domain.Hit{ID: id, Hits: int64(z.Score)})
+	}
+
+	return hits, nil
+}
+
+type trackedReadRepository struct {
+	domain.CharacterReadRepository
+	popularity *PopularityRepository
+}
+
+// TrackLookups counts the characters and pages repo finds in popularity.
+// Lookups of what is not cached are left out, the cache not being able to
+// keep it any longer.
+func TrackLookups(repo domain.CharacterReadRepository, popularity *PopularityRepository) domain.CharacterReadRepository {
+	return &trackedReadRepository{CharacterReadRepository: repo, popularity: popularity}
+}
+
+func (r *trackedReadRepository) Fetch(ctx context.Context, page int) ([]int, error) {
+	ids, err := r.CharacterReadRepository.Fetch(ctx, page)
+	if err == nil {
+		// Pages before the first are the first one.
+		if page < 1 {
+			page = 1
+		}
+		r.popularity.Touch(cachekey.KindPage, page)
+	}
+
+	return ids, err
+}
+
+func (r *trackedReadRepository) GetByID(ctx context.Context, id int) (domain.Character, error) {
+	character, err := r.CharacterReadRepository.GetByID(ctx, id)
+	if err == nil {
+		r.popularity.Touch(cachekey.KindCharacter, id)
+	}
+
+	return character, err
+}
+
+func (r *trackedReadRepository) GetByIDs(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	batch, err := r.CharacterReadRepository.GetByIDs(ctx, ids)
+	for _, character := range batch.Characters {
+		r.popularity.Touch(cachekey.KindCharacter, int(character.ID))
+	}
+
+	return batch, err
+}
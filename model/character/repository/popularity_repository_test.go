@@ -0,0 +1,113 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	"github.com/hezbymuhammad/golang-marvel-demo/model/character/repository"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+type PopularityRepositoryTestSuite struct {
+	suite.Suite
+	mr   *miniredis.Miniredis
+	keys cachekey.Builder
+	repo *repository.PopularityRepository
+}
+
+func TestPopularityRepository(t *testing.T) {
+	suite.Run(t, new(PopularityRepositoryTestSuite))
+}
+
+func (s *PopularityRepositoryTestSuite) SetupTest() {
+	mr, err := miniredis.Run()
+	s.Require().NoError(err)
+	s.mr = mr
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s.keys = cachekey.New(cachekey.DefaultPrefix)
+	s.repo = repository.NewPopularityRepository(client, s.keys, time.Hour, 24*time.Hour, logger.Discard())
+}
+
+func (s *PopularityRepositoryTestSuite) TearDownTest() {
+	s.mr.Close()
+}
+
+func (s *PopularityRepositoryTestSuite) touch(kind string, id, times int) {
+	for i := 0; i < times; i++ {
+		s.repo.Touch(kind, id)
+	}
+}
+
+func (s *PopularityRepositoryTestSuite) TestSuccessTop() {
+	s.touch(cachekey.KindCharacter, 1, 3)
+	s.touch(cachekey.KindCharacter, 2, 1)
+	s.touch(cachekey.KindPage, 1, 2)
+	s.Require().NoError(s.repo.Flush(context.Background()))
+	s.touch(cachekey.KindCharacter, 2, 4)
+	s.Require().NoError(s.repo.Flush(context.Background()))
+
+	// A bucket of the window before.
+	s.mr.ZAdd(s.keys.Popularity(cachekey.KindCharacter, time.Now().Truncate(time.Hour).Add(-3*time.Hour)), 10, "3")
+
+	key := s.keys.Popularity(cachekey.KindCharacter, time.Now().Truncate(time.Hour))
+	if !s.mr.Exists(key) {
+		// The hour turned since the flush.
+		key = s.keys.Popularity(cachekey.KindCharacter, time.Now().Truncate(time.Hour).Add(-time.Hour))
+	}
+	s.Assert().Equal(25*time.Hour, s.mr.TTL(key))
+
+	hits, err := s.repo.Top(context.Background(), cachekey.KindCharacter, 2*time.Hour, 10, 2)
+	s.Require().NoError(err)
+	s.Assert().Equal([]domain.Hit{{ID: 2, Hits: 5}, {ID: 1, Hits: 3}}, hits)
+
+	hits, err = s.repo.Top(context.Background(), cachekey.KindCharacter, 24*time.Hour, 2, 0)
+	s.Require().NoError(err)
+	s.Assert().Equal([]domain.Hit{{ID: 3, Hits: 10}, {ID: 2, Hits: 5}}, hits)
+
+	hits, err = s.repo.Top(context.Background(), cachekey.KindPage, 2*time.Hour, 10, 0)
+	s.Require().NoError(err)
+	s.Assert().Equal([]domain.Hit{{ID: 1, Hits: 2}}, hits)
+
+	// The sums are dropped.
+	s.Assert().False(s.mr.Exists("marvel-v2-popular-character-window"))
+	s.Assert().Equal(24*time.Hour, s.repo.Retention())
+}
+
+func (s *PopularityRepositoryTestSuite) TestFailedTop() {
+	s.mr.Close()
+
+	_, err := s.repo.Top(context.Background(), cachekey.KindCharacter, time.Hour, 10, 0)
+	s.Assert().Equal(domain.ErrInternalServerError, err)
+}
+
+func (s *PopularityRepositoryTestSuite) TestSuccessTrackLookups() {
+	repo := new(mocks.CharacterReadRepository)
+	repo.On("Fetch", mock.Anything, 0).Return([]int{1}, nil)
+	repo.On("GetByID", mock.Anything, 1).Return(domain.Character{ID: 1}, nil)
+	repo.On("GetByID", mock.Anything, 2).Return(domain.Character{}, domain.ErrCacheKeyEmpty)
+	repo.On("GetByIDs", mock.Anything, []int{1, 2}).Return(domain.CharacterBatch{Characters: []domain.Character{{ID: 1}}, Missing: []int{2}}, nil)
+	tracked := repository.TrackLookups(repo, s.repo)
+
+	_, _ = tracked.Fetch(context.Background(), 0)
+	_, _ = tracked.GetByID(context.Background(), 1)
+	_, _ = tracked.GetByID(context.Background(), 2)
+	_, _ = tracked.GetByIDs(context.Background(), []int{1, 2})
+	s.Require().NoError(s.repo.Flush(context.Background()))
+
+	hits, err := s.repo.Top(context.Background(), cachekey.KindCharacter, 2*time.Hour, 10, 0)
+	s.Require().NoError(err)
+	s.Assert().Equal([]domain.Hit{{ID: 1, Hits: 2}}, hits)
+	hits, err = s.repo.Top(context.Background(), cachekey.KindPage, 2*time.Hour, 10, 0)
+	s.Require().NoError(err)
+	s.Assert().Equal([]domain.Hit{{ID: 1, Hits: 1}}, hits)
+}
@@ -2,22 +2,28 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	redis "github.com/go-redis/redis/v8"
 
 	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
 )
 
 type CharacterReadRepository struct {
 	Client redis.Cmdable
+	Keys   cachekey.Builder
+	Logger *slog.Logger
 }
 
-func NewCharacterReadRepository(Conn redis.Cmdable) domain.CharacterReadRepository {
+func NewCharacterReadRepository(Conn redis.Cmdable, keys cachekey.Builder, logger *slog.Logger) domain.CharacterReadRepository {
 	return &CharacterReadRepository{
 		Client: Conn,
+		Keys:   keys,
+		Logger: logger,
 	}
 }
 
@@ -29,62 +35,80 @@ func (c *CharacterReadRepository) Fetch(ctx context.Context, page int) ([]int, e
 	} else {
 		pageNorm = page
 	}
-	key := "marvel-characters-page-" + fmt.Sprint(pageNorm)
+	key := c.Keys.Page(pageNorm)
 
 	isEmpty, err := c.checkRedisKeyEmpty(ctx, key)
 	if err != nil {
-		log.Println("[ERROR][CharacterReadRepository] Fetch checkRedisKeyEmpty: " + err.Error())
+		c.Logger.ErrorContext(ctx, "Fetch checkRedisKeyEmpty failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheError).Inc()
 		return nil, domain.ErrInternalServerError
 	}
 	if isEmpty {
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheMiss).Inc()
 		return nil, domain.ErrCacheKeyEmpty
 	}
 
 	val, err := c.Client.Get(ctx, key).Result()
 	if err != nil {
-		log.Println("[ERROR][CharacterReadRepository] Fetch Get: " + err.Error())
+		c.Logger.ErrorContext(ctx, "Fetch Get failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheError).Inc()
 		return nil, domain.ErrInternalServerError
 	}
 	if len(val) == 0 {
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheTombstone).Inc()
 		return nil, domain.ErrNotFound
 	}
 
-	err = json.Unmarshal([]byte(val), &data)
+	err = codec.Decode([]byte(val), &data)
 	if err != nil {
-		log.Println("[ERROR][CharacterReadRepository] Fetch Unmarshal: " + err.Error())
+		c.Logger.ErrorContext(ctx, "Fetch Decode failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("page", metrics.CacheError).Inc()
 		return nil, domain.ErrInternalServerError
 	}
+	metrics.CacheLookups.WithLabelValues("page", metrics.CacheHit).Inc()
 
 	return data, nil
 }
 
 func (c *CharacterReadRepository) GetByID(ctx context.Context, id int) (domain.Character, error) {
 	var character domain.Character
-	key := "marvel-character-id-" + fmt.Sprint(id)
+	key := c.Keys.Character(id)
 
 	isEmpty, err := c.checkRedisKeyEmpty(ctx, key)
 	if err != nil {
-		log.Println("[ERROR][CharacterReadRepository] GetByID checkRedisKeyEmpty: " + err.Error())
+		c.Logger.ErrorContext(ctx, "GetByID checkRedisKeyEmpty failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Inc()
 		return domain.Character{}, domain.ErrInternalServerError
 	}
 	if isEmpty {
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheMiss).Inc()
 		return domain.Character{}, domain.ErrCacheKeyEmpty
 	}
 
 	val, err := c.Client.Get(ctx, key).Result()
 	if err != nil {
-		log.Println("[ERROR][CharacterReadRepository] GetByID Get: " + err.Error())
+		c.Logger.ErrorContext(ctx, "GetByID Get failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Inc()
 		return domain.Character{}, domain.ErrInternalServerError
 	}
 	if len(val) == 0 {
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheTombstone).Inc()
 		return domain.Character{}, domain.ErrNotFound
 	}
 
-	err = json.Unmarshal([]byte(val), &character)
+	err = codec.Decode([]byte(val), &character)
 	if err != nil {
-		log.Println("[ERROR][CharacterReadRepository] GetByID Unmarshal: " + err.Error())
+		c.Logger.ErrorContext(ctx, "GetByID Decode failed", "key", key, "error", err)
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Inc()
 		return domain.Character{}, domain.ErrInternalServerError
 	}
+	metrics.CacheLookups.WithLabelValues("character", metrics.CacheHit).Inc()
+
+	// The expiry only feeds the HTTP caching headers, which do without it.
+	ttl, err := c.Client.PTTL(ctx, key).Result()
+	if err == nil && ttl > 0 {
+		character.ExpiresAt = time.Now().Add(ttl)
+	}
 
 	return character, nil
 }
@@ -97,3 +121,56 @@ func (c *CharacterReadRepository) checkRedisKeyEmpty(ctx context.Context, str st
 
 	return val == 0, nil
 }
+
+// GetByIDs looks the characters up with a single MGET. IDs cached as not
+// found are reported not found, those not cached or that fail to decode
+// missing.
+func (c *CharacterReadRepository) GetByIDs(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	batch := domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{}, NotFound: []int{}}
+	if len(ids) == 0 {
+		return batch, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = c.Keys.Character(id)
+	}
+
+	vals, err := c.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		c.Logger.ErrorContext(ctx, "GetByIDs MGet failed", "keys", len(keys), "error", err)
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Add(float64(len(keys)))
+		return domain.CharacterBatch{}, domain.ErrInternalServerError
+	}
+
+	for i, val := range vals {
+		str, ok := val.(string)
+		if !ok {
+			metrics.CacheLookups.WithLabelValues("character", metrics.CacheMiss).Inc()
+			batch.Missing = append(batch.Missing, ids[i])
+			continue
+		}
+		if len(str) == 0 {
+			metrics.CacheLookups.WithLabelValues("character", metrics.CacheTombstone).Inc()
+			batch.NotFound = append(batch.NotFound, ids[i])
+			continue
+		}
+
+		var character domain.Character
+		if err := codec.Decode([]byte(str), &character); err != nil {
+			c.Logger.ErrorContext(ctx, "GetByIDs Decode failed", "key", keys[i], "error", err)
+			metrics.CacheLookups.WithLabelValues("character", metrics.CacheError).Inc()
+			batch.Missing = append(batch.Missing, ids[i])
+			continue
+		}
+		metrics.CacheLookups.WithLabelValues("character", metrics.CacheHit).Inc()
+		batch.Characters = append(batch.Characters, character)
+	}
+
+	return batch, nil
+}
+
+// Range fails with not found: only the hash layout orders the characters.
+func (c *CharacterReadRepository) Range(ctx context.Context, order string, offset, limit int) ([]int, error) {
+	return nil, domain.NewError(domain.CodeNotFound, "characters are only ordered by the hash layout")
+}
@@ -10,11 +10,15 @@ import (
 	"github.com/alicebob/miniredis"
 	redismock "github.com/elliotchance/redismock/v8"
 	redis "github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/hezbymuhammad/golang-marvel-demo/domain"
 	"github.com/hezbymuhammad/golang-marvel-demo/model/character/repository"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
 )
 
 var record domain.Character
@@ -39,12 +43,12 @@ func (s *CharacterReadRepositoryTestSuite) SetupTest() {
 		Addr: mr.Addr(),
 	})
 	s.mock = redismock.NewNiceMock(client)
-	s.repo = repository.NewCharacterReadRepository(s.mock)
+	s.repo = repository.NewCharacterReadRepository(s.mock, cachekey.New(cachekey.DefaultPrefix), logger.Discard())
 }
 
 func (s *CharacterReadRepositoryTestSuite) TestNilFetch() {
 	s.mock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
-	s.mock.On("Get", mock.Anything, "marvel-characters-page-1").Return(redis.NewStringResult("", nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-characters-page-1").Return(redis.NewStringResult("", nil))
 
 	_, err := s.repo.Fetch(context.Background(), 1)
 	s.Assert().Equal(err, domain.ErrNotFound)
@@ -52,21 +56,21 @@ func (s *CharacterReadRepositoryTestSuite) TestNilFetch() {
 
 func (s *CharacterReadRepositoryTestSuite) TestFailedJSONFetch() {
 	s.mock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
-	s.mock.On("Get", mock.Anything, "marvel-characters-page-2").Return(redis.NewStringResult("val", nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-characters-page-2").Return(redis.NewStringResult("val", nil))
 
 	_, err := s.repo.Fetch(context.Background(), 2)
 	s.Assert().Equal(err, domain.ErrInternalServerError)
 }
 
 func (s *CharacterReadRepositoryTestSuite) TestFailedEmptyKeyFetch() {
-	s.mock.On("Exists", mock.Anything, []string{"marvel-characters-page-2"}).Return(redis.NewIntResult(0, nil))
+	s.mock.On("Exists", mock.Anything, []string{"marvel-v2-characters-page-2"}).Return(redis.NewIntResult(0, nil))
 
 	_, err := s.repo.Fetch(context.Background(), 2)
 	s.Assert().Equal(err, domain.ErrCacheKeyEmpty)
 }
 
 func (s *CharacterReadRepositoryTestSuite) TestFailedErrorEmptyKeyFetch() {
-	s.mock.On("Exists", mock.Anything, []string{"marvel-characters-page-2"}).Return(redis.NewIntResult(0, errors.New("fail")))
+	s.mock.On("Exists", mock.Anything, []string{"marvel-v2-characters-page-2"}).Return(redis.NewIntResult(0, errors.New("fail")))
 
 	_, err := s.repo.Fetch(context.Background(), 2)
 	s.Assert().Equal(err, domain.ErrInternalServerError)
@@ -79,7 +83,7 @@ func (s *CharacterReadRepositoryTestSuite) TestSuccessPageNilFetch() {
 		s.T().Fatalf("Error: '%s'", err)
 	}
 
-	s.mock.On("Get", mock.Anything, "marvel-characters-page-1").Return(redis.NewStringResult(string(json_data), nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-characters-page-1").Return(redis.NewStringResult(string(json_data), nil))
 	s.mock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
 	res, err := s.repo.Fetch(context.Background(), 0)
 	s.Assert().Equal(err, nil)
@@ -93,7 +97,7 @@ func (s *CharacterReadRepositoryTestSuite) TestSuccessFetch() {
 		s.T().Fatalf("Error: '%s'", err)
 	}
 
-	s.mock.On("Get", mock.Anything, "marvel-characters-page-3").Return(redis.NewStringResult(string(json_data), nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-characters-page-3").Return(redis.NewStringResult(string(json_data), nil))
 	s.mock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
 	res, err := s.repo.Fetch(context.Background(), 3)
 	s.Assert().Equal(err, nil)
@@ -101,7 +105,7 @@ func (s *CharacterReadRepositoryTestSuite) TestSuccessFetch() {
 }
 
 func (s *CharacterReadRepositoryTestSuite) TestNilGetByID() {
-	s.mock.On("Get", mock.Anything, "marvel-character-id-1").Return(redis.NewStringResult("", nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-character-id-1").Return(redis.NewStringResult("", nil))
 	s.mock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
 
 	_, err := s.repo.GetByID(context.Background(), 1)
@@ -110,7 +114,7 @@ func (s *CharacterReadRepositoryTestSuite) TestNilGetByID() {
 
 func (s *CharacterReadRepositoryTestSuite) TestFailedJSONGetByID() {
 	s.mock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
-	s.mock.On("Get", mock.Anything, "marvel-character-id-2").Return(redis.NewStringResult("val", nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-character-id-2").Return(redis.NewStringResult("val", nil))
 
 	_, err := s.repo.GetByID(context.Background(), 2)
 	s.Assert().Equal(err, domain.ErrInternalServerError)
@@ -143,7 +147,7 @@ func (s *CharacterReadRepositoryTestSuite) TestSuccessGetByID() {
 	}
 
 	s.mock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
-	s.mock.On("Get", mock.Anything, "marvel-character-id-3").Return(redis.NewStringResult(string(json_data), nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-character-id-3").Return(redis.NewStringResult(string(json_data), nil))
 
 	res, err := s.repo.GetByID(context.Background(), 3)
 	s.Assert().Equal(err, nil)
@@ -151,3 +155,65 @@ func (s *CharacterReadRepositoryTestSuite) TestSuccessGetByID() {
 	s.Assert().Equal(res.Name, record.Name)
 	s.Assert().Equal(res.FetchedAt.Format(time.RFC3339Nano), record.FetchedAt.Format(time.RFC3339Nano))
 }
+
+func (s *CharacterReadRepositoryTestSuite) TestSuccessGetByIDExpiresAt() {
+	json_data, err := json.Marshal(domain.Character{ID: 4, Name: "lorem"})
+	if err != nil {
+		s.T().Fatalf("Error: '%s'", err)
+	}
+
+	s.mock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-character-id-4").Return(redis.NewStringResult(string(json_data), nil))
+	s.mock.On("PTTL", mock.Anything, "marvel-v2-character-id-4").Return(redis.NewDurationResult(time.Minute, nil))
+
+	res, err := s.repo.GetByID(context.Background(), 4)
+	s.Assert().Equal(err, nil)
+	s.Assert().WithinDuration(time.Now().Add(time.Minute), res.ExpiresAt, time.Second)
+}
+
+func (s *CharacterReadRepositoryTestSuite) TestSuccessGetByIDs() {
+	json_data, err := json.Marshal(domain.Character{ID: 1, Name: "lorem"})
+	if err != nil {
+		s.T().Fatalf("Error: '%s'", err)
+	}
+
+	s.mock.On("MGet", mock.Anything, []string{"marvel-v2-character-id-1", "marvel-v2-character-id-2", "marvel-v2-character-id-3", "marvel-v2-character-id-4"}).
+		Return(redis.NewSliceResult([]interface{}{string(json_data), nil, "", "val"}, nil))
+
+	res, err := s.repo.GetByIDs(context.Background(), []int{1, 2, 3, 4})
+	s.Assert().Equal(err, nil)
+	s.Assert().Len(res.Characters, 1)
+	s.Assert().Equal(uint(1), res.Characters[0].ID)
+	s.Assert().Equal([]int{2, 4}, res.Missing)
+	s.Assert().Equal([]int{3}, res.NotFound)
+}
+
+func (s *CharacterReadRepositoryTestSuite) TestFailedGetByIDs() {
+	s.mock.On("MGet", mock.Anything, mock.Anything).Return(redis.NewSliceResult(nil, errors.New("SomeError")))
+
+	_, err := s.repo.GetByIDs(context.Background(), []int{1})
+	s.Assert().Equal(err, domain.ErrInternalServerError)
+}
+
+func (s *CharacterReadRepositoryTestSuite) TestNotFoundRange() {
+	_, err := s.repo.Range(context.Background(), domain.OrderByName, 0, 10)
+	s.Assert().Equal(domain.CodeNotFound, domain.CodeOf(err))
+}
+
+func (s *CharacterReadRepositoryTestSuite) TestCacheLookupMetrics() {
+	hit := metrics.CacheLookups.WithLabelValues("page", metrics.CacheHit)
+	miss := metrics.CacheLookups.WithLabelValues("page", metrics.CacheMiss)
+	beforeHit, beforeMiss := testutil.ToFloat64(hit), testutil.ToFloat64(miss)
+
+	s.mock.On("Exists", mock.Anything, []string{"marvel-v2-characters-page-4"}).Return(redis.NewIntResult(1, nil))
+	s.mock.On("Exists", mock.Anything, []string{"marvel-v2-characters-page-5"}).Return(redis.NewIntResult(0, nil))
+	s.mock.On("Get", mock.Anything, "marvel-v2-characters-page-4").Return(redis.NewStringResult("[1]", nil))
+
+	_, err := s.repo.Fetch(context.Background(), 4)
+	s.Assert().Equal(err, nil)
+	_, err = s.repo.Fetch(context.Background(), 5)
+	s.Assert().Equal(err, domain.ErrCacheKeyEmpty)
+
+	s.Assert().Equal(beforeHit+1, testutil.ToFloat64(hit))
+	s.Assert().Equal(beforeMiss+1, testutil.ToFloat64(miss))
+}
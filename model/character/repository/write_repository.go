@@ -5,58 +5,210 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	redis "github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 
 	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
 )
 
 var (
 	HttpClient = http.DefaultClient
 )
 
+// pageSize is the number of characters of a page.
+const pageSize = 10
+
 type response struct {
-	Data data `json:data`
+	Data data `json:"data"`
 }
 
 type data struct {
-	Results Characters `json:results`
+	Results []result `json:"results"`
+}
+
+// result is a character as Marvel API returns it. Modified is only cached by
+// the hash layout.
+type result struct {
+	domain.Character
+	Modified marvelTime `json:"modified"`
+}
+
+// marvelTime reads the timestamps of Marvel API, like
+// "2014-04-29T14:18:17-0400". Those it cannot read are left zero, as Marvel
+// API sends some that are not dates.
+type marvelTime struct {
+	time.Time
+}
+
+func (t *marvelTime) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return nil
+	}
+	t.Time, _ = time.Parse("2006-01-02T15:04:05-0700", str)
+
+	return nil
 }
 
 type Characters []domain.Character
 
-type CharacterWriteRepository struct {
-	httpClient      *http.Client
-	redisClient     redis.Cmdable
-	api             string
-	publicKey       string
-	privateKey      string
-	cacheExpiration time.Duration
+// Popularity tells whether the character or page id, by kind, is looked up
+// often enough to be kept longer.
+type Popularity interface {
+	Popular(kind string, id int) bool
 }
 
-func NewCharacterWriteRepository(api, publicKey, privateKey string, Conn redis.Cmdable, timeout, cacheExpiration time.Duration) domain.CharacterWriteRepository {
-	httpClient := HttpClient
-	httpClient.Timeout = timeout
+// CharacterWriteRepository caches what Marvel API returns. Characters and
+// pages Marvel API does not know are cached as tombstones, empty values that
+// expire after notFoundExpiration, so that looking them up again does not
+// call Marvel API until then. Expirations are shortened by up to the jitter
+// ratio at random, so that keys written together do not expire together.
+// Popular characters and pages are kept for popularExpiration instead, when
+// set.
+type CharacterWriteRepository struct {
+	httpClient         *http.Client
+	redisClient        redis.Cmdable
+	keys               cachekey.Builder
+	encoder            *codec.Encoder
+	api                string
+	publicKey          string
+	privateKey         string
+	timeout            int64
+	cacheExpiration    int64
+	notFoundExpiration int64
+	jitter             uint64
+	popularExpiration  int64
+	popularity         Popularity
+	logger             *slog.Logger
+}
 
+func NewCharacterWriteRepository(api, publicKey, privateKey string, Conn redis.Cmdable, keys cachekey.Builder, encoder *codec.Encoder, timeout, cacheExpiration, notFoundExpiration time.Duration, logger *slog.Logger) *CharacterWriteRepository {
 	return &CharacterWriteRepository{
-		httpClient:      httpClient,
-		redisClient:     Conn,
-		api:             api,
-		publicKey:       publicKey,
-		privateKey:      privateKey,
-		cacheExpiration: cacheExpiration,
+		httpClient:         HttpClient,
+		redisClient:        Conn,
+		keys:               keys,
+		encoder:            encoder,
+		api:                api,
+		publicKey:          publicKey,
+		privateKey:         privateKey,
+		timeout:            int64(timeout),
+		cacheExpiration:    int64(cacheExpiration),
+		notFoundExpiration: int64(notFoundExpiration),
+		logger:             logger,
+	}
+}
+
+// SetTimeout changes the Marvel API timeout of requests started afterwards.
+func (r *CharacterWriteRepository) SetTimeout(timeout time.Duration) {
+	atomic.StoreInt64(&r.timeout, int64(timeout))
+}
+
+// SetCacheExpiration changes the expiration of keys written afterwards.
+func (r *CharacterWriteRepository) SetCacheExpiration(cacheExpiration time.Duration) {
+	atomic.StoreInt64(&r.cacheExpiration, int64(cacheExpiration))
+}
+
+// SetNotFoundExpiration changes the expiration of tombstones written
+// afterwards. 0 stops writing them.
+func (r *CharacterWriteRepository) SetNotFoundExpiration(notFoundExpiration time.Duration) {
+	atomic.StoreInt64(&r.notFoundExpiration, int64(notFoundExpiration))
+}
+
+// SetCacheExpirationJitter changes the jitter ratio, between 0 and 1, of the
+// expirations of keys written afterwards.
+func (r *CharacterWriteRepository) SetCacheExpirationJitter(ratio float64) {
+	atomic.StoreUint64(&r.jitter, math.Float64bits(ratio))
+}
+
+// SetPopularity tells the popular characters and pages. It is not safe to
+// call once keys are written.
+func (r *CharacterWriteRepository) SetPopularity(popularity Popularity) {
+	r.popularity = popularity
+}
+
+// SetPopularExpiration changes the expiration of the popular characters and
+// pages written afterwards. 0 keeps them as long as the others.
+func (r *CharacterWriteRepository) SetPopularExpiration(popularExpiration time.Duration) {
+	atomic.StoreInt64(&r.popularExpiration, int64(popularExpiration))
+}
+
+func (r *CharacterWriteRepository) getTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.timeout))
+}
+
+func (r *CharacterWriteRepository) getCacheExpiration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.cacheExpiration))
+}
+
+func (r *CharacterWriteRepository) getNotFoundExpiration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.notFoundExpiration))
+}
+
+func (r *CharacterWriteRepository) getPopularExpiration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.popularExpiration))
+}
+
+// getExpiration is the expiration of the character or page id, by kind,
+// longer when it is popular.
+func (r *CharacterWriteRepository) getExpiration(kind string, id int) time.Duration {
+	expiration := r.getCacheExpiration()
+	if popular := r.getPopularExpiration(); popular > expiration && r.popularity != nil && r.popularity.Popular(kind, id) {
+		return popular
 	}
+
+	return expiration
+}
+
+// getIndexExpiration outlives every character and page of the indexes.
+func (r *CharacterWriteRepository) getIndexExpiration() time.Duration {
+	expiration := r.getCacheExpiration()
+	if popular := r.getPopularExpiration(); popular > expiration {
+		return popular
+	}
+
+	return expiration
+}
+
+// withJitter shortens expiration by up to the jitter ratio.
+func (r *CharacterWriteRepository) withJitter(expiration time.Duration) time.Duration {
+	ratio := math.Float64frombits(atomic.LoadUint64(&r.jitter))
+	if ratio <= 0 || expiration <= 0 {
+		return expiration
+	}
+
+	return expiration - time.Duration(rand.Float64()*ratio*float64(expiration))
 }
 
-func (r *CharacterWriteRepository) StoreByPage(ctx context.Context, page int) error {
+// StoreByPage caches the IDs of page and its characters, unless the page is
+// cached already, in which case Marvel API is not called.
+func (r *CharacterWriteRepository) StoreByPage(c context.Context, page int) error {
+	return r.storeByPage(c, page, false)
+}
+
+// RefreshByPage caches page and its characters like StoreByPage, overwriting
+// the cached copies.
+func (r *CharacterWriteRepository) RefreshByPage(c context.Context, page int) error {
+	return r.storeByPage(c, page, true)
+}
+
+func (r *CharacterWriteRepository) storeByPage(c context.Context, page int, force bool) error {
+	ctx, cancel := context.WithTimeout(c, r.getTimeout())
+	defer cancel()
+
 	salt := uuid.New().String()
 	hash := generateHash(salt, r.publicKey, r.privateKey)
 
@@ -66,12 +218,19 @@ func (r *CharacterWriteRepository) StoreByPage(ctx context.Context, page int) er
 	} else {
 		pageNorm = page
 	}
-	limit := 10
-	offset := 10 * pageNorm
+	limit := pageSize
+	offset := pageSize * pageNorm
+	key := r.keys.Page(pageNorm)
 
-	req, err := http.NewRequest("GET", r.api+"/v1/public/characters", nil)
+	if !force {
+		if err := r.checkNotCached(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", r.api+"/v1/public/characters", nil)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return domain.ErrInternalServerError.Wrap(err)
 	}
 
 	q := req.URL.Query()
@@ -83,51 +242,79 @@ func (r *CharacterWriteRepository) StoreByPage(ctx context.Context, page int) er
 
 	req.URL.RawQuery = q.Encode()
 
-	res, err := r.httpClient.Do(req)
-	defer res.Body.Close()
+	res, err := r.do(req, "characters")
 	if err != nil {
-		log.Println("[ERROR][CharacterWriteRepository] StoreByPage httpClient.Do: " + err.Error())
+		r.logger.ErrorContext(ctx, "StoreByPage request failed", "page", page, "error", err)
 		return domain.ErrInternalServerError
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode == http.StatusNotFound {
-		return domain.ErrNotFound
+		return r.storeTombstone(ctx, key)
 	}
 
 	if res.StatusCode != http.StatusOK {
 		dump, _ := httputil.DumpResponse(res, true)
-		nerr := fmt.Errorf("Error saving page %d: %q", page, dump)
-		log.Println("[ERROR][CharacterWriteRepository] StoreByPage httpClient: " + nerr.Error())
+		r.logger.ErrorContext(ctx, "StoreByPage unexpected response", "page", page, "status", res.StatusCode, "response", string(dump))
 		return domain.ErrNotFound
 	}
 
 	var rs response
 	err = json.NewDecoder(res.Body).Decode(&rs)
 	if err != nil {
-		log.Println("[ERROR][CharacterWriteRepository] StoreByPage NewDecoder: " + err.Error())
+		r.logger.ErrorContext(ctx, "StoreByPage decode failed", "page", page, "error", err)
 		return domain.ErrInternalServerError
 	}
 	if len(rs.Data.Results) == 0 {
-		return domain.ErrNotFound
+		return r.storeTombstone(ctx, key)
 	}
 
-	IDs := getArrayFromCharacters(rs.Data.Results)
-	err = r.storePage(ctx, IDs, pageNorm)
+	IDs := getArrayFromResults(rs.Data.Results)
+	if r.keys.Layout() == cachekey.LayoutHash {
+		err = r.indexPage(ctx, IDs, pageNorm, offset, r.withJitter(r.getExpiration(cachekey.KindPage, pageNorm)), force)
+	} else {
+		err = r.storePage(ctx, IDs, pageNorm, force)
+	}
 	if err != nil {
-		log.Println("[INFO][CharacterWriteRepository] StoreByPage storePage: " + err.Error())
+		r.logger.InfoContext(ctx, "StoreByPage storePage skipped", "page", pageNorm, "error", err)
 		return domain.ErrInternalServerError
 	}
 
-	err = r.storeCharacters(ctx, rs.Data.Results)
+	err = r.storeCharacters(ctx, rs.Data.Results, force)
 	if err != nil {
-		log.Println("[WARNING][CharacterWriteRepository] StoreByPage storeCharacters: " + err.Error())
+		r.logger.WarnContext(ctx, "StoreByPage storeCharacters skipped", "page", pageNorm, "error", err)
+		if force {
+			return domain.ErrInternalServerError
+		}
 		return domain.ErrCacheKeyExists
 	}
 
 	return nil
 }
 
-func (r *CharacterWriteRepository) StoreByID(ctx context.Context, id int) error {
+// StoreByID caches the character id, unless it is cached already, in which
+// case Marvel API is not called.
+func (r *CharacterWriteRepository) StoreByID(c context.Context, id int) error {
+	return r.storeByID(c, id, false)
+}
+
+// RefreshByID caches the character id like StoreByID, overwriting the cached
+// copy.
+func (r *CharacterWriteRepository) RefreshByID(c context.Context, id int) error {
+	return r.storeByID(c, id, true)
+}
+
+func (r *CharacterWriteRepository) storeByID(c context.Context, id int, force bool) error {
+	ctx, cancel := context.WithTimeout(c, r.getTimeout())
+	defer cancel()
+
+	key := r.keys.Character(id)
+	if !force {
+		if err := r.checkNotCached(ctx, key); err != nil {
+			return err
+		}
+	}
+
 	salt := uuid.New().String()
 	hash := generateHash(salt, r.publicKey, r.privateKey)
 
@@ -140,111 +327,179 @@ func (r *CharacterWriteRepository) StoreByID(ctx context.Context, id int) error
 
 	url.RawQuery = q.Encode()
 
-	res, err := r.httpClient.Get(url.String())
+	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
+	if err != nil {
+		return domain.ErrInternalServerError.Wrap(err)
+	}
+
+	res, err := r.do(req, "character")
 	if err != nil {
-		log.Println("[ERROR][CharacterWriteRepository] StoreByID httpClient: " + err.Error())
+		r.logger.ErrorContext(ctx, "StoreByID request failed", "id", id, "error", err)
 		return domain.ErrInternalServerError
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode == http.StatusNotFound {
-		return domain.ErrNotFound
+		return r.storeTombstone(ctx, key)
 	}
 
 	if res.StatusCode != http.StatusOK {
 		dump, _ := httputil.DumpResponse(res, true)
-		nerr := fmt.Errorf("Error saving ID %d: %q", id, dump)
-		log.Println("[ERROR][CharacterWriteRepository] StoreByID httpClient: " + nerr.Error())
+		r.logger.ErrorContext(ctx, "StoreByID unexpected response", "id", id, "status", res.StatusCode, "response", string(dump))
 		return domain.ErrNotFound
 	}
 
 	var rs response
 	err = json.NewDecoder(res.Body).Decode(&rs)
 	if err != nil {
-		log.Println("[ERROR][CharacterWriteRepository] StoreByID Decode: " + err.Error())
+		r.logger.ErrorContext(ctx, "StoreByID decode failed", "id", id, "error", err)
 		return domain.ErrInternalServerError
 	}
 	if len(rs.Data.Results) == 0 {
-		return domain.ErrNotFound
+		return r.storeTombstone(ctx, key)
 	}
 
 	char := rs.Data.Results[0]
-	err = r.storeCharacter(ctx, char)
+	err = r.storeCharacter(ctx, char.Character, char.Modified.Time, force)
 	if err != nil {
-		log.Println("[INFO][CharacterWriteRepository] StoreByID storeCharacter: " + err.Error())
+		r.logger.InfoContext(ctx, "StoreByID storeCharacter skipped", "id", id, "error", err)
 		return err
 	}
 	return nil
 }
 
+// do sends req to the Marvel API and records the call under endpoint.
+func (r *CharacterWriteRepository) do(req *http.Request, endpoint string) (*http.Response, error) {
+	start := time.Now()
+	res, err := r.httpClient.Do(req)
+
+	code := 0
+	if err == nil {
+		code = res.StatusCode
+	}
+	metrics.ObserveUpstream(endpoint, code, time.Since(start))
+
+	return res, err
+}
+
 func generateHash(salt, publicKey, privateKey string) string {
 	return fmt.Sprintf("%x", md5.Sum([]byte(salt+privateKey+publicKey)))
 }
 
-func getArrayFromCharacters(chars []domain.Character) []int {
+func getArrayFromResults(results []result) []int {
 	var IDs []int
 
-	for _, v := range chars {
+	for _, v := range results {
 		IDs = append(IDs, int(v.ID))
 	}
 
 	return IDs
 }
 
-func (r *CharacterWriteRepository) storePage(ctx context.Context, IDs []int, page int) error {
-	key := "marvel-characters-page-" + fmt.Sprint(page)
+func (r *CharacterWriteRepository) storePage(ctx context.Context, IDs []int, page int, force bool) error {
+	key := r.keys.Page(page)
 
-	isExists, err := r.checkRedisKeyExists(ctx, key)
-	if err != nil {
-		return err
-	}
-	if isExists {
-		return domain.ErrCacheKeyExists
+	if !force {
+		isExists, err := r.checkRedisKeyExists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if isExists {
+			return domain.ErrCacheKeyExists
+		}
 	}
 
-	json_data, err := json.Marshal(IDs)
+	data, err := r.encoder.Encode(IDs)
 	if err != nil {
 		return err
 	}
-	_, err = r.redisClient.Set(ctx, key, string(json_data), r.cacheExpiration).Result()
+	_, err = r.redisClient.Set(ctx, key, string(data), r.withJitter(r.getExpiration(cachekey.KindPage, page))).Result()
 	return err
 }
 
-func (r *CharacterWriteRepository) storeCharacters(ctx context.Context, chars []domain.Character) error {
-	json_data, _ := json.Marshal(getArrayFromCharacters(chars))
-	log.Println("[INFO] Caching character IDs: " + string(json_data))
+func (r *CharacterWriteRepository) storeCharacters(ctx context.Context, results []result, force bool) error {
+	r.logger.InfoContext(ctx, "Caching characters", "ids", getArrayFromResults(results))
 
-	return Characters(chars).Each(10, func(c domain.Character, wg *sync.WaitGroup) error {
-		err := r.storeCharacter(ctx, c)
-		wg.Done()
-		return err
+	chars := make(Characters, len(results))
+	modified := make(map[uint]time.Time, len(results))
+	for i, res := range results {
+		chars[i] = res.Character
+		modified[res.ID] = res.Modified.Time
+	}
+
+	return chars.Each(10, func(c domain.Character) error {
+		return r.storeCharacter(ctx, c, modified[c.ID], force)
 	})
 }
 
-func (r *CharacterWriteRepository) storeCharacter(ctx context.Context, char domain.Character) error {
-	key := "marvel-character-id-" + fmt.Sprint(char.ID)
+func (r *CharacterWriteRepository) storeCharacter(ctx context.Context, char domain.Character, modified time.Time, force bool) error {
+	key := r.keys.Character(int(char.ID))
 	char.FetchedAt = time.Now()
 
-	isExists, err := r.checkRedisKeyExists(ctx, key)
-	if err != nil {
-		return err
+	if !force {
+		isExists, err := r.checkRedisKeyExists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if isExists {
+			return domain.ErrCacheKeyExists
+		}
 	}
-	if isExists {
-		return domain.ErrCacheKeyExists
+
+	if r.keys.Layout() == cachekey.LayoutHash {
+		return r.storeCharacterHash(ctx, char, modified, r.withJitter(r.getExpiration(cachekey.KindCharacter, int(char.ID))))
 	}
 
-	json_data, err := json.Marshal(char)
+	data, err := r.encoder.Encode(char)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.redisClient.Set(ctx, key, string(json_data), r.cacheExpiration).Result()
+	_, err = r.redisClient.Set(ctx, key, string(data), r.withJitter(r.getExpiration(cachekey.KindCharacter, int(char.ID)))).Result()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// checkNotCached fails with ErrCacheKeyExists when key holds a copy or a
+// tombstone.
+func (r *CharacterWriteRepository) checkNotCached(ctx context.Context, key string) error {
+	isExists, err := r.checkRedisKeyExists(ctx, key)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "checkRedisKeyExists failed", "key", key, "error", err)
+		return domain.ErrInternalServerError
+	}
+	if isExists {
+		return domain.ErrCacheKeyExists
+	}
+
+	return nil
+}
+
+// storeTombstone caches key as not found on Marvel API. It returns
+// ErrNotFound, what the lookup of key found.
+func (r *CharacterWriteRepository) storeTombstone(ctx context.Context, key string) error {
+	expiration := r.withJitter(r.getNotFoundExpiration())
+	if expiration <= 0 {
+		return domain.ErrNotFound
+	}
+
+	var err error
+	if r.keys.Layout() == cachekey.LayoutHash && r.keys.Kind(key) == cachekey.KindCharacter {
+		err = r.storeCharacterTombstone(ctx, key, expiration)
+	} else {
+		err = r.redisClient.Set(ctx, key, "", expiration).Err()
+	}
+	if err != nil {
+		r.logger.WarnContext(ctx, "Caching tombstone failed", "key", key, "error", err)
+		return domain.ErrNotFound
+	}
+	metrics.TombstonesStored.WithLabelValues(r.keys.Kind(key)).Inc()
+
+	return domain.ErrNotFound
+}
+
 func (r *CharacterWriteRepository) checkRedisKeyExists(ctx context.Context, str string) (bool, error) {
 	val, err := r.redisClient.Exists(ctx, str).Result()
 	if err != nil {
@@ -254,20 +509,74 @@ func (r *CharacterWriteRepository) checkRedisKeyExists(ctx context.Context, str
 	return val == 1, nil
 }
 
-func (cs Characters) Each(workers int, fn func(domain.Character, *sync.WaitGroup) error) error {
+// ImportCharacter writes the character id of a snapshot for expiration, 0
+// keeping it without expiry, replacing the cached copy. A nil character is
+// a tombstone. modified scores it in the ByModified index of the hash layout
+// when known.
+func (r *CharacterWriteRepository) ImportCharacter(ctx context.Context, id int, char *domain.Character, modified time.Time, expiration time.Duration) error {
+	key := r.keys.Character(id)
+
+	switch {
+	case r.keys.Layout() == cachekey.LayoutHash && char == nil:
+		return r.storeCharacterTombstone(ctx, key, expiration)
+	case r.keys.Layout() == cachekey.LayoutHash:
+		return r.storeCharacterHash(ctx, *char, modified, expiration)
+	case char == nil:
+		return r.redisClient.Set(ctx, key, "", expiration).Err()
+	}
+
+	data, err := r.encoder.Encode(*char)
+	if err != nil {
+		return err
+	}
+
+	return r.redisClient.Set(ctx, key, string(data), expiration).Err()
+}
+
+// ImportPage writes the page of a snapshot for expiration, 0 keeping it
+// without expiry, replacing the cached copy. Nil IDs are a tombstone.
+func (r *CharacterWriteRepository) ImportPage(ctx context.Context, page int, IDs []int, expiration time.Duration) error {
+	key := r.keys.Page(page)
+
+	switch {
+	case IDs == nil:
+		return r.redisClient.Set(ctx, key, "", expiration).Err()
+	case r.keys.Layout() == cachekey.LayoutHash:
+		return r.indexPage(ctx, IDs, page, pageSize*page, expiration, true)
+	}
+
+	data, err := r.encoder.Encode(IDs)
+	if err != nil {
+		return err
+	}
+
+	return r.redisClient.Set(ctx, key, string(data), expiration).Err()
+}
+
+// Each runs fn on every character, workers at a time, and returns the first
+// error.
+func (cs Characters) Each(workers int, fn func(domain.Character) error) error {
 	var wg sync.WaitGroup
-	err := make(chan error)
+	errs := make(chan error, len(cs))
+	slots := make(chan struct{}, workers)
 
-	for i, c := range cs {
+	for _, c := range cs {
 		wg.Add(1)
+		slots <- struct{}{}
 		go func() {
-			err <- fn(c, &wg)
+			defer wg.Done()
+			defer func() { <-slots }()
+			errs <- fn(c)
 		}()
-		if i%workers == 0 {
-			wg.Wait()
-		}
 	}
 	wg.Wait()
+	close(errs)
 
-	return <-err
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -3,6 +3,7 @@ package repository_test
 import (
 	"context"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
@@ -15,12 +16,15 @@ import (
 
 	"github.com/hezbymuhammad/golang-marvel-demo/domain"
 	"github.com/hezbymuhammad/golang-marvel-demo/model/character/repository"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
 )
 
 type CharacterWriteRepositoryTestSuite struct {
 	suite.Suite
 	redisMock *redismock.ClientMock
-	repo      domain.CharacterWriteRepository
+	repo      *repository.CharacterWriteRepository
 }
 
 func TestCharacterWriteRepository(t *testing.T) {
@@ -43,13 +47,13 @@ func (s *CharacterWriteRepositoryTestSuite) SetupTest() {
 		Addr: mr.Addr(),
 	})
 	s.redisMock = redismock.NewNiceMock(client)
-	s.repo = repository.NewCharacterWriteRepository(api, pubK, privK, s.redisMock, timeout, cacheExpiration)
+	s.repo = repository.NewCharacterWriteRepository(api, pubK, privK, s.redisMock, cachekey.New(cachekey.DefaultPrefix), codec.NewEncoder(codec.JSON, codec.None, 0), timeout, cacheExpiration, time.Second, logger.Discard())
 }
 
 func (s *CharacterWriteRepositoryTestSuite) TestSuccessStoreByPage() {
 	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString("{\"data\": { \"results\": [{\"id\": 1011334, \"name\": \"lorem\", \"description\": \"asd\"}] }}")
-	s.redisMock.On("Set", mock.Anything, "marvel-characters-page-1", "[1011334]", mock.Anything).Return(redis.NewStatusResult("", nil))
-	s.redisMock.On("Set", mock.Anything, "marvel-character-id-1011334", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-characters-page-1", "[1011334]", mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-1011334", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
 	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
 
 	err := s.repo.StoreByPage(context.Background(), 1)
@@ -58,8 +62,8 @@ func (s *CharacterWriteRepositoryTestSuite) TestSuccessStoreByPage() {
 
 func (s *CharacterWriteRepositoryTestSuite) TestSuccessStoreByPageWithNumLessThanZero() {
 	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString("{\"data\": { \"results\": [{\"id\": 1011334, \"name\": \"lorem\", \"description\": \"asd\"}] }}")
-	s.redisMock.On("Set", mock.Anything, "marvel-characters-page-1", "[1011334]", mock.Anything).Return(redis.NewStatusResult("", nil))
-	s.redisMock.On("Set", mock.Anything, "marvel-character-id-1011334", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-characters-page-1", "[1011334]", mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-1011334", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
 	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
 
 	err := s.repo.StoreByPage(context.Background(), -12)
@@ -82,8 +86,8 @@ func (s *CharacterWriteRepositoryTestSuite) TestFailedJSONStoreByPage() {
 
 func (s *CharacterWriteRepositoryTestSuite) TestFailedRedisStoreByPage() {
 	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString("{\"data\": { \"results\": [{\"id\": 1011334, \"name\": \"lorem\", \"description\": \"asd\"}] }}")
-	s.redisMock.On("Set", mock.Anything, "marvel-characters-page-1", "[1011334]", mock.Anything).Return(redis.NewStatusResult("", errors.New("error")))
-	s.redisMock.On("Set", mock.Anything, "marvel-character-id-1011334", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-characters-page-1", "[1011334]", mock.Anything).Return(redis.NewStatusResult("", errors.New("error")))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-1011334", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
 	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
 
 	err := s.repo.StoreByPage(context.Background(), 1)
@@ -106,7 +110,7 @@ func (s *CharacterWriteRepositoryTestSuite) TestHttpErrorStoreByPage() {
 
 func (s *CharacterWriteRepositoryTestSuite) TestSuccessStoreByID() {
 	gock.New("http://foo.com").Get("/v1/public/characters/1").Reply(200).BodyString("{\"data\": { \"results\": [{\"id\": 10113341, \"name\": \"lorem\", \"description\": \"asd\"}] }}")
-	s.redisMock.On("Set", mock.Anything, "marvel-character-id-10113341", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-10113341", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
 	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
 
 	err := s.repo.StoreByID(context.Background(), 1)
@@ -129,7 +133,7 @@ func (s *CharacterWriteRepositoryTestSuite) TestFailedJSONStoreByID() {
 
 func (s *CharacterWriteRepositoryTestSuite) TestFailedRedisStoreByID() {
 	gock.New("http://foo.com").Get("/v1/public/characters/4").Reply(200).BodyString("{\"data\": { \"results\": [{\"id\": 10113344, \"name\": \"lorem\", \"description\": \"asd\"}] }}")
-	s.redisMock.On("Set", mock.Anything, "marvel-character-id-10113344", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", errors.New("error")))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-10113344", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", errors.New("error")))
 	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
 
 	err := s.repo.StoreByID(context.Background(), 4)
@@ -144,6 +148,27 @@ func (s *CharacterWriteRepositoryTestSuite) TestRedisKeyExistsStoreByID() {
 	s.Assert().Equal(err, domain.ErrCacheKeyExists)
 }
 
+func (s *CharacterWriteRepositoryTestSuite) TestRedisKeyExistsRefreshByID() {
+	gock.New("http://foo.com").Get("/v1/public/characters/9").Reply(200).BodyString("{\"data\": { \"results\": [{\"id\": 10113349, \"name\": \"lorem\", \"description\": \"asd\"}] }}")
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-10113349", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
+
+	err := s.repo.RefreshByID(context.Background(), 9)
+	s.Assert().Equal(err, nil)
+	s.redisMock.AssertNotCalled(s.T(), "Exists", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestRedisKeyExistsRefreshByPage() {
+	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString("{\"data\": { \"results\": [{\"id\": 10113350, \"name\": \"lorem\", \"description\": \"asd\"}] }}")
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-characters-page-3", "[10113350]", mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-10113350", mock.Anything, mock.Anything).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
+
+	err := s.repo.RefreshByPage(context.Background(), 3)
+	s.Assert().Equal(err, nil)
+	s.redisMock.AssertNotCalled(s.T(), "Exists", mock.Anything, mock.Anything)
+}
+
 func (s *CharacterWriteRepositoryTestSuite) TestHttpNotFoundStoreByID() {
 	gock.New("http://foo.com").Get("/v1/public/characters/6").Reply(404).BodyString("{\"data\": {  }}")
 
@@ -157,3 +182,113 @@ func (s *CharacterWriteRepositoryTestSuite) TestHttpErrorStoreByID() {
 	err := s.repo.StoreByID(context.Background(), 7)
 	s.Assert().Equal(err, domain.ErrNotFound)
 }
+
+func (s *CharacterWriteRepositoryTestSuite) TestSuccessSetCacheExpiration() {
+	gock.New("http://foo.com").Get("/v1/public/characters/8").Reply(200).BodyString("{\"data\": { \"results\": [{\"id\": 10113348, \"name\": \"lorem\", \"description\": \"asd\"}] }}")
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-10113348", mock.Anything, time.Minute).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
+
+	s.repo.SetCacheExpiration(time.Minute)
+	err := s.repo.StoreByID(context.Background(), 8)
+	s.Assert().Equal(err, nil)
+	s.redisMock.AssertCalled(s.T(), "Set", mock.Anything, "marvel-v2-character-id-10113348", mock.Anything, time.Minute)
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestSuccessSetCacheExpirationJitter() {
+	inRange := mock.MatchedBy(func(expiration time.Duration) bool {
+		return expiration > 45*time.Second && expiration <= time.Minute
+	})
+	s.redisMock.On("Set", mock.Anything, mock.Anything, mock.Anything, inRange).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
+
+	s.repo.SetCacheExpiration(time.Minute)
+	s.repo.SetCacheExpirationJitter(0.25)
+	for id := 20; id < 30; id++ {
+		gock.New("http://foo.com").Get("/v1/public/characters/" + strconv.Itoa(id)).Reply(200).BodyString(`{"data": {"results": [{"id": ` + strconv.Itoa(id) + `, "name": "lorem"}]}}`)
+		s.Require().NoError(s.repo.StoreByID(context.Background(), id))
+	}
+	s.redisMock.AssertNumberOfCalls(s.T(), "Set", 10)
+}
+
+type popularIDs map[int]bool
+
+func (p popularIDs) Popular(kind string, id int) bool {
+	return kind == cachekey.KindCharacter && p[id]
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestSuccessSetPopularExpiration() {
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-31", mock.Anything, time.Hour).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-32", mock.Anything, time.Minute).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
+
+	s.repo.SetCacheExpiration(time.Minute)
+	s.repo.SetPopularExpiration(time.Hour)
+	s.repo.SetPopularity(popularIDs{31: true})
+	for _, id := range []int{31, 32} {
+		gock.New("http://foo.com").Get("/v1/public/characters/" + strconv.Itoa(id)).Reply(200).BodyString(`{"data": {"results": [{"id": ` + strconv.Itoa(id) + `, "name": "lorem"}]}}`)
+		s.Require().NoError(s.repo.StoreByID(context.Background(), id))
+	}
+	s.redisMock.AssertNumberOfCalls(s.T(), "Set", 2)
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestSuccessImportCharacterProtobuf() {
+	enc := codec.NewEncoder(codec.Protobuf, codec.None, 0)
+	repo := repository.NewCharacterWriteRepository("http://foo.com", "asd", "asd", s.redisMock, cachekey.New(cachekey.DefaultPrefix), enc, time.Second, time.Minute, time.Second, logger.Discard())
+	char := domain.Character{ID: 1, Name: "Hulk"}
+	data, err := enc.Encode(char)
+	s.Require().NoError(err)
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-1", string(data), time.Hour).Return(redis.NewStatusResult("", nil)).Once()
+
+	err = repo.ImportCharacter(context.Background(), 1, &char, time.Time{}, time.Hour)
+	s.Assert().NoError(err)
+	s.redisMock.AssertExpectations(s.T())
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestTombstoneStoreByID() {
+	gock.New("http://foo.com").Get("/v1/public/characters/11").Reply(404).BodyString("{\"data\": {  }}")
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-character-id-11", "", time.Second).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
+
+	err := s.repo.StoreByID(context.Background(), 11)
+	s.Assert().Equal(err, domain.ErrNotFound)
+	s.redisMock.AssertCalled(s.T(), "Set", mock.Anything, "marvel-v2-character-id-11", "", time.Second)
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestTombstoneStoreByPage() {
+	gock.New("http://foo.com").Get("/v1/public/characters").Reply(200).BodyString("{\"data\": { \"results\": [] }}")
+	s.redisMock.On("Set", mock.Anything, "marvel-v2-characters-page-900", "", time.Second).Return(redis.NewStatusResult("", nil))
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
+
+	err := s.repo.StoreByPage(context.Background(), 900)
+	s.Assert().Equal(err, domain.ErrNotFound)
+	s.redisMock.AssertCalled(s.T(), "Set", mock.Anything, "marvel-v2-characters-page-900", "", time.Second)
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestNoTombstoneOnHttpError() {
+	gock.New("http://foo.com").Get("/v1/public/characters/12").Reply(500).BodyString("{\"data\": {  }}")
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
+
+	err := s.repo.StoreByID(context.Background(), 12)
+	s.Assert().Equal(err, domain.ErrNotFound)
+	s.redisMock.AssertNotCalled(s.T(), "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestNoTombstoneWhenDisabled() {
+	gock.New("http://foo.com").Get("/v1/public/characters/13").Reply(404).BodyString("{\"data\": {  }}")
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(0, nil))
+
+	s.repo.SetNotFoundExpiration(0)
+	err := s.repo.StoreByID(context.Background(), 13)
+	s.Assert().Equal(err, domain.ErrNotFound)
+	s.redisMock.AssertNotCalled(s.T(), "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CharacterWriteRepositoryTestSuite) TestCachedStoreByIDSkipsAPI() {
+	defer gock.Off()
+	gock.New("http://foo.com").Get("/v1/public/characters/14").Reply(404).BodyString("{\"data\": {  }}")
+	s.redisMock.On("Exists", mock.Anything, mock.Anything).Return(redis.NewIntResult(1, nil))
+
+	err := s.repo.StoreByID(context.Background(), 14)
+	s.Assert().Equal(err, domain.ErrCacheKeyExists)
+	s.Assert().True(gock.IsPending())
+}
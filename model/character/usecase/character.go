@@ -2,34 +2,67 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/ratelimit"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/tracing"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/worker"
 )
 
+// maxBatchSize bounds the IDs of a batch lookup, and so the size of the MGET
+// and of the background fills it can trigger.
+const maxBatchSize = 100
+
+var tracer = otel.Tracer("github.com/hezbymuhammad/golang-marvel-demo/model/character/usecase")
+
 type characterUsecase struct {
 	characterReadRepo  domain.CharacterReadRepository
 	characterWriteRepo domain.CharacterWriteRepository
+	popularityRepo     domain.PopularityRepository
+	pool               *worker.Pool
 	contextTimeout     time.Duration
+	logger             *slog.Logger
 }
 
-func NewCharacterUsecase(crr domain.CharacterReadRepository, cwr domain.CharacterWriteRepository, timeout time.Duration) domain.CharacterUsecase {
+// NewCharacterUsecase builds the character usecase. pr may be nil when
+// lookups are not counted, Popular then failing with not found.
+func NewCharacterUsecase(crr domain.CharacterReadRepository, cwr domain.CharacterWriteRepository, pr domain.PopularityRepository, pool *worker.Pool, timeout time.Duration, logger *slog.Logger) domain.CharacterUsecase {
 	return &characterUsecase{
 		characterReadRepo:  crr,
 		characterWriteRepo: cwr,
+		popularityRepo:     pr,
+		pool:               pool,
 		contextTimeout:     timeout,
+		logger:             logger,
 	}
 }
 
-func (cu *characterUsecase) Fetch(c context.Context, page int) ([]int, error) {
+func (cu *characterUsecase) Fetch(c context.Context, page int) (res []int, err error) {
+	c, span := tracer.Start(c, "CharacterUsecase.Fetch", trace.WithAttributes(attribute.Int("page", page)))
+	defer func() { tracing.End(span, err) }()
+
 	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
 	defer cancel()
 
-	go func() {
-		_ = cu.characterWriteRepo.StoreByPage(context.Background(), page)
-	}()
+	res, err = cu.characterReadRepo.Fetch(ctx, page)
+	if errors.Is(err, domain.ErrCacheKeyEmpty) {
+		if err := ratelimit.AllowMiss(c); err != nil {
+			return nil, err
+		}
+	}
 
-	res, err := cu.characterReadRepo.Fetch(ctx, page)
+	cu.refresh(c, "StoreByPage", func(ctx context.Context) error {
+		return cu.characterWriteRepo.StoreByPage(ctx, page)
+	}, slog.Int("page", page))
 
 	if err != nil {
 		return nil, err
@@ -38,15 +71,45 @@ func (cu *characterUsecase) Fetch(c context.Context, page int) ([]int, error) {
 	return res, nil
 }
 
-func (cu *characterUsecase) GetByID(c context.Context, id int) (domain.Character, error) {
+// FetchCharacters returns the characters of page rather than their IDs.
+func (cu *characterUsecase) FetchCharacters(c context.Context, page int) (res domain.CharacterPage, err error) {
+	c, span := tracer.Start(c, "CharacterUsecase.FetchCharacters", trace.WithAttributes(attribute.Int("page", page)))
+	defer func() { tracing.End(span, err) }()
+
+	ids, err := cu.Fetch(c, page)
+	if err != nil {
+		return domain.CharacterPage{}, err
+	}
+
+	batch, err := cu.GetByIDs(c, ids)
+	if err != nil {
+		return domain.CharacterPage{}, err
+	}
+
+	return domain.CharacterPage{
+		Page:           page,
+		CharacterBatch: batch,
+		Partial:        len(batch.Missing) > 0,
+	}, nil
+}
+
+func (cu *characterUsecase) GetByID(c context.Context, id int) (res domain.Character, err error) {
+	c, span := tracer.Start(c, "CharacterUsecase.GetByID", trace.WithAttributes(attribute.Int("id", id)))
+	defer func() { tracing.End(span, err) }()
+
 	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
 	defer cancel()
 
-	go func() {
-		_ = cu.characterWriteRepo.StoreByID(context.Background(), id)
-	}()
+	res, err = cu.characterReadRepo.GetByID(ctx, id)
+	if errors.Is(err, domain.ErrCacheKeyEmpty) {
+		if err := ratelimit.AllowMiss(c); err != nil {
+			return domain.Character{}, err
+		}
+	}
 
-	res, err := cu.characterReadRepo.GetByID(ctx, id)
+	cu.refresh(c, "StoreByID", func(ctx context.Context) error {
+		return cu.characterWriteRepo.StoreByID(ctx, id)
+	}, slog.Int("id", id))
 
 	if err != nil {
 		return domain.Character{}, err
@@ -54,3 +117,130 @@ func (cu *characterUsecase) GetByID(c context.Context, id int) (domain.Character
 
 	return res, nil
 }
+
+// GetByIDs looks many characters up at once. Duplicated IDs are looked up
+// once, and the missing ones are fetched in the background as long as the
+// miss limit of the caller allows. Those Marvel API does not know are not
+// fetched again, nor charged to the miss limit.
+func (cu *characterUsecase) GetByIDs(c context.Context, ids []int) (res domain.CharacterBatch, err error) {
+	c, span := tracer.Start(c, "CharacterUsecase.GetByIDs", trace.WithAttributes(attribute.Int("ids", len(ids))))
+	defer func() { tracing.End(span, err) }()
+
+	if len(ids) == 0 || len(ids) > maxBatchSize {
+		return domain.CharacterBatch{}, domain.NewError(domain.CodeBadRequest, fmt.Sprintf("between 1 and %d ids are required", maxBatchSize))
+	}
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	res, err = cu.characterReadRepo.GetByIDs(ctx, unique(ids))
+	if err != nil {
+		return domain.CharacterBatch{}, err
+	}
+
+	for _, id := range res.Missing {
+		id := id
+		// The other missing characters are left to a later lookup.
+		if err := ratelimit.AllowMiss(c); err != nil {
+			cu.logger.DebugContext(c, "Fetching missing characters rate limited", "id", id)
+			break
+		}
+		cu.refresh(c, "StoreByID", func(ctx context.Context) error {
+			return cu.characterWriteRepo.StoreByID(ctx, id)
+		}, slog.Int("id", id))
+	}
+
+	return res, nil
+}
+
+// Range returns the IDs of limit cached characters by order from offset on.
+// Unlike pages, nothing is fetched from Marvel API for them.
+func (cu *characterUsecase) Range(c context.Context, order string, offset, limit int) (res []int, err error) {
+	c, span := tracer.Start(c, "CharacterUsecase.Range", trace.WithAttributes(attribute.String("order", order), attribute.Int("offset", offset), attribute.Int("limit", limit)))
+	defer func() { tracing.End(span, err) }()
+
+	if limit < 1 || limit > maxBatchSize {
+		return nil, domain.NewError(domain.CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxBatchSize))
+	}
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	return cu.characterReadRepo.Range(ctx, order, offset, limit)
+}
+
+// RangeCharacters returns the characters of Range rather than their IDs.
+func (cu *characterUsecase) RangeCharacters(c context.Context, order string, offset, limit int) (res domain.CharacterBatch, err error) {
+	c, span := tracer.Start(c, "CharacterUsecase.RangeCharacters", trace.WithAttributes(attribute.String("order", order), attribute.Int("offset", offset), attribute.Int("limit", limit)))
+	defer func() { tracing.End(span, err) }()
+
+	ids, err := cu.Range(c, order, offset, limit)
+	if err != nil {
+		return domain.CharacterBatch{}, err
+	}
+	if len(ids) == 0 {
+		return domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{}, NotFound: []int{}}, nil
+	}
+
+	return cu.GetByIDs(c, ids)
+}
+
+// Popular returns the limit characters looked up the most within window.
+func (cu *characterUsecase) Popular(c context.Context, window time.Duration, limit int) (res domain.PopularCharacters, err error) {
+	c, span := tracer.Start(c, "CharacterUsecase.Popular", trace.WithAttributes(attribute.String("window", window.String()), attribute.Int("limit", limit)))
+	defer func() { tracing.End(span, err) }()
+
+	if cu.popularityRepo == nil {
+		return domain.PopularCharacters{}, domain.NewError(domain.CodeNotFound, "lookups are not counted")
+	}
+	if retention := cu.popularityRepo.Retention(); window <= 0 || window > retention {
+		return domain.PopularCharacters{}, domain.NewError(domain.CodeBadRequest, "window must be a positive duration up to "+retention.String())
+	}
+	if limit < 1 || limit > maxBatchSize {
+		return domain.PopularCharacters{}, domain.NewError(domain.CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxBatchSize))
+	}
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	hits, err := cu.popularityRepo.Top(ctx, cachekey.KindCharacter, window, limit, 1)
+	if err != nil {
+		return domain.PopularCharacters{}, err
+	}
+
+	return domain.PopularCharacters{Window: window.String(), Characters: hits}, nil
+}
+
+func unique(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	res := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+
+	return res
+}
+
+// refresh runs store in the background. It is handed the request context so
+// the job keeps its request ID and joins the request trace, but not its
+// deadline.
+func (cu *characterUsecase) refresh(c context.Context, op string, store func(ctx context.Context) error, attrs ...slog.Attr) {
+	err := cu.pool.Go(c, func(ctx context.Context) {
+		ctx, span := tracer.Start(ctx, "CharacterUsecase."+op)
+		err := store(ctx)
+		tracing.End(span, err)
+		switch {
+		case err == nil:
+		case errors.Is(err, domain.ErrCacheKeyExists):
+			cu.logger.LogAttrs(ctx, slog.LevelDebug, op+" skipped, already cached", attrs...)
+		default:
+			cu.logger.LogAttrs(ctx, slog.LevelWarn, op+" failed", append(attrs, slog.Any("error", err))...)
+		}
+	})
+	if err != nil {
+		cu.logger.LogAttrs(c, slog.LevelDebug, op+" not scheduled", append(attrs, slog.Any("error", err))...)
+	}
+}
@@ -12,13 +12,19 @@ import (
 	"github.com/hezbymuhammad/golang-marvel-demo/domain"
 	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
 	"github.com/hezbymuhammad/golang-marvel-demo/model/character/usecase"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/ratelimit"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/worker"
 )
 
 type CharacterUsecaseTestSuite struct {
 	suite.Suite
-	usecase   domain.CharacterUsecase
-	readRepo  *mocks.CharacterReadRepository
-	writeRepo *mocks.CharacterWriteRepository
+	usecase        domain.CharacterUsecase
+	readRepo       *mocks.CharacterReadRepository
+	writeRepo      *mocks.CharacterWriteRepository
+	popularityRepo *mocks.PopularityRepository
+	pool           *worker.Pool
 }
 
 func TestCharacterUsecase(t *testing.T) {
@@ -28,7 +34,14 @@ func TestCharacterUsecase(t *testing.T) {
 func (s *CharacterUsecaseTestSuite) SetupTest() {
 	s.readRepo = new(mocks.CharacterReadRepository)
 	s.writeRepo = new(mocks.CharacterWriteRepository)
-	s.usecase = usecase.NewCharacterUsecase(s.readRepo, s.writeRepo, time.Second*2)
+	s.popularityRepo = new(mocks.PopularityRepository)
+	s.popularityRepo.On("Retention").Return(7 * 24 * time.Hour)
+	s.pool = worker.NewPool()
+	s.usecase = usecase.NewCharacterUsecase(s.readRepo, s.writeRepo, s.popularityRepo, s.pool, time.Second*2, logger.Discard())
+}
+
+func (s *CharacterUsecaseTestSuite) TearDownTest() {
+	s.Assert().Equal(s.pool.Shutdown(context.Background()), nil)
 }
 
 func (s *CharacterUsecaseTestSuite) TestSuccessFetch() {
@@ -107,3 +120,152 @@ func (s *CharacterUsecaseTestSuite) TestFailedStoreByID() {
 	s.Assert().Equal(res, record)
 	s.Assert().Equal(err, nil)
 }
+
+func (s *CharacterUsecaseTestSuite) TestSkipStoreAfterShutdown() {
+	arr := []int{1, 2, 3}
+	s.readRepo.On("Fetch", mock.Anything, 1).Return(arr, nil).Once()
+	s.Assert().Equal(s.pool.Shutdown(context.Background()), nil)
+
+	res, err := s.usecase.Fetch(context.Background(), 1)
+	s.Assert().Equal(res, arr)
+	s.Assert().Equal(err, nil)
+	s.writeRepo.AssertNotCalled(s.T(), "StoreByPage", mock.Anything, 1)
+}
+
+func (s *CharacterUsecaseTestSuite) TestSuccessGetByIDs() {
+	batch := domain.CharacterBatch{
+		Characters: []domain.Character{{ID: 1, Name: "Lorem"}},
+		Missing:    []int{2},
+	}
+	s.readRepo.On("GetByIDs", mock.Anything, []int{1, 2}).Return(batch, nil).Once()
+	s.writeRepo.On("StoreByID", mock.Anything, 2).Return(nil).Once()
+
+	res, err := s.usecase.GetByIDs(context.Background(), []int{1, 2, 1})
+	s.Assert().Equal(res, batch)
+	s.Assert().Equal(err, nil)
+
+	s.Assert().Equal(s.pool.Shutdown(context.Background()), nil)
+	s.writeRepo.AssertExpectations(s.T())
+	s.writeRepo.AssertNotCalled(s.T(), "StoreByID", mock.Anything, 1)
+}
+
+func (s *CharacterUsecaseTestSuite) TestMissRateLimited() {
+	ctx := ratelimit.WithMissLimit(context.Background(), ratelimit.NewMemoryLimiter(), "miss-lorem", ratelimit.Limit{PerMinute: 1, Burst: 1}, logger.Discard())
+	s.readRepo.On("GetByID", mock.Anything, 1).Return(domain.Character{}, domain.ErrCacheKeyEmpty).Once()
+	s.readRepo.On("GetByID", mock.Anything, 2).Return(domain.Character{}, domain.ErrCacheKeyEmpty).Once()
+	s.readRepo.On("GetByIDs", mock.Anything, []int{3, 4}).Return(domain.CharacterBatch{Missing: []int{3, 4}}, nil).Once()
+	s.writeRepo.On("StoreByID", mock.Anything, 1).Return(nil).Once()
+
+	_, err := s.usecase.GetByID(ctx, 1)
+	s.Assert().True(errors.Is(err, domain.ErrCacheKeyEmpty))
+	_, err = s.usecase.GetByID(ctx, 2)
+	s.Assert().True(errors.Is(err, domain.ErrRateLimited))
+	res, err := s.usecase.GetByIDs(ctx, []int{3, 4})
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal([]int{3, 4}, res.Missing)
+
+	s.Assert().Equal(s.pool.Shutdown(context.Background()), nil)
+	s.writeRepo.AssertExpectations(s.T())
+	s.writeRepo.AssertNumberOfCalls(s.T(), "StoreByID", 1)
+}
+
+func (s *CharacterUsecaseTestSuite) TestNotFoundGetByIDs() {
+	ctx := ratelimit.WithMissLimit(context.Background(), ratelimit.NewMemoryLimiter(), "miss-lorem", ratelimit.Limit{PerMinute: 1, Burst: 1}, logger.Discard())
+	batch := domain.CharacterBatch{Characters: []domain.Character{}, Missing: []int{2}, NotFound: []int{1}}
+	s.readRepo.On("GetByIDs", mock.Anything, []int{1, 2}).Return(batch, nil).Once()
+	s.writeRepo.On("StoreByID", mock.Anything, 2).Return(nil).Once()
+
+	res, err := s.usecase.GetByIDs(ctx, []int{1, 2})
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal([]int{1}, res.NotFound)
+
+	s.Assert().Equal(s.pool.Shutdown(context.Background()), nil)
+	s.writeRepo.AssertExpectations(s.T())
+	s.writeRepo.AssertNotCalled(s.T(), "StoreByID", mock.Anything, 1)
+}
+
+func (s *CharacterUsecaseTestSuite) TestFailedGetByIDsSize() {
+	_, err := s.usecase.GetByIDs(context.Background(), nil)
+	s.Assert().True(errors.Is(err, domain.ErrBadRequest))
+
+	_, err = s.usecase.GetByIDs(context.Background(), make([]int, 101))
+	s.Assert().True(errors.Is(err, domain.ErrBadRequest))
+	s.readRepo.AssertNotCalled(s.T(), "GetByIDs", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterUsecaseTestSuite) TestSuccessFetchCharacters() {
+	batch := domain.CharacterBatch{
+		Characters: []domain.Character{{ID: 1, Name: "Lorem"}},
+		Missing:    []int{2},
+	}
+	s.readRepo.On("Fetch", mock.Anything, 2).Return([]int{1, 2}, nil).Once()
+	s.readRepo.On("GetByIDs", mock.Anything, []int{1, 2}).Return(batch, nil).Once()
+	s.writeRepo.On("StoreByPage", mock.Anything, 2).Return(nil).Once()
+	s.writeRepo.On("StoreByID", mock.Anything, 2).Return(nil).Once()
+
+	res, err := s.usecase.FetchCharacters(context.Background(), 2)
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal(domain.CharacterPage{Page: 2, CharacterBatch: batch, Partial: true}, res)
+}
+
+func (s *CharacterUsecaseTestSuite) TestFailedFetchCharacters() {
+	s.readRepo.On("Fetch", mock.Anything, 2).Return(nil, domain.ErrCacheKeyEmpty).Once()
+	s.writeRepo.On("StoreByPage", mock.Anything, 2).Return(nil).Once()
+
+	_, err := s.usecase.FetchCharacters(context.Background(), 2)
+	s.Assert().Equal(err, domain.ErrCacheKeyEmpty)
+	s.readRepo.AssertNotCalled(s.T(), "GetByIDs", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterUsecaseTestSuite) TestSuccessRangeCharacters() {
+	batch := domain.CharacterBatch{
+		Characters: []domain.Character{{ID: 1, Name: "Lorem"}},
+		Missing:    []int{2},
+	}
+	s.readRepo.On("Range", mock.Anything, domain.OrderByModified, 10, 2).Return([]int{1, 2}, nil).Once()
+	s.readRepo.On("GetByIDs", mock.Anything, []int{1, 2}).Return(batch, nil).Once()
+	s.writeRepo.On("StoreByID", mock.Anything, 2).Return(nil).Once()
+
+	res, err := s.usecase.RangeCharacters(context.Background(), domain.OrderByModified, 10, 2)
+	s.Assert().NoError(err)
+	s.Assert().Equal(batch, res)
+
+	s.readRepo.On("Range", mock.Anything, domain.OrderByModified, 20, 2).Return([]int{}, nil).Once()
+	res, err = s.usecase.RangeCharacters(context.Background(), domain.OrderByModified, 20, 2)
+	s.Assert().NoError(err)
+	s.Assert().Empty(res.Characters)
+	s.readRepo.AssertNumberOfCalls(s.T(), "GetByIDs", 1)
+	s.writeRepo.AssertNotCalled(s.T(), "StoreByPage", mock.Anything, mock.Anything)
+}
+
+func (s *CharacterUsecaseTestSuite) TestFailedRange() {
+	_, err := s.usecase.Range(context.Background(), domain.OrderByName, 0, 101)
+	s.Assert().True(errors.Is(err, domain.ErrBadRequest))
+	s.readRepo.AssertNotCalled(s.T(), "Range", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *CharacterUsecaseTestSuite) TestSuccessPopular() {
+	hits := []domain.Hit{{ID: 1011334, Hits: 42}, {ID: 1017100, Hits: 7}}
+	s.popularityRepo.On("Top", mock.Anything, cachekey.KindCharacter, 24*time.Hour, 10, int64(1)).Return(hits, nil).Once()
+
+	res, err := s.usecase.Popular(context.Background(), 24*time.Hour, 10)
+	s.Assert().NoError(err)
+	s.Assert().Equal(domain.PopularCharacters{Window: "24h0m0s", Characters: hits}, res)
+}
+
+func (s *CharacterUsecaseTestSuite) TestFailedPopular() {
+	_, err := s.usecase.Popular(context.Background(), 8*24*time.Hour, 10)
+	s.Assert().EqualError(err, "window must be a positive duration up to 168h0m0s")
+	s.Assert().True(errors.Is(err, domain.ErrBadRequest))
+
+	_, err = s.usecase.Popular(context.Background(), time.Hour, 101)
+	s.Assert().EqualError(err, "limit must be between 1 and 100")
+
+	s.popularityRepo.On("Top", mock.Anything, cachekey.KindCharacter, time.Hour, 10, int64(1)).Return(nil, domain.ErrInternalServerError).Once()
+	_, err = s.usecase.Popular(context.Background(), time.Hour, 10)
+	s.Assert().Equal(domain.ErrInternalServerError, err)
+
+	untracked := usecase.NewCharacterUsecase(s.readRepo, s.writeRepo, nil, s.pool, time.Second, logger.Discard())
+	_, err = untracked.Popular(context.Background(), time.Hour, 10)
+	s.Assert().True(errors.Is(err, domain.ErrNotFound))
+}
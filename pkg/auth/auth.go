@@ -0,0 +1,179 @@
+// Package auth authenticates the callers of the API.
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+// APIKeyHeader carries an API key, as an alternative to sending it as a
+// bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// Method authenticates requests one way. It returns errNoCredentials when
+// the request carries no credentials of its kind, so that the next method
+// gets a try.
+type Method func(c echo.Context) (Identity, error)
+
+var errNoCredentials = errors.New("no credentials")
+
+// limitedError is a rejection that lifts after retryAfter.
+type limitedError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *limitedError) Error() string { return e.err.Error() }
+
+func (e *limitedError) Unwrap() error { return e.err }
+
+func (e *limitedError) RetryAfter() time.Duration { return e.retryAfter }
+
+// Require only lets through requests authenticated by one of methods, tried
+// in order, whose identity holds scope. The identity is stored in the
+// request context, where handlers and logs find it.
+func Require(scope string, methods ...Method) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id, err := Authenticate(c, scope, methods...)
+			if err != nil {
+				return reject(c, err)
+			}
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(WithIdentity(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
+
+// Authenticate returns the identity of the first of methods, tried in order,
+// that finds credentials of its kind in the request of c. It fails with
+// domain.ErrUnauthorized when none does, and with domain.ErrForbidden when
+// the identity does not hold scope.
+func Authenticate(c echo.Context, scope string, methods ...Method) (Identity, error) {
+	for _, method := range methods {
+		id, err := method(c)
+		if errors.Is(err, errNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return Identity{}, err
+		}
+		if !id.HasScope(scope) {
+			return Identity{}, domain.ErrForbidden
+		}
+
+		return id, nil
+	}
+
+	return Identity{}, domain.ErrUnauthorized
+}
+
+// AdminToken only lets through requests bearing token in their
+// Authorization header.
+func AdminToken(token string) echo.MiddlewareFunc {
+	return Require(ScopeAdmin, AdminTokenMethod(token))
+}
+
+// APIKey only lets through requests bearing a valid API key, and counts them
+// against the limits of the key.
+func APIKey(u domain.APIKeyUsecase) echo.MiddlewareFunc {
+	return Require(ScopeCharactersRead, APIKeyMethod(u))
+}
+
+// AdminTokenMethod accepts the bearer token token, granting ScopeAdmin.
+// Both sides are hashed first so that the comparison does not leak the
+// length of the token.
+func AdminTokenMethod(token string) Method {
+	want := sha256.Sum256([]byte(token))
+
+	return func(c echo.Context) (Identity, error) {
+		got, ok := BearerToken(c.Request())
+		if !ok {
+			return Identity{}, errNoCredentials
+		}
+		sum := sha256.Sum256([]byte(got))
+		if subtle.ConstantTimeCompare(sum[:], want[:]) != 1 {
+			return Identity{}, domain.ErrUnauthorized
+		}
+
+		return Identity{Subject: "admin", Scopes: []string{ScopeAdmin}, Method: "admin_token"}, nil
+	}
+}
+
+// WithAdminToken appends AdminTokenMethod(token) to methods, unless token
+// is empty. It comes last since it rejects every other bearer token, JWTs
+// included.
+func WithAdminToken(token string, methods ...Method) []Method {
+	if token == "" {
+		return methods
+	}
+
+	return append(methods, AdminTokenMethod(token))
+}
+
+// APIKeyMethod accepts API keys sent in the X-API-Key header or as bearer
+// tokens, granting ScopeCharactersRead, and counts the request against the
+// limits of the key.
+func APIKeyMethod(u domain.APIKeyUsecase) Method {
+	return func(c echo.Context) (Identity, error) {
+		token := c.Request().Header.Get(APIKeyHeader)
+		if token == "" {
+			token, _ = BearerToken(c.Request())
+		}
+		if token == "" {
+			return Identity{}, errNoCredentials
+		}
+
+		ctx := c.Request().Context()
+		key, err := u.Authenticate(ctx, token)
+		if err != nil {
+			return Identity{}, err
+		}
+
+		usage, err := u.Consume(ctx, key)
+		if err != nil {
+			return Identity{}, &limitedError{err: err, retryAfter: usage.RetryAfter}
+		}
+
+		return Identity{Subject: key.ID, Scopes: []string{ScopeCharactersRead}, Method: "api_key"}, nil
+	}
+}
+
+// BearerToken returns the token of the Authorization header of req.
+func BearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[len("Bearer "):])
+	return token, token != ""
+}
+
+// Unauthorized rejects the request with a 401 problem asking for a bearer
+// token.
+func Unauthorized(c echo.Context) error {
+	c.Response().Header().Set("WWW-Authenticate", `Bearer realm="golang-marvel-demo"`)
+	return problem.Error(c, domain.ErrUnauthorized)
+}
+
+// reject writes the failure of a method. Limited requests carry a
+// Retry-After header telling when the limit lifts.
+func reject(c echo.Context, err error) error {
+	if errors.Is(err, domain.ErrUnauthorized) {
+		return Unauthorized(c)
+	}
+
+	return problem.Error(c, err)
+}
@@ -0,0 +1,104 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type AuthTestSuite struct {
+	suite.Suite
+	e       *echo.Echo
+	usecase *mocks.APIKeyUsecase
+}
+
+func TestAuth(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
+
+func (s *AuthTestSuite) SetupTest() {
+	s.e = echo.New()
+	s.e.GET("/admin", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, auth.AdminToken("s3cret"))
+
+	s.usecase = new(mocks.APIKeyUsecase)
+	s.e.GET("/characters", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, auth.APIKey(s.usecase))
+}
+
+func (s *AuthTestSuite) serve(authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(echo.GET, "/admin", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s *AuthTestSuite) TestSuccessAdminToken() {
+	s.Assert().Equal(http.StatusOK, s.serve("Bearer s3cret").Code)
+	s.Assert().Equal(http.StatusOK, s.serve("bearer s3cret").Code)
+}
+
+func (s *AuthTestSuite) TestFailedAdminToken() {
+	for _, authorization := range []string{"", "Bearer", "Bearer ", "Bearer s3cre", "Basic s3cret", "s3cret"} {
+		rec := s.serve(authorization)
+		s.Assert().Equal(http.StatusUnauthorized, rec.Code, authorization)
+		s.Assert().Equal(problem.ContentType, rec.Header().Get(echo.HeaderContentType))
+		s.Assert().Contains(rec.Header().Get("WWW-Authenticate"), "Bearer")
+		s.Assert().Contains(rec.Body.String(), `"code":"unauthorized"`)
+	}
+}
+
+func (s *AuthTestSuite) serveCharacters(header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(echo.GET, "/characters", nil)
+	if value != "" {
+		req.Header.Set(header, value)
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s *AuthTestSuite) TestSuccessAPIKey() {
+	key := domain.APIKey{ID: "abc"}
+	s.usecase.On("Authenticate", mock.Anything, "mk_abc.secret").Return(key, nil).Twice()
+	s.usecase.On("Consume", mock.Anything, key).Return(domain.APIKeyUsage{Minute: 1}, nil).Twice()
+
+	s.Assert().Equal(http.StatusOK, s.serveCharacters(auth.APIKeyHeader, "mk_abc.secret").Code)
+	s.Assert().Equal(http.StatusOK, s.serveCharacters("Authorization", "Bearer mk_abc.secret").Code)
+}
+
+func (s *AuthTestSuite) TestFailedAPIKey() {
+	s.usecase.On("Authenticate", mock.Anything, "mk_abc.wrong").Return(domain.APIKey{}, domain.ErrUnauthorized).Once()
+
+	s.Assert().Equal(http.StatusUnauthorized, s.serveCharacters(auth.APIKeyHeader, "").Code)
+	s.Assert().Equal(http.StatusUnauthorized, s.serveCharacters(auth.APIKeyHeader, "mk_abc.wrong").Code)
+	s.usecase.AssertNotCalled(s.T(), "Consume", mock.Anything, mock.Anything)
+}
+
+func (s *AuthTestSuite) TestFailedAPIKeyRateLimited() {
+	key := domain.APIKey{ID: "abc"}
+	s.usecase.On("Authenticate", mock.Anything, "mk_abc.secret").Return(key, nil).Once()
+	s.usecase.On("Consume", mock.Anything, key).Return(domain.APIKeyUsage{Minute: 61, RetryAfter: 1500 * time.Millisecond}, domain.ErrRateLimited).Once()
+
+	rec := s.serveCharacters(auth.APIKeyHeader, "mk_abc.secret")
+	s.Assert().Equal(http.StatusTooManyRequests, rec.Code)
+	s.Assert().Equal("2", rec.Header().Get("Retry-After"))
+	s.Assert().Contains(rec.Body.String(), `"code":"rate_limited"`)
+}
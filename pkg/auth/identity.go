@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+// Scopes granted to callers. Characters are read with ScopeCharactersRead,
+// the admin endpoints need ScopeAdmin.
+const (
+	ScopeCharactersRead = "characters:read"
+	ScopeAdmin          = "admin"
+)
+
+// Identity is who made a request. Method tells how it was proven: "jwt",
+// "api_key" or "admin_token".
+type Identity struct {
+	Subject string
+	Scopes  []string
+	Method  string
+}
+
+func (i Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type ctxKey int
+
+const identityKey ctxKey = iota
+
+// WithIdentity returns a context holding id, whose records are logged with
+// the subject and the method of id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	ctx = logger.WithAttrs(ctx, slog.String("subject", id.Subject), slog.String("auth", id.Method))
+	return context.WithValue(ctx, identityKey, id)
+}
+
+// IdentityFrom returns the identity of the authenticated request of ctx.
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey).(Identity)
+	return id, ok
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/labstack/echo"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+)
+
+// Only asymmetric algorithms are accepted, the keys of a JWKS are public.
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+const (
+	// jwtLeeway absorbs clock skew with the issuer.
+	jwtLeeway = time.Minute
+	// maxKeySetAge is how long a fetched JWKS is trusted, so that rotated
+	// keys eventually stop verifying tokens.
+	maxKeySetAge = time.Hour
+	// minKeySetRefresh spaces out the fetches made for unknown key IDs, so
+	// that tokens with made-up key IDs cannot flood the issuer.
+	minKeySetRefresh = time.Minute
+)
+
+// KeySet returns the keys that may have signed a token whose key ID is kid.
+// Tokens without a key ID may have been signed by any key.
+type KeySet interface {
+	Keys(ctx context.Context, kid string) ([]jose.JSONWebKey, error)
+}
+
+type staticKeySet struct {
+	set jose.JSONWebKeySet
+}
+
+// StaticKeys verifies tokens with keys, for tests and fixed deployments.
+func StaticKeys(keys ...jose.JSONWebKey) KeySet {
+	return &staticKeySet{set: jose.JSONWebKeySet{Keys: keys}}
+}
+
+// LoadKeySet reads the JWKS at path once.
+func LoadKeySet(path string) (KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parsing JWKS %s: %w", path, err)
+	}
+
+	return &staticKeySet{set: set}, nil
+}
+
+func (s *staticKeySet) Keys(_ context.Context, kid string) ([]jose.JSONWebKey, error) {
+	return keysOf(s.set, kid), nil
+}
+
+// RemoteKeySet fetches the JWKS of an issuer on first use, again once it is
+// older than maxKeySetAge, and when a token names a key it does not hold.
+type RemoteKeySet struct {
+	url       string
+	client    *http.Client
+	mu        sync.Mutex
+	set       jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// NewRemoteKeySet reads the JWKS at url with client. A nil client uses
+// http.DefaultClient.
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &RemoteKeySet{url: url, client: client}
+}
+
+func (r *RemoteKeySet) Keys(ctx context.Context, kid string) ([]jose.JSONWebKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	age := time.Since(r.fetchedAt)
+	keys := keysOf(r.set, kid)
+	if age < maxKeySetAge && (len(keys) > 0 || age < minKeySetRefresh) {
+		return keys, nil
+	}
+
+	if err := r.fetch(ctx); err != nil {
+		// A stale set still verifies tokens while the issuer is down.
+		if len(keys) > 0 {
+			return keys, nil
+		}
+		return nil, err
+	}
+
+	return keysOf(r.set, kid), nil
+}
+
+func (r *RemoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching JWKS %s: status %d", r.url, res.StatusCode)
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return fmt.Errorf("parsing JWKS %s: %w", r.url, err)
+	}
+	r.set = set
+	r.fetchedAt = time.Now()
+
+	return nil
+}
+
+func keysOf(set jose.JSONWebKeySet, kid string) []jose.JSONWebKey {
+	if kid == "" {
+		return set.Keys
+	}
+
+	return set.Key(kid)
+}
+
+// JWTVerifier checks bearer JWTs. Issuer and audience, when set, must match
+// the iss and aud claims.
+type JWTVerifier struct {
+	keys     KeySet
+	issuer   string
+	audience string
+}
+
+func NewJWTVerifier(keys KeySet, issuer, audience string) *JWTVerifier {
+	return &JWTVerifier{keys: keys, issuer: issuer, audience: audience}
+}
+
+// scopeClaims holds the scopes of a token, either in the OAuth 2.0 scope
+// claim, space separated, or in a scp claim, as a string or a list.
+type scopeClaims struct {
+	Scope string          `json:"scope"`
+	Scp   json.RawMessage `json:"scp"`
+}
+
+func (s scopeClaims) scopes() []string {
+	scopes := strings.Fields(s.Scope)
+
+	var list []string
+	var single string
+	switch {
+	case json.Unmarshal(s.Scp, &list) == nil:
+		scopes = append(scopes, list...)
+	case json.Unmarshal(s.Scp, &single) == nil:
+		scopes = append(scopes, strings.Fields(single)...)
+	}
+
+	return scopes
+}
+
+// Verify returns the identity of the valid token raw. Any failure is
+// ErrUnauthorized, wrapping the reason.
+func (v *JWTVerifier) Verify(ctx context.Context, raw string) (Identity, error) {
+	token, err := jwt.ParseSigned(raw, signatureAlgorithms)
+	if err != nil {
+		return Identity{}, domain.ErrUnauthorized.Wrap(err)
+	}
+
+	keys, err := v.keys.Keys(ctx, token.Headers[0].KeyID)
+	if err != nil {
+		return Identity{}, domain.ErrUnauthorized.Wrap(err)
+	}
+
+	var claims jwt.Claims
+	var extra scopeClaims
+	verified := false
+	for _, key := range keys {
+		if token.Claims(key.Key, &claims, &extra) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return Identity{}, domain.ErrUnauthorized.Wrap(errors.New("no key verifies the token"))
+	}
+
+	expected := jwt.Expected{Issuer: v.issuer, Time: time.Now()}
+	if v.audience != "" {
+		expected.AnyAudience = jwt.Audience{v.audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, jwtLeeway); err != nil {
+		return Identity{}, domain.ErrUnauthorized.Wrap(err)
+	}
+	if claims.Expiry == nil {
+		return Identity{}, domain.ErrUnauthorized.Wrap(errors.New("token does not expire"))
+	}
+
+	return Identity{Subject: claims.Subject, Scopes: extra.scopes(), Method: "jwt"}, nil
+}
+
+// JWTMethod accepts bearer JWTs verified by v, granting the scopes of the
+// token. Bearer tokens that are not JWTs are left to the other methods.
+func JWTMethod(v *JWTVerifier) Method {
+	return func(c echo.Context) (Identity, error) {
+		raw, ok := BearerToken(c.Request())
+		if !ok || strings.Count(raw, ".") != 2 {
+			return Identity{}, errNoCredentials
+		}
+
+		return v.Verify(c.Request().Context(), raw)
+	}
+}
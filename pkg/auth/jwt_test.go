@@ -0,0 +1,183 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type JWTTestSuite struct {
+	suite.Suite
+	key      *ecdsa.PrivateKey
+	public   jose.JSONWebKey
+	verifier *auth.JWTVerifier
+}
+
+func TestJWT(t *testing.T) {
+	suite.Run(t, new(JWTTestSuite))
+}
+
+func (s *JWTTestSuite) SetupTest() {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	s.key = key
+	s.public = jose.JSONWebKey{Key: &key.PublicKey, KeyID: "k1", Algorithm: string(jose.ES256), Use: "sig"}
+	s.verifier = auth.NewJWTVerifier(auth.StaticKeys(s.public), "https://sso.example.com", "marvel")
+}
+
+type tokenClaims struct {
+	jwt.Claims
+	Scope string `json:"scope,omitempty"`
+}
+
+func (s *JWTTestSuite) sign(kid string, claims tokenClaims) string {
+	opts := (&jose.SignerOptions{}).WithType("JWT")
+	if kid != "" {
+		opts = opts.WithHeader("kid", kid)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: s.key}, opts)
+	s.Require().NoError(err)
+
+	raw, err := jwt.Signed(signer).Claims(claims).Serialize()
+	s.Require().NoError(err)
+	return raw
+}
+
+func (s *JWTTestSuite) claims(scope string) tokenClaims {
+	return tokenClaims{
+		Claims: jwt.Claims{
+			Issuer:   "https://sso.example.com",
+			Subject:  "jane",
+			Audience: jwt.Audience{"marvel"},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Scope: scope,
+	}
+}
+
+func (s *JWTTestSuite) TestSuccessVerify() {
+	id, err := s.verifier.Verify(context.Background(), s.sign("k1", s.claims("characters:read admin")))
+	s.Require().NoError(err)
+	s.Assert().Equal("jane", id.Subject)
+	s.Assert().Equal("jwt", id.Method)
+	s.Assert().True(id.HasScope(auth.ScopeCharactersRead))
+	s.Assert().True(id.HasScope(auth.ScopeAdmin))
+
+	_, err = s.verifier.Verify(context.Background(), s.sign("", s.claims("")))
+	s.Assert().NoError(err)
+}
+
+func (s *JWTTestSuite) TestFailedVerify() {
+	expired := s.claims("")
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	otherIssuer := s.claims("")
+	otherIssuer.Issuer = "https://evil.example.com"
+	otherAudience := s.claims("")
+	otherAudience.Audience = jwt.Audience{"other"}
+	neverExpires := s.claims("")
+	neverExpires.Expiry = nil
+
+	for name, raw := range map[string]string{
+		"expired":       s.sign("k1", expired),
+		"issuer":        s.sign("k1", otherIssuer),
+		"audience":      s.sign("k1", otherAudience),
+		"never expires": s.sign("k1", neverExpires),
+		"unknown key":   s.sign("k2", s.claims("")),
+		"malformed":     "lorem.ipsum.dolor",
+		"tampered":      s.sign("k1", s.claims("")) + "x",
+	} {
+		_, err := s.verifier.Verify(context.Background(), raw)
+		s.Assert().ErrorIs(err, domain.ErrUnauthorized, name)
+	}
+}
+
+func (s *JWTTestSuite) TestSuccessLoadKeySet() {
+	data, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{s.public}})
+	s.Require().NoError(err)
+	path := filepath.Join(s.T().TempDir(), "jwks.json")
+	s.Require().NoError(os.WriteFile(path, data, 0o600))
+
+	keys, err := auth.LoadKeySet(path)
+	s.Require().NoError(err)
+
+	_, err = auth.NewJWTVerifier(keys, "", "").Verify(context.Background(), s.sign("k1", s.claims("")))
+	s.Assert().NoError(err)
+}
+
+func (s *JWTTestSuite) TestSuccessRemoteKeySet() {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{s.public}})
+	}))
+	defer server.Close()
+
+	verifier := auth.NewJWTVerifier(auth.NewRemoteKeySet(server.URL, nil), "", "")
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(context.Background(), s.sign("k1", s.claims("")))
+		s.Require().NoError(err)
+	}
+	s.Assert().Equal(1, fetches)
+
+	// Unknown keys only trigger a refetch once the set is a minute old.
+	_, err := verifier.Verify(context.Background(), s.sign("k2", s.claims("")))
+	s.Assert().ErrorIs(err, domain.ErrUnauthorized)
+	s.Assert().Equal(1, fetches)
+}
+
+func (s *JWTTestSuite) TestRequireScope() {
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.GET("/admin", func(c echo.Context) error {
+		id, _ := auth.IdentityFrom(c.Request().Context())
+		return c.String(http.StatusOK, id.Subject)
+	}, auth.Require(auth.ScopeAdmin, auth.WithAdminToken("s3cret", auth.JWTMethod(s.verifier))...))
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(s.sign("k1", s.claims("admin")))
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("jane", rec.Body.String())
+
+	rec = serve(s.sign("k1", s.claims("characters:read")))
+	s.Assert().Equal(http.StatusForbidden, rec.Code)
+	s.Assert().Contains(rec.Body.String(), `"code":"forbidden"`)
+
+	rec = serve("s3cret")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("admin", rec.Body.String())
+
+	rec = serve(s.sign("k2", s.claims("admin")))
+	s.Assert().Equal(http.StatusUnauthorized, rec.Code)
+
+	rec = serve("lorem")
+	s.Assert().Equal(http.StatusUnauthorized, rec.Code)
+}
+
+func (s *JWTTestSuite) TestWithAdminToken() {
+	s.Assert().Len(auth.WithAdminToken("", auth.JWTMethod(s.verifier)), 1)
+	s.Assert().Len(auth.WithAdminToken("s3cret", auth.JWTMethod(s.verifier)), 2)
+}
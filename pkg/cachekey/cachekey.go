@@ -0,0 +1,221 @@
+// Package cachekey lays out the Redis keys of the cached characters and
+// pages, and of the API keys and rate limits stored next to them, so that
+// every reader and writer agrees on them.
+package cachekey
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the schema version of the cached values, part of their keys.
+// Bump it when the JSON of domain.Character or of pages changes, and teach
+// `marvelctl cache migrate` to upgrade the values of the older version.
+// Entries of older versions are not read anymore: they are fetched again
+// from Marvel API on their next lookup, unless migrated first.
+const Version = 2
+
+// DefaultPrefix starts every key unless configured otherwise.
+const DefaultPrefix = "marvel-"
+
+// Kinds of cached values. Indexes are the sorted sets of the hash layout,
+// popularity the sorted sets counting lookups.
+const (
+	KindCharacter  = "character"
+	KindPage       = "page"
+	KindIndex      = "index"
+	KindPopularity = "popularity"
+)
+
+// Layout is how the cached values are stored in Redis.
+type Layout string
+
+const (
+	// LayoutString stores characters and the IDs of pages as encoded values.
+	LayoutString Layout = "string"
+	// LayoutHash stores characters as hashes and orders their IDs in sorted
+	// sets by name and by modification date. Pages are ranges of the former,
+	// their keys only marking them as fetched.
+	LayoutHash Layout = "hash"
+)
+
+func ParseLayout(name string) (Layout, error) {
+	switch layout := Layout(name); layout {
+	case LayoutString, LayoutHash:
+		return layout, nil
+	}
+
+	return "", fmt.Errorf("unknown layout %q, want string or hash", name)
+}
+
+// Builder lays out the keys of a schema version under a prefix, which keeps
+// them apart from other applications or environments sharing the Redis.
+type Builder struct {
+	prefix  string
+	version int
+	layout  Layout
+}
+
+// New lays out the keys of the current Version under prefix.
+func New(prefix string) Builder {
+	return Builder{prefix: prefix, version: Version, layout: LayoutString}
+}
+
+// WithVersion lays out the keys of version instead, to find entries written
+// by older releases.
+func (b Builder) WithVersion(version int) Builder {
+	b.version = version
+	return b
+}
+
+// Prefix starts the keys of every version.
+func (b Builder) Prefix() string {
+	return b.prefix
+}
+
+func (b Builder) Version() int {
+	return b.version
+}
+
+// WithLayout lays out the keys of layout instead. The keys of the hash
+// layout have a part of their own, so that switching layouts never reads a
+// value of the other one.
+func (b Builder) WithLayout(layout Layout) Builder {
+	b.layout = layout
+	return b
+}
+
+func (b Builder) Layout() Layout {
+	return b.layout
+}
+
+// base starts the keys of the version and layout. Version 1 predates
+// versioned keys.
+func (b Builder) base() string {
+	if b.version <= 1 {
+		return b.prefix
+	}
+
+	base := b.prefix + "v" + strconv.Itoa(b.version) + "-"
+	if b.layout == LayoutHash {
+		base += "hash-"
+	}
+
+	return base
+}
+
+func (b Builder) CharacterPrefix() string {
+	return b.base() + "character-id-"
+}
+
+func (b Builder) PagePrefix() string {
+	return b.base() + "characters-page-"
+}
+
+func (b Builder) Character(id int) string {
+	return b.CharacterPrefix() + strconv.Itoa(id)
+}
+
+func (b Builder) Page(page int) string {
+	return b.PagePrefix() + strconv.Itoa(page)
+}
+
+// ByName is the sorted set of the character IDs of the hash layout, scored
+// by their position in the listing of Marvel API, which is by name.
+func (b Builder) ByName() string {
+	return b.base() + "characters-by-name"
+}
+
+// ByModified is the sorted set of the character IDs of the hash layout,
+// scored by when Marvel API last modified them, in Unix seconds.
+func (b Builder) ByModified() string {
+	return b.base() + "characters-by-modified"
+}
+
+// PopularityPrefix starts the keys counting lookups.
+func (b Builder) PopularityPrefix() string {
+	return b.base() + "popular-"
+}
+
+// Popularity is the sorted set counting the lookups of the characters or
+// pages of kind during the bucket starting at start, scored by lookups.
+func (b Builder) Popularity(kind string, start time.Time) string {
+	return b.PopularityPrefix() + kind + "-" + strconv.FormatInt(start.Unix(), 10)
+}
+
+// APIKeyPrefix starts the keys of the API keys and their usage. Like the
+// rate limit buckets, they are under the prefix, to keep environments apart,
+// but outside of every version and layout, since they are not cache: schema
+// bumps must not lose them and purges leave them alone.
+func (b Builder) APIKeyPrefix() string {
+	return b.prefix + "auth-apikey-"
+}
+
+// APIKey stores the API key id.
+func (b Builder) APIKey(id string) string {
+	return b.APIKeyPrefix() + "id-" + id
+}
+
+// APIKeyIndex is the set of the IDs of every API key.
+func (b Builder) APIKeyIndex() string {
+	return b.prefix + "auth-apikeys"
+}
+
+// APIKeyUsage starts the keys counting the requests of the API key id over
+// period: "total", or "minute" and "day" followed by the one counted.
+func (b Builder) APIKeyUsage(period, id string) string {
+	return b.APIKeyPrefix() + period + "-" + id
+}
+
+// RateLimit is the token bucket of the rate limit key.
+func (b Builder) RateLimit(key string) string {
+	return b.RateLimitPrefix() + key
+}
+
+func (b Builder) RateLimitPrefix() string {
+	return b.prefix + "ratelimit-"
+}
+
+// Reserved tells whether key holds API keys or rate limits rather than
+// cached values, which purges must leave alone whatever their pattern.
+func (b Builder) Reserved(key string) bool {
+	return key == b.APIKeyIndex() || strings.HasPrefix(key, b.APIKeyPrefix()) || strings.HasPrefix(key, b.RateLimitPrefix())
+}
+
+// Parse tells the kind and the character ID or page number of key. ok is
+// false for keys of other versions or outside the cache. Indexes and
+// popularity counts have no ID.
+func (b Builder) Parse(key string) (kind string, id int, ok bool) {
+	if key == b.ByName() || key == b.ByModified() {
+		return KindIndex, 0, true
+	}
+	if strings.HasPrefix(key, b.PopularityPrefix()) {
+		return KindPopularity, 0, true
+	}
+
+	var rest string
+	switch {
+	case strings.HasPrefix(key, b.CharacterPrefix()):
+		kind, rest = KindCharacter, strings.TrimPrefix(key, b.CharacterPrefix())
+	case strings.HasPrefix(key, b.PagePrefix()):
+		kind, rest = KindPage, strings.TrimPrefix(key, b.PagePrefix())
+	default:
+		return "", 0, false
+	}
+
+	id, err := strconv.Atoi(rest)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return kind, id, true
+}
+
+// Kind tells whether key caches a character, a page, an index or popularity
+// counts of the version, and is empty otherwise.
+func (b Builder) Kind(key string) string {
+	kind, _, _ := b.Parse(key)
+	return kind
+}
@@ -0,0 +1,102 @@
+package cachekey_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+)
+
+type CacheKeyTestSuite struct {
+	suite.Suite
+}
+
+func TestCacheKey(t *testing.T) {
+	suite.Run(t, new(CacheKeyTestSuite))
+}
+
+func (s *CacheKeyTestSuite) TestSuccessLayout() {
+	keys := cachekey.New("staging-")
+	s.Assert().Equal("staging-", keys.Prefix())
+	s.Assert().Equal(cachekey.Version, keys.Version())
+	s.Assert().Equal("staging-v2-character-id-1011334", keys.Character(1011334))
+	s.Assert().Equal("staging-v2-characters-page-3", keys.Page(3))
+}
+
+func (s *CacheKeyTestSuite) TestSuccessLegacyLayout() {
+	keys := cachekey.New(cachekey.DefaultPrefix).WithVersion(1)
+	s.Assert().Equal("marvel-character-id-1011334", keys.Character(1011334))
+	s.Assert().Equal("marvel-characters-page-3", keys.Page(3))
+}
+
+func (s *CacheKeyTestSuite) TestParse() {
+	keys := cachekey.New(cachekey.DefaultPrefix)
+
+	kind, id, ok := keys.Parse("marvel-v2-character-id-1011334")
+	s.Assert().True(ok)
+	s.Assert().Equal(cachekey.KindCharacter, kind)
+	s.Assert().Equal(1011334, id)
+
+	kind, id, ok = keys.Parse("marvel-v2-characters-page-3")
+	s.Assert().True(ok)
+	s.Assert().Equal(cachekey.KindPage, kind)
+	s.Assert().Equal(3, id)
+
+	for _, key := range []string{"marvel-character-id-1", "staging-v2-character-id-1", "marvel-v2-character-id-lorem", "marvel-ratelimit-global"} {
+		_, _, ok = keys.Parse(key)
+		s.Assert().False(ok, key)
+		s.Assert().Empty(keys.Kind(key), key)
+	}
+}
+
+func (s *CacheKeyTestSuite) TestSuccessHashLayout() {
+	keys := cachekey.New(cachekey.DefaultPrefix).WithLayout(cachekey.LayoutHash)
+	s.Assert().Equal(cachekey.LayoutHash, keys.Layout())
+	s.Assert().Equal("marvel-v2-hash-character-id-1011334", keys.Character(1011334))
+	s.Assert().Equal("marvel-v2-hash-characters-page-3", keys.Page(3))
+	s.Assert().Equal("marvel-v2-hash-characters-by-name", keys.ByName())
+	s.Assert().Equal("marvel-v2-hash-characters-by-modified", keys.ByModified())
+
+	s.Assert().Equal(cachekey.KindIndex, keys.Kind(keys.ByName()))
+	s.Assert().Equal(cachekey.KindCharacter, keys.Kind("marvel-v2-hash-character-id-1"))
+	s.Assert().Empty(keys.Kind("marvel-v2-character-id-1"))
+	s.Assert().Empty(cachekey.New(cachekey.DefaultPrefix).Kind("marvel-v2-hash-character-id-1"))
+}
+
+func (s *CacheKeyTestSuite) TestSuccessPopularity() {
+	keys := cachekey.New(cachekey.DefaultPrefix)
+	key := keys.Popularity(cachekey.KindCharacter, time.Unix(1700000000, 0))
+	s.Assert().Equal("marvel-v2-popular-character-1700000000", key)
+
+	kind, id, ok := keys.Parse(key)
+	s.Assert().True(ok)
+	s.Assert().Equal(cachekey.KindPopularity, kind)
+	s.Assert().Equal(0, id)
+}
+
+func (s *CacheKeyTestSuite) TestSuccessReserved() {
+	keys := cachekey.New("staging-").WithLayout(cachekey.LayoutHash).WithVersion(3)
+	s.Assert().Equal("staging-auth-apikey-id-lorem", keys.APIKey("lorem"))
+	s.Assert().Equal("staging-auth-apikeys", keys.APIKeyIndex())
+	s.Assert().Equal("staging-auth-apikey-total-lorem", keys.APIKeyUsage("total", "lorem"))
+	s.Assert().Equal("staging-ratelimit-global", keys.RateLimit("global"))
+
+	for _, key := range []string{keys.APIKey("lorem"), keys.APIKeyIndex(), keys.APIKeyUsage("day", "lorem") + "-2021-07-21", keys.RateLimit("global")} {
+		s.Assert().True(keys.Reserved(key), key)
+		s.Assert().Empty(keys.Kind(key), key)
+	}
+	for _, key := range []string{keys.Character(1), keys.ByName(), "auth-apikeys", "marvel-ratelimit-global"} {
+		s.Assert().False(keys.Reserved(key), key)
+	}
+}
+
+func (s *CacheKeyTestSuite) TestParseLayout() {
+	layout, err := cachekey.ParseLayout("hash")
+	s.Assert().NoError(err)
+	s.Assert().Equal(cachekey.LayoutHash, layout)
+
+	_, err = cachekey.ParseLayout("list")
+	s.Assert().EqualError(err, `unknown layout "list", want string or hash`)
+}
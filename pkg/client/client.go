@@ -0,0 +1,141 @@
+// Package client calls the HTTP API of the service. Failures come back as
+// domain errors rebuilt from the problem responses, so that callers handle
+// them like the ones of the usecase.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+// Client calls the HTTP API. APIKey, when set, is sent with every request,
+// for servers requiring API keys.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	APIKey     string
+}
+
+// New returns a client of the server at baseURL, such as
+// http://localhost:8080. A nil httpClient uses http.DefaultClient.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: httpClient,
+	}
+}
+
+// Fetch returns the IDs of the characters of page.
+func (c *Client) Fetch(ctx context.Context, page int) ([]int, error) {
+	var ids []int
+	err := c.get(ctx, "/characters", url.Values{"page": {strconv.Itoa(page)}}, &ids)
+
+	return ids, err
+}
+
+// FetchCharacters returns the characters of page.
+func (c *Client) FetchCharacters(ctx context.Context, page int) (domain.CharacterPage, error) {
+	var characters domain.CharacterPage
+	err := c.get(ctx, "/characters", url.Values{"page": {strconv.Itoa(page)}, "expand": {"characters"}}, &characters)
+
+	return characters, err
+}
+
+func (c *Client) GetByID(ctx context.Context, id int) (domain.Character, error) {
+	var character domain.Character
+	err := c.get(ctx, "/characters/"+strconv.Itoa(id), nil, &character)
+
+	return character, err
+}
+
+// Range returns the IDs of limit characters by order from offset on.
+func (c *Client) Range(ctx context.Context, order string, offset, limit int) ([]int, error) {
+	var ids []int
+	err := c.get(ctx, "/characters", url.Values{"order": {order}, "offset": {strconv.Itoa(offset)}, "limit": {strconv.Itoa(limit)}}, &ids)
+
+	return ids, err
+}
+
+// GetByIDs looks up to 100 characters up with POST /characters:batchGet.
+func (c *Client) GetByIDs(ctx context.Context, ids []int) (domain.CharacterBatch, error) {
+	var batch domain.CharacterBatch
+
+	body, err := json.Marshal(map[string][]int{"ids": ids})
+	if err != nil {
+		return batch, domain.ErrInternalServerError.Wrap(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/characters:batchGet", bytes.NewReader(body))
+	if err != nil {
+		return batch, domain.ErrInternalServerError.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	err = c.do(req, &batch)
+	return batch, err
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return domain.ErrInternalServerError.Wrap(err)
+	}
+
+	return c.do(req, out)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set(auth.APIKeyHeader, c.APIKey)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return domain.ErrInternalServerError.Wrap(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errorOf(res)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return domain.ErrInternalServerError.Wrap(err)
+	}
+
+	return nil
+}
+
+// errorOf rebuilds the domain error of a problem response. Other failed
+// responses are internal errors.
+func errorOf(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+
+	var p problem.Problem
+	if strings.HasPrefix(res.Header.Get("Content-Type"), problem.ContentType) && json.Unmarshal(body, &p) == nil && p.Code != "" {
+		return domain.NewError(domain.ErrorCode(p.Code), p.Detail)
+	}
+
+	return domain.ErrInternalServerError.Wrap(fmt.Errorf("unexpected response %s", res.Status))
+}
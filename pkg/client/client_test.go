@@ -0,0 +1,141 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	characterHttp "github.com/hezbymuhammad/golang-marvel-demo/model/character/delivery/http"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/client"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+// ClientTestSuite runs the client against the real HTTP delivery, so that
+// both sides agree on the wire format.
+type ClientTestSuite struct {
+	suite.Suite
+	server  *httptest.Server
+	client  *client.Client
+	usecase *mocks.CharacterUsecase
+}
+
+func TestClient(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
+
+func (s *ClientTestSuite) SetupTest() {
+	s.usecase = new(mocks.CharacterUsecase)
+
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	characterHttp.NewCharacterHandler(e, s.usecase, logger.Discard())
+	s.server = httptest.NewServer(e)
+	s.client = client.New(s.server.URL+"/", nil)
+}
+
+func (s *ClientTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *ClientTestSuite) TestSuccessFetch() {
+	s.usecase.On("Fetch", mock.Anything, 2).Return([]int{1, 2}, nil).Once()
+
+	ids, err := s.client.Fetch(context.Background(), 2)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1, 2}, ids)
+}
+
+func (s *ClientTestSuite) TestSuccessRange() {
+	s.usecase.On("Range", mock.Anything, domain.OrderByModified, 10, 5).Return([]int{3, 1}, nil).Once()
+
+	ids, err := s.client.Range(context.Background(), domain.OrderByModified, 10, 5)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{3, 1}, ids)
+}
+
+func (s *ClientTestSuite) TestSuccessFetchCharacters() {
+	page := domain.CharacterPage{
+		Page:           2,
+		CharacterBatch: domain.CharacterBatch{Characters: []domain.Character{{ID: 1, Name: "Lorem"}}, Missing: []int{2}},
+		Partial:        true,
+	}
+	s.usecase.On("FetchCharacters", mock.Anything, 2).Return(page, nil).Once()
+
+	res, err := s.client.FetchCharacters(context.Background(), 2)
+	s.Require().NoError(err)
+	s.Assert().Equal(page, res)
+}
+
+func (s *ClientTestSuite) TestSuccessGetByID() {
+	s.usecase.On("GetByID", mock.Anything, 1).Return(domain.Character{ID: 1, Name: "Lorem"}, nil).Once()
+
+	character, err := s.client.GetByID(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Assert().Equal(domain.Character{ID: 1, Name: "Lorem"}, character)
+}
+
+func (s *ClientTestSuite) TestSuccessGetByIDs() {
+	batch := domain.CharacterBatch{Characters: []domain.Character{{ID: 1}}, Missing: []int{2}}
+	s.usecase.On("GetByIDs", mock.Anything, []int{1, 2}).Return(batch, nil).Once()
+
+	res, err := s.client.GetByIDs(context.Background(), []int{1, 2})
+	s.Require().NoError(err)
+	s.Assert().Equal(batch, res)
+}
+
+func (s *ClientTestSuite) TestFailedGetByID() {
+	s.usecase.On("GetByID", mock.Anything, 1).Return(domain.Character{}, domain.ErrCacheKeyEmpty).Once()
+	s.usecase.On("GetByID", mock.Anything, 2).Return(domain.Character{}, domain.ErrNotFound).Once()
+
+	_, err := s.client.GetByID(context.Background(), 1)
+	s.Assert().True(errors.Is(err, domain.ErrCacheKeyEmpty))
+	s.Assert().Equal("Resource not cached yet", err.Error())
+
+	_, err = s.client.GetByID(context.Background(), 2)
+	s.Assert().True(errors.Is(err, domain.ErrNotFound))
+}
+
+func (s *ClientTestSuite) TestFailedUnexpectedResponse() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "bad gateway"})
+	}))
+	defer server.Close()
+
+	_, err := client.New(server.URL, nil).Fetch(context.Background(), 1)
+	s.Assert().Equal(domain.CodeInternal, domain.CodeOf(err))
+	s.Assert().Contains(err.Error(), "502")
+}
+
+func (s *ClientTestSuite) TestSuccessAPIKey() {
+	keys := new(mocks.APIKeyUsecase)
+	key := domain.APIKey{ID: "abc"}
+	keys.On("Authenticate", mock.Anything, "mk_abc.secret").Return(key, nil).Once()
+	keys.On("Consume", mock.Anything, key).Return(domain.APIKeyUsage{}, nil).Once()
+	s.usecase.On("Fetch", mock.Anything, 1).Return([]int{1}, nil).Once()
+
+	e := echo.New()
+	characterHttp.NewCharacterHandler(e, s.usecase, logger.Discard(), auth.APIKey(keys))
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	c := client.New(server.URL, nil)
+	_, err := c.Fetch(context.Background(), 1)
+	s.Assert().True(errors.Is(err, domain.ErrUnauthorized))
+
+	c.APIKey = "mk_abc.secret"
+	ids, err := c.Fetch(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Assert().Equal([]int{1}, ids)
+}
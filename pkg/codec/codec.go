@@ -0,0 +1,186 @@
+// Package codec encodes the cached values. Encoded values start with a
+// header byte naming their format and compression, so that values written
+// with other settings stay readable and entries of every kind can coexist.
+// The characters of the hash layout are hashes instead, laid out by
+// EncodeHash.
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Format is how a value is marshaled, in the high nibble of the header.
+// 5 and 7 are never used: headers starting with them would be the '[' and
+// '{' of header-less JSON.
+type Format byte
+
+const (
+	JSON Format = iota + 1
+	MsgPack
+	Protobuf
+)
+
+// Compression is how a marshaled value is compressed, in the low nibble of
+// the header.
+type Compression byte
+
+const (
+	None Compression = iota
+	Zstd
+	Snappy
+)
+
+var formatNames = map[Format]string{JSON: "json", MsgPack: "msgpack", Protobuf: "protobuf"}
+
+var compressionNames = map[Compression]string{None: "none", Zstd: "zstd", Snappy: "snappy"}
+
+// Marshaler marshals the cached values: domain.Character and the []int of
+// pages, decoded into *domain.Character and *[]int.
+type Marshaler interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var marshalers = map[Format]Marshaler{
+	JSON:     jsonMarshaler{},
+	MsgPack:  msgpackMarshaler{},
+	Protobuf: protobufMarshaler{},
+}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+func (c Compression) String() string {
+	return compressionNames[c]
+}
+
+func ParseFormat(name string) (Format, error) {
+	for f, n := range formatNames {
+		if n == name {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown format %q, want json, msgpack or protobuf", name)
+}
+
+func ParseCompression(name string) (Compression, error) {
+	for c, n := range compressionNames {
+		if n == name {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown compression %q, want none, zstd or snappy", name)
+}
+
+// Encoder encodes values in its format, compressing those marshaled to at
+// least threshold bytes. Uncompressed JSON is written without header, as by
+// releases before codecs, so that they can still read it.
+type Encoder struct {
+	format      Format
+	compression Compression
+	threshold   int
+}
+
+func NewEncoder(format Format, compression Compression, threshold int) *Encoder {
+	return &Encoder{format: format, compression: compression, threshold: threshold}
+}
+
+// New is NewEncoder with the format and compression given by name.
+func New(format, compression string, threshold int) (*Encoder, error) {
+	f, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseCompression(compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEncoder(f, c, threshold), nil
+}
+
+func (e *Encoder) Encode(v interface{}) ([]byte, error) {
+	data, err := marshalers[e.format].Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	compression := None
+	if e.compression != None && len(data) >= e.threshold {
+		compression = e.compression
+		if data, err = compress(compression, data); err != nil {
+			return nil, err
+		}
+	}
+	if e.format == JSON && compression == None {
+		return data, nil
+	}
+
+	out := make([]byte, 1+len(data))
+	out[0] = byte(e.format)<<4 | byte(compression)
+	copy(out[1:], data)
+
+	return out, nil
+}
+
+// Decode decodes data written by any Encoder into v.
+func Decode(data []byte, v interface{}) error {
+	format, compression, err := header(data)
+	if err != nil {
+		return err
+	}
+	if !hasHeader(data) {
+		return json.Unmarshal(data, v)
+	}
+
+	payload, err := decompress(compression, data[1:])
+	if err != nil {
+		return err
+	}
+
+	return marshalers[format].Unmarshal(payload, v)
+}
+
+// Describe names the format and compression of data, like "msgpack+zstd".
+func Describe(data []byte) string {
+	format, compression, err := header(data)
+	if err != nil {
+		return "unknown"
+	}
+	if compression == None {
+		return format.String()
+	}
+
+	return format.String() + "+" + compression.String()
+}
+
+// IsJSON tells whether data is header-less JSON, readable as is.
+func IsJSON(data []byte) bool {
+	return len(data) > 0 && !hasHeader(data)
+}
+
+func hasHeader(data []byte) bool {
+	return data[0] != '{' && data[0] != '['
+}
+
+func header(data []byte) (Format, Compression, error) {
+	if len(data) == 0 {
+		return 0, 0, fmt.Errorf("codec: empty value")
+	}
+	if !hasHeader(data) {
+		return JSON, None, nil
+	}
+
+	format, compression := Format(data[0]>>4), Compression(data[0]&0x0f)
+	_, knownFormat := marshalers[format]
+	_, knownCompression := compressionNames[compression]
+	if !knownFormat || !knownCompression {
+		return 0, 0, fmt.Errorf("codec: unknown header %#x", data[0])
+	}
+
+	return format, compression, nil
+}
@@ -0,0 +1,181 @@
+package codec_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec"
+)
+
+var (
+	formats      = []codec.Format{codec.JSON, codec.MsgPack, codec.Protobuf}
+	compressions = []codec.Compression{codec.None, codec.Zstd, codec.Snappy}
+)
+
+// character is about the size of a Marvel character with a long description.
+var character = domain.Character{
+	ID:          1011334,
+	Name:        "3-D Man",
+	Description: strings.Repeat("Rick Jones has been Hulk's best bud since day one. ", 20),
+	FetchedAt:   time.Date(2021, 7, 21, 10, 8, 56, 0, time.UTC),
+}
+
+var page = []int{1011334, 1017100, 1009144, 1010699, 1009146, 1016823, 1009148, 1009149, 1010903, 1011266}
+
+type CodecTestSuite struct {
+	suite.Suite
+}
+
+func TestCodec(t *testing.T) {
+	suite.Run(t, new(CodecTestSuite))
+}
+
+func (s *CodecTestSuite) TestSuccessRoundTrip() {
+	for _, format := range formats {
+		for _, compression := range compressions {
+			name := format.String() + "+" + compression.String()
+			enc := codec.NewEncoder(format, compression, 0)
+
+			data, err := enc.Encode(character)
+			s.Require().NoError(err, name)
+			var decoded domain.Character
+			s.Require().NoError(codec.Decode(data, &decoded), name)
+			s.Assert().Equal(character.ID, decoded.ID, name)
+			s.Assert().Equal(character.Name, decoded.Name, name)
+			s.Assert().Equal(character.Description, decoded.Description, name)
+			s.Assert().True(character.FetchedAt.Equal(decoded.FetchedAt), name)
+
+			data, err = enc.Encode(page)
+			s.Require().NoError(err, name)
+			var ids []int
+			s.Require().NoError(codec.Decode(data, &ids), name)
+			s.Assert().Equal(page, ids, name)
+		}
+	}
+}
+
+func (s *CodecTestSuite) TestSuccessHeader() {
+	data, err := codec.NewEncoder(codec.JSON, codec.None, 0).Encode(page)
+	s.Require().NoError(err)
+	s.Assert().True(codec.IsJSON(data))
+	s.Assert().Equal("json", codec.Describe(data))
+
+	data, err = codec.NewEncoder(codec.MsgPack, codec.Zstd, 0).Encode(character)
+	s.Require().NoError(err)
+	s.Assert().False(codec.IsJSON(data))
+	s.Assert().Equal(byte(0x21), data[0])
+	s.Assert().Equal("msgpack+zstd", codec.Describe(data))
+}
+
+func (s *CodecTestSuite) TestSuccessThreshold() {
+	enc := codec.NewEncoder(codec.Protobuf, codec.Snappy, 1024)
+
+	data, err := enc.Encode(page)
+	s.Require().NoError(err)
+	s.Assert().Equal("protobuf", codec.Describe(data))
+
+	data, err = enc.Encode(character)
+	s.Require().NoError(err)
+	s.Assert().Equal("protobuf+snappy", codec.Describe(data))
+}
+
+func (s *CodecTestSuite) TestSuccessLegacyJSON() {
+	var decoded domain.Character
+	s.Require().NoError(codec.Decode([]byte(`{"id":1,"name":"Hulk"}`), &decoded))
+	s.Assert().Equal("Hulk", decoded.Name)
+}
+
+func (s *CodecTestSuite) TestFailedDecode() {
+	var decoded domain.Character
+	s.Assert().Error(codec.Decode(nil, &decoded))
+	s.Assert().Error(codec.Decode([]byte{0x91, 0x00}, &decoded))
+	s.Assert().Error(codec.Decode([]byte{0x21, 0x00}, &decoded))
+	s.Assert().Equal("unknown", codec.Describe([]byte{0x91}))
+}
+
+func (s *CodecTestSuite) TestParse() {
+	enc, err := codec.New("msgpack", "zstd", 512)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(enc)
+
+	_, err = codec.New("xml", "none", 0)
+	s.Assert().EqualError(err, `unknown format "xml", want json, msgpack or protobuf`)
+	_, err = codec.New("json", "gzip", 0)
+	s.Assert().EqualError(err, `unknown compression "gzip", want none, zstd or snappy`)
+}
+
+// BenchmarkEncode and BenchmarkDecode compare the latency of every format
+// and compression on a character, and report the size of the value.
+//
+//	go test ./pkg/codec -bench . -benchmem
+func BenchmarkEncode(b *testing.B) {
+	for _, format := range formats {
+		for _, compression := range compressions {
+			enc := codec.NewEncoder(format, compression, 0)
+			b.Run(format.String()+"+"+compression.String(), func(b *testing.B) {
+				var data []byte
+				for i := 0; i < b.N; i++ {
+					data, _ = enc.Encode(character)
+				}
+				b.ReportMetric(float64(len(data)), "bytes/value")
+			})
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for _, format := range formats {
+		for _, compression := range compressions {
+			data, err := codec.NewEncoder(format, compression, 0).Encode(character)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(format.String()+"+"+compression.String(), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					var decoded domain.Character
+					if err := codec.Decode(data, &decoded); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(data)), "bytes/value")
+			})
+		}
+	}
+}
+
+func (s *CodecTestSuite) TestSuccessHash() {
+	modified := time.Date(2014, 4, 29, 18, 18, 17, 0, time.UTC)
+	fields := codec.EncodeHash(character, modified)
+	s.Assert().Equal("1011334", fields[codec.FieldID])
+	s.Assert().Equal("2014-04-29T18:18:17Z", fields[codec.FieldModified])
+
+	hash := map[string]string{}
+	for field, val := range fields {
+		hash[field] = val.(string)
+	}
+	decoded, tombstone, err := codec.DecodeHash(hash)
+	s.Require().NoError(err)
+	s.Assert().False(tombstone)
+	s.Assert().Equal(character.Name, decoded.Name)
+	s.Assert().True(character.FetchedAt.Equal(decoded.FetchedAt))
+
+	_, tombstone, err = codec.DecodeHash(map[string]string{codec.FieldTombstone: "1"})
+	s.Assert().NoError(err)
+	s.Assert().True(tombstone)
+
+	_, _, err = codec.DecodeHash(map[string]string{codec.FieldName: "Hulk"})
+	s.Assert().Error(err)
+}
+
+func (s *CodecTestSuite) TestHashValues() {
+	fields, ok := codec.HashValues([]interface{}{"1", "Hulk", nil, nil, nil})
+	s.Assert().True(ok)
+	s.Assert().Equal(map[string]string{codec.FieldID: "1", codec.FieldName: "Hulk"}, fields)
+
+	_, ok = codec.HashValues([]interface{}{nil, nil, nil, nil, nil})
+	s.Assert().False(ok)
+}
@@ -0,0 +1,5 @@
+// Package codecpb holds the protobuf messages of the cached values,
+// generated from cache.proto.
+package codecpb
+
+//go:generate buf generate
@@ -0,0 +1,51 @@
+package codec
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxDecodedSize bounds what a zstd value may decompress to.
+const maxDecodedSize = 64 << 20
+
+// The zstd encoder and decoder are safe for concurrent EncodeAll and
+// DecodeAll calls, and costly to create.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecodedSize))
+)
+
+func compress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case None:
+		return data, nil
+	case Zstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case Snappy:
+		return snappy.Encode(nil, data), nil
+	default:
+		return nil, fmt.Errorf("codec: unknown compression %d", compression)
+	}
+}
+
+func decompress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case None:
+		return data, nil
+	case Zstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	case Snappy:
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxDecodedSize {
+			return nil, fmt.Errorf("codec: snappy value of %d bytes is too large", n)
+		}
+		return snappy.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("codec: unknown compression %d", compression)
+	}
+}
@@ -0,0 +1,75 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+)
+
+// Fields of the character hashes of the hash layout. A tombstone is a hash
+// holding only FieldTombstone, as a hash cannot be empty.
+const (
+	FieldID          = "id"
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldFetchedAt   = "fetchedAt"
+	FieldModified    = "modified"
+	FieldTombstone   = "tombstone"
+)
+
+// CharacterFields are the fields a character is read from, in the order
+// HashValues expects them.
+var CharacterFields = []string{FieldID, FieldName, FieldDescription, FieldFetchedAt, FieldTombstone}
+
+// EncodeHash lays char out as hash fields. modified is left out when zero,
+// as Marvel API does not always know it.
+func EncodeHash(char domain.Character, modified time.Time) map[string]interface{} {
+	fields := map[string]interface{}{
+		FieldID:          strconv.FormatUint(uint64(char.ID), 10),
+		FieldName:        char.Name,
+		FieldDescription: char.Description,
+		FieldFetchedAt:   char.FetchedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if !modified.IsZero() {
+		fields[FieldModified] = modified.UTC().Format(time.RFC3339)
+	}
+
+	return fields
+}
+
+// DecodeHash reads a character from the fields of a hash. tombstone is set
+// for a character Marvel API does not have.
+func DecodeHash(fields map[string]string) (char domain.Character, tombstone bool, err error) {
+	if _, ok := fields[FieldTombstone]; ok {
+		return domain.Character{}, true, nil
+	}
+
+	id, err := strconv.ParseUint(fields[FieldID], 10, 64)
+	if err != nil {
+		return domain.Character{}, false, fmt.Errorf("codec: hash id: %w", err)
+	}
+	char = domain.Character{ID: uint(id), Name: fields[FieldName], Description: fields[FieldDescription]}
+	if fetchedAt := fields[FieldFetchedAt]; fetchedAt != "" {
+		if char.FetchedAt, err = time.Parse(time.RFC3339Nano, fetchedAt); err != nil {
+			return domain.Character{}, false, fmt.Errorf("codec: hash fetchedAt: %w", err)
+		}
+	}
+
+	return char, false, nil
+}
+
+// HashValues pairs the values HMGET returned for CharacterFields with their
+// fields, leaving out the missing ones. ok is false when the hash does not
+// exist.
+func HashValues(vals []interface{}) (fields map[string]string, ok bool) {
+	fields = make(map[string]string, len(vals))
+	for i, val := range vals {
+		if str, isString := val.(string); isString && i < len(CharacterFields) {
+			fields[CharacterFields[i]] = str
+		}
+	}
+
+	return fields, len(fields) > 0
+}
@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/codec/codecpb"
+)
+
+type jsonMarshaler struct{}
+
+func (jsonMarshaler) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackMarshaler keys maps by the JSON field names, so that fields left
+// out of JSON are left out too.
+type msgpackMarshaler struct{}
+
+func (msgpackMarshaler) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (msgpackMarshaler) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+
+	return dec.Decode(v)
+}
+
+// protobufMarshaler goes through the messages of codecpb.
+type protobufMarshaler struct{}
+
+func (protobufMarshaler) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case domain.Character:
+		m := &codecpb.Character{Id: int64(v.ID), Name: v.Name, Description: v.Description}
+		if !v.FetchedAt.IsZero() {
+			m.FetchedAt = timestamppb.New(v.FetchedAt)
+		}
+		return proto.Marshal(m)
+	case []int:
+		m := &codecpb.Page{Ids: make([]int64, len(v))}
+		for i, id := range v {
+			m.Ids[i] = int64(id)
+		}
+		return proto.Marshal(m)
+	default:
+		return nil, fmt.Errorf("codec: protobuf cannot marshal %T", v)
+	}
+}
+
+func (protobufMarshaler) Unmarshal(data []byte, v interface{}) error {
+	switch v := v.(type) {
+	case *domain.Character:
+		var m codecpb.Character
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = domain.Character{ID: uint(m.GetId()), Name: m.GetName(), Description: m.GetDescription()}
+		if m.FetchedAt != nil {
+			v.FetchedAt = m.FetchedAt.AsTime()
+		}
+		return nil
+	case *[]int:
+		var m codecpb.Page
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = make([]int, len(m.GetIds()))
+		for i, id := range m.GetIds() {
+			(*v)[i] = int(id)
+		}
+		return nil
+	default:
+		return fmt.Errorf("codec: protobuf cannot unmarshal into %T", v)
+	}
+}
@@ -0,0 +1,42 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+type Handler struct {
+	Checker *Checker
+}
+
+func NewHandler(e *echo.Echo, checker *Checker) *Handler {
+	handler := &Handler{
+		Checker: checker,
+	}
+	e.GET("/healthz", handler.Live)
+	e.GET("/readyz", handler.Ready)
+	e.GET("/status", handler.Status)
+
+	return handler
+}
+
+func (h *Handler) Live(c echo.Context) error {
+	return respond(c, h.Checker.Live())
+}
+
+func (h *Handler) Ready(c echo.Context) error {
+	return respond(c, h.Checker.Ready(c.Request().Context()))
+}
+
+func (h *Handler) Status(c echo.Context) error {
+	return respond(c, h.Checker.Status(c.Request().Context()))
+}
+
+func respond(c echo.Context, report Report) error {
+	if !report.Up() {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
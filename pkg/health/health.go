@@ -0,0 +1,265 @@
+// Package health reports whether the service and its dependencies are up, in
+// a shape suitable for Kubernetes liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+const (
+	checkTimeout = 2 * time.Second
+	scanCount    = 1000
+)
+
+// Version and Commit are set at build time with
+// -ldflags "-X github.com/hezbymuhammad/golang-marvel-demo/pkg/health.Version=..."
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+type Check struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Build struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"goVersion"`
+	StartedAt string `json:"startedAt"`
+	Uptime    string `json:"uptime"`
+}
+
+type Report struct {
+	Status string           `json:"status"`
+	Checks []Check          `json:"checks,omitempty"`
+	Cache  map[string]int64 `json:"cache,omitempty"`
+	Jobs   *int             `json:"pendingJobs,omitempty"`
+	Build  *Build           `json:"build,omitempty"`
+}
+
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+type Checker struct {
+	redisClient redis.Cmdable
+	httpClient  *http.Client
+	marvelURL   string
+	keyPatterns map[string]string
+	validate    func() error
+	pending     func() int
+	warm        int32
+	startedAt   time.Time
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// NewChecker builds a checker. keyPatterns maps a name to the redis MATCH
+// pattern whose keys are counted in the status report. validate reports
+// whether the running configuration is valid and pending returns the number
+// of queued background jobs; both may be nil.
+func NewChecker(redisClient redis.Cmdable, marvelURL string, keyPatterns map[string]string, validate func() error, pending func() int) *Checker {
+	return &Checker{
+		redisClient: redisClient,
+		httpClient:  &http.Client{Timeout: checkTimeout},
+		marvelURL:   marvelURL,
+		keyPatterns: keyPatterns,
+		validate:    validate,
+		pending:     pending,
+		startedAt:   time.Now(),
+	}
+}
+
+// MarkWarm records that the startup cache warmup is done.
+func (c *Checker) MarkWarm() {
+	atomic.StoreInt32(&c.warm, 1)
+}
+
+// Live only reports that the process is able to answer.
+func (c *Checker) Live() Report {
+	return Report{Status: StatusUp}
+}
+
+// Ready reports whether the service should receive traffic: redis is
+// reachable, the warmup is done and the configuration is valid.
+func (c *Checker) Ready(ctx context.Context) Report {
+	checks := []Check{
+		c.checkRedis(ctx),
+		c.checkWarm(),
+		c.checkConfig(),
+	}
+
+	return Report{
+		Status: overall(checks),
+		Checks: checks,
+	}
+}
+
+// Status is the detailed report. On top of the readiness checks it probes
+// the Marvel API and includes the last key counts and build information.
+func (c *Checker) Status(ctx context.Context) Report {
+	report := c.Ready(ctx)
+	report.Checks = append(report.Checks, c.checkMarvelAPI(ctx))
+	report.Status = overall(report.Checks)
+	c.mu.Lock()
+	report.Cache = c.counts
+	c.mu.Unlock()
+	if c.pending != nil {
+		pending := c.pending()
+		report.Jobs = &pending
+	}
+	report.Build = &Build{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+		StartedAt: c.startedAt.UTC().Format(time.RFC3339),
+		Uptime:    time.Since(c.startedAt).Truncate(time.Second).String(),
+	}
+
+	return report
+}
+
+func (c *Checker) checkRedis(parent context.Context) Check {
+	ctx, cancel := context.WithTimeout(parent, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.redisClient.Ping(ctx).Err()
+
+	return newCheck("redis", start, err)
+}
+
+func (c *Checker) checkMarvelAPI(parent context.Context) Check {
+	ctx, cancel := context.WithTimeout(parent, checkTimeout)
+	defer cancel()
+
+	// Any HTTP answer means the API is reachable. The request is not signed
+	// so it does not count against the Marvel quota.
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "GET", c.marvelURL, nil)
+	if err == nil {
+		var res *http.Response
+		res, err = c.httpClient.Do(req)
+		if err == nil {
+			res.Body.Close()
+		}
+	}
+
+	return newCheck("marvel_api", start, err)
+}
+
+func (c *Checker) checkWarm() Check {
+	check := Check{Name: "warmup", Status: StatusUp}
+	if atomic.LoadInt32(&c.warm) == 0 {
+		check.Status = StatusDown
+		check.Error = "Cache warmup in progress"
+	}
+
+	return check
+}
+
+func (c *Checker) checkConfig() Check {
+	check := Check{Name: "config", Status: StatusUp}
+	if c.validate == nil {
+		return check
+	}
+	if err := c.validate(); err != nil {
+		check.Status = StatusDown
+		check.Error = err.Error()
+	}
+
+	return check
+}
+
+// Run counts the keys right away and then every interval until ctx is done,
+// so that status requests, which anyone can make, never scan the keyspace.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.CountKeys(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CountKeys counts the keys of every pattern for the next status reports. It
+// uses SCAN rather than KEYS so that a large keyspace does not block redis.
+// Counts are -1 when the scan fails.
+func (c *Checker) CountKeys(ctx context.Context) {
+	counts := map[string]int64{}
+
+	names := make([]string, 0, len(c.keyPatterns))
+	for name := range c.keyPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var count int64
+		var cursor uint64
+		for {
+			keys, next, err := c.redisClient.Scan(ctx, cursor, c.keyPatterns[name], scanCount).Result()
+			if err != nil {
+				count = -1
+				break
+			}
+			count += int64(len(keys))
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+		counts[name] = count
+	}
+
+	c.mu.Lock()
+	c.counts = counts
+	c.mu.Unlock()
+}
+
+func newCheck(name string, start time.Time, err error) Check {
+	check := Check{
+		Name:      name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Status = StatusDown
+		check.Error = err.Error()
+	}
+
+	return check
+}
+
+func overall(checks []Check) string {
+	for _, check := range checks {
+		if check.Status != StatusUp {
+			return StatusDown
+		}
+	}
+
+	return StatusUp
+}
@@ -0,0 +1,149 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/health"
+)
+
+type HealthTestSuite struct {
+	suite.Suite
+	redis   *miniredis.Miniredis
+	marvel  *httptest.Server
+	checker *health.Checker
+	handler *health.Handler
+	invalid error
+}
+
+func TestHealth(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
+
+func (s *HealthTestSuite) SetupTest() {
+	mr, err := miniredis.Run()
+	if err != nil {
+		s.T().Fatalf("Error: '%s'", err)
+	}
+	s.redis = mr
+	s.marvel = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}))
+	s.invalid = nil
+
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+	s.checker = health.NewChecker(
+		client,
+		s.marvel.URL,
+		map[string]string{"characters": "marvel-v2-character-id-*"},
+		func() error { return s.invalid },
+		func() int { return 2 },
+	)
+	s.handler = health.NewHandler(echo.New(), s.checker)
+}
+
+func (s *HealthTestSuite) TearDownTest() {
+	s.redis.Close()
+	s.marvel.Close()
+}
+
+func (s *HealthTestSuite) serve(fn echo.HandlerFunc, path string) (*httptest.ResponseRecorder, health.Report) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	req, err := http.NewRequest(echo.GET, path, strings.NewReader(""))
+	ctx := e.NewContext(req, rec)
+
+	err = fn(ctx)
+	s.Assert().Equal(err, nil)
+
+	var report health.Report
+	err = json.Unmarshal(rec.Body.Bytes(), &report)
+	s.Assert().Equal(err, nil)
+
+	return rec, report
+}
+
+func (s *HealthTestSuite) TestSuccessLive() {
+	s.redis.Close()
+
+	rec, report := s.serve(s.handler.Live, "/healthz")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal(health.StatusUp, report.Status)
+}
+
+func (s *HealthTestSuite) TestSuccessReady() {
+	s.checker.MarkWarm()
+
+	rec, report := s.serve(s.handler.Ready, "/readyz")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal(health.StatusUp, report.Status)
+	s.Assert().Len(report.Checks, 3)
+}
+
+func (s *HealthTestSuite) TestFailedReadyWarmup() {
+	rec, report := s.serve(s.handler.Ready, "/readyz")
+	s.Assert().Equal(http.StatusServiceUnavailable, rec.Code)
+	s.Assert().Equal("warmup", report.Checks[1].Name)
+	s.Assert().Equal(health.StatusDown, report.Checks[1].Status)
+}
+
+func (s *HealthTestSuite) TestFailedReadyRedis() {
+	s.checker.MarkWarm()
+	s.redis.Close()
+
+	rec, report := s.serve(s.handler.Ready, "/readyz")
+	s.Assert().Equal(http.StatusServiceUnavailable, rec.Code)
+	s.Assert().Equal("redis", report.Checks[0].Name)
+	s.Assert().Equal(health.StatusDown, report.Checks[0].Status)
+}
+
+func (s *HealthTestSuite) TestFailedReadyConfig() {
+	s.checker.MarkWarm()
+	s.invalid = errors.New("invalid")
+
+	rec, report := s.serve(s.handler.Ready, "/readyz")
+	s.Assert().Equal(http.StatusServiceUnavailable, rec.Code)
+	s.Assert().Equal("invalid", report.Checks[2].Error)
+}
+
+func (s *HealthTestSuite) TestSuccessStatus() {
+	s.checker.MarkWarm()
+	s.redis.Set("marvel-v2-character-id-1", "{}")
+	s.redis.Set("marvel-v2-character-id-2", "{}")
+	s.redis.Set("marvel-v2-characters-page-1", "[]")
+
+	_, report := s.serve(s.handler.Status, "/status")
+	s.Assert().Nil(report.Cache)
+
+	s.checker.CountKeys(context.Background())
+	// Keys are counted again on the next run only.
+	s.redis.Set("marvel-v2-character-id-3", "{}")
+	rec, report := s.serve(s.handler.Status, "/status")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("marvel_api", report.Checks[3].Name)
+	s.Assert().Equal(health.StatusUp, report.Checks[3].Status)
+	s.Assert().Equal(int64(2), report.Cache["characters"])
+	s.Assert().Equal(2, *report.Jobs)
+	s.Assert().Equal(health.Version, report.Build.Version)
+}
+
+func (s *HealthTestSuite) TestFailedStatusMarvelAPI() {
+	s.checker.MarkWarm()
+	s.marvel.Close()
+
+	rec, report := s.serve(s.handler.Status, "/status")
+	s.Assert().Equal(http.StatusServiceUnavailable, rec.Code)
+	s.Assert().Equal(health.StatusDown, report.Checks[3].Status)
+}
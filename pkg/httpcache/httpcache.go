@@ -0,0 +1,93 @@
+// Package httpcache adds cache validators to responses and answers
+// conditional requests, so that browsers and CDNs can cache them.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+)
+
+// JSON writes v with an ETag computed from its content, a Last-Modified
+// header set to lastModified and a max-age counting down to expiresAt. Zero
+// times are left out, and without an expiry clients must revalidate.
+// Responses to authenticated requests are private, so that shared caches do
+// not hand them to other clients. It answers 304 Not Modified when the
+// request validators match.
+func JSON(c echo.Context, v interface{}, lastModified, expiresAt time.Time) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(v); err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	etag := ETag(body.Bytes())
+	header.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	_, private := auth.IdentityFrom(c.Request().Context())
+	header.Set("Cache-Control", cacheControl(expiresAt, time.Now(), private))
+
+	if NotModified(c.Request(), etag, lastModified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, body.Bytes())
+}
+
+// ETag returns a strong entity tag for body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified tells whether the client copy validated by If-None-Match, or
+// by If-Modified-Since when no entity tag was sent, is still current.
+func NotModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+	ims, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// Last-Modified only has a one second precision.
+	return !lastModified.Truncate(time.Second).After(ims)
+}
+
+func cacheControl(expiresAt, now time.Time, private bool) string {
+	maxAge := int(expiresAt.Sub(now) / time.Second)
+	if expiresAt.IsZero() || maxAge <= 0 {
+		return "no-cache"
+	}
+	if private {
+		return "private, max-age=" + strconv.Itoa(maxAge)
+	}
+
+	return "public, max-age=" + strconv.Itoa(maxAge)
+}
@@ -0,0 +1,88 @@
+package httpcache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/httpcache"
+)
+
+type HTTPCacheTestSuite struct {
+	suite.Suite
+	modified time.Time
+	etag     string
+}
+
+func TestHTTPCache(t *testing.T) {
+	suite.Run(t, new(HTTPCacheTestSuite))
+}
+
+func (s *HTTPCacheTestSuite) SetupTest() {
+	s.modified = time.Date(2021, 7, 21, 10, 8, 56, 456957000, time.UTC)
+	s.etag = httpcache.ETag([]byte(`{"id":1}`))
+}
+
+func (s *HTTPCacheTestSuite) request(method, header, value string) *http.Request {
+	req := httptest.NewRequest(method, "/characters/1", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	return req
+}
+
+func (s *HTTPCacheTestSuite) TestNotModifiedETag() {
+	s.Assert().True(httpcache.NotModified(s.request(echo.GET, "If-None-Match", s.etag), s.etag, s.modified))
+	s.Assert().True(httpcache.NotModified(s.request(echo.GET, "If-None-Match", `"other", W/`+s.etag), s.etag, s.modified))
+	s.Assert().True(httpcache.NotModified(s.request(echo.HEAD, "If-None-Match", "*"), s.etag, s.modified))
+	s.Assert().False(httpcache.NotModified(s.request(echo.GET, "If-None-Match", `"other"`), s.etag, s.modified))
+	s.Assert().False(httpcache.NotModified(s.request(echo.POST, "If-None-Match", s.etag), s.etag, s.modified))
+}
+
+func (s *HTTPCacheTestSuite) TestNotModifiedSince() {
+	s.Assert().True(httpcache.NotModified(s.request(echo.GET, "If-Modified-Since", "Wed, 21 Jul 2021 10:08:56 GMT"), s.etag, s.modified))
+	s.Assert().False(httpcache.NotModified(s.request(echo.GET, "If-Modified-Since", "Wed, 21 Jul 2021 10:08:55 GMT"), s.etag, s.modified))
+	s.Assert().False(httpcache.NotModified(s.request(echo.GET, "If-Modified-Since", "yesterday"), s.etag, s.modified))
+	s.Assert().False(httpcache.NotModified(s.request(echo.GET, "If-Modified-Since", "Wed, 21 Jul 2021 10:08:56 GMT"), s.etag, time.Time{}))
+}
+
+func (s *HTTPCacheTestSuite) TestNotModifiedETagWins() {
+	req := s.request(echo.GET, "If-None-Match", `"other"`)
+	req.Header.Set("If-Modified-Since", "Wed, 21 Jul 2021 10:08:56 GMT")
+
+	s.Assert().False(httpcache.NotModified(req, s.etag, s.modified))
+}
+
+func (s *HTTPCacheTestSuite) TestJSONWithoutExpiry() {
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(s.request(echo.GET, "", ""), rec)
+
+	err := httpcache.JSON(ctx, map[string]int{"id": 1}, time.Time{}, time.Time{})
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("no-cache", rec.Header().Get("Cache-Control"))
+	s.Assert().Empty(rec.Header().Get("Last-Modified"))
+	s.Assert().Equal(httpcache.ETag([]byte("{\"id\":1}\n")), rec.Header().Get("ETag"))
+	s.Assert().Equal("{\"id\":1}\n", rec.Body.String())
+}
+
+func (s *HTTPCacheTestSuite) TestJSONPrivate() {
+	expiresAt := time.Now().Add(time.Hour)
+
+	rec := httptest.NewRecorder()
+	err := httpcache.JSON(echo.New().NewContext(s.request(echo.GET, "", ""), rec), map[string]int{"id": 1}, time.Time{}, expiresAt)
+	s.Assert().Equal(err, nil)
+	s.Assert().Regexp(`^public, max-age=(3599|3600)$`, rec.Header().Get("Cache-Control"))
+
+	req := s.request(echo.GET, "X-API-Key", "lorem")
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Subject: "lorem", Method: "api_key"}))
+	rec = httptest.NewRecorder()
+	err = httpcache.JSON(echo.New().NewContext(req, rec), map[string]int{"id": 1}, time.Time{}, expiresAt)
+	s.Assert().Equal(err, nil)
+	s.Assert().Regexp(`^private, max-age=(3599|3600)$`, rec.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,175 @@
+// Package logger builds the structured loggers handed to every layer. Each
+// component gets its own level, which can be changed while running, and every
+// record logged with a context carries the request ID, trace and attributes
+// stored in that context.
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Factory struct {
+	mu         sync.Mutex
+	handler    slog.Handler
+	level      slog.Level
+	overrides  map[string]slog.Level
+	components map[string]*slog.LevelVar
+}
+
+// New returns a factory writing JSON records to w, or logfmt-like text when
+// format is "text".
+func New(w io.Writer, format string) *Factory {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	var handler slog.Handler = slog.NewJSONHandler(w, opts)
+	if format == "text" {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	return &Factory{
+		handler:    handler,
+		level:      slog.LevelInfo,
+		overrides:  map[string]slog.Level{},
+		components: map[string]*slog.LevelVar{},
+	}
+}
+
+// For returns the logger of component. Loggers of the same component share
+// their level.
+func (f *Factory) For(component string) *slog.Logger {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	level, ok := f.components[component]
+	if !ok {
+		level = new(slog.LevelVar)
+		level.Set(f.levelOf(component))
+		f.components[component] = level
+	}
+
+	return slog.New(&handler{
+		inner: f.handler.WithAttrs([]slog.Attr{slog.String("component", component)}),
+		level: level,
+	})
+}
+
+// SetLevels changes the default level and the per component overrides of
+// every logger, including the ones already handed out.
+func (f *Factory) SetLevels(level string, overrides map[string]string) error {
+	def, parsed, err := ParseLevels(level, overrides)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.level = def
+	f.overrides = parsed
+	for component, level := range f.components {
+		level.Set(f.levelOf(component))
+	}
+
+	return nil
+}
+
+func (f *Factory) levelOf(component string) slog.Level {
+	if level, ok := f.overrides[strings.ToLower(component)]; ok {
+		return level
+	}
+
+	return f.level
+}
+
+// ParseLevels parses a default level and per component overrides. Component
+// names are case insensitive.
+func ParseLevels(level string, overrides map[string]string) (slog.Level, map[string]slog.Level, error) {
+	var def slog.Level
+	if err := def.UnmarshalText([]byte(level)); err != nil {
+		return def, nil, err
+	}
+
+	parsed := map[string]slog.Level{}
+	for component, value := range overrides {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(value)); err != nil {
+			return def, nil, err
+		}
+		parsed[strings.ToLower(component)] = l
+	}
+
+	return def, parsed, nil
+}
+
+// Discard returns a logger that drops every record, for tests.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	attrsKey
+)
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithAttrs returns a context whose records get attrs on top of the ones
+// already stored in ctx.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(attrsKey).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, attrsKey, merged)
+}
+
+// handler filters records with the level of its component and enriches them
+// with the request scoped attributes and the trace found in the context.
+type handler struct {
+	inner slog.Handler
+	level *slog.LevelVar
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if attrs, ok := ctx.Value(attrsKey).([]slog.Attr); ok {
+			r.AddAttrs(attrs...)
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{inner: h.inner.WithAttrs(attrs), level: h.level}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{inner: h.inner.WithGroup(name), level: h.level}
+}
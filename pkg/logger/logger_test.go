@@ -0,0 +1,148 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+)
+
+type LoggerTestSuite struct {
+	suite.Suite
+	out  *bytes.Buffer
+	logs *logger.Factory
+}
+
+func TestLogger(t *testing.T) {
+	suite.Run(t, new(LoggerTestSuite))
+}
+
+func (s *LoggerTestSuite) SetupTest() {
+	s.out = new(bytes.Buffer)
+	s.logs = logger.New(s.out, "json")
+}
+
+func (s *LoggerTestSuite) records() []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(s.out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		err := json.Unmarshal([]byte(line), &record)
+		s.Require().Nil(err)
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func (s *LoggerTestSuite) TestSuccessRequestAttrs() {
+	ctx := logger.WithRequestID(context.Background(), "abc")
+	ctx = logger.WithAttrs(ctx, slog.Int("id", 1))
+
+	s.logs.For("usecase").InfoContext(ctx, "hello")
+
+	records := s.records()
+	s.Require().Len(records, 1)
+	s.Assert().Equal("usecase", records[0]["component"])
+	s.Assert().Equal("abc", records[0]["request_id"])
+	s.Assert().Equal(float64(1), records[0]["id"])
+}
+
+func (s *LoggerTestSuite) TestSuccessSetLevels() {
+	usecase := s.logs.For("usecase")
+	handler := s.logs.For("handler")
+
+	usecase.Debug("dropped")
+	err := s.logs.SetLevels("warn", map[string]string{"UseCase": "debug"})
+	s.Assert().Nil(err)
+	usecase.Debug("kept")
+	handler.Info("dropped")
+
+	records := s.records()
+	s.Require().Len(records, 1)
+	s.Assert().Equal("kept", records[0]["msg"])
+}
+
+func (s *LoggerTestSuite) TestFailedSetLevels() {
+	err := s.logs.SetLevels("loud", nil)
+	s.Assert().NotNil(err)
+}
+
+func (s *LoggerTestSuite) TestSuccessMiddleware() {
+	e := echo.New()
+	e.Use(logger.Middleware(s.logs.For("http")))
+	e.GET("/characters/:id", func(c echo.Context) error {
+		s.Assert().Equal("abc", logger.RequestID(c.Request().Context()))
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(echo.GET, "/characters/1", nil)
+	req.Header.Set(logger.RequestIDHeader, "abc")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	s.Assert().Equal("abc", rec.Header().Get(logger.RequestIDHeader))
+	records := s.records()
+	s.Require().Len(records, 1)
+	s.Assert().Equal("/characters/:id", records[0]["route"])
+	s.Assert().Equal(float64(http.StatusOK), records[0]["status"])
+}
+
+func (s *LoggerTestSuite) TestSuccessMiddlewareAttrsOfHandler() {
+	e := echo.New()
+	e.Use(logger.Middleware(s.logs.For("http")))
+	e.GET("/", func(c echo.Context) error {
+		ctx := logger.WithAttrs(c.Request().Context(), slog.String("subject", "jane"))
+		c.SetRequest(c.Request().WithContext(ctx))
+		return c.NoContent(http.StatusOK)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(echo.GET, "/", nil))
+
+	records := s.records()
+	s.Require().Len(records, 1)
+	s.Assert().Equal("jane", records[0]["subject"])
+	s.Assert().NotEmpty(records[0]["request_id"])
+}
+
+func (s *LoggerTestSuite) TestSuccessMiddlewareGeneratesID() {
+	e := echo.New()
+	e.Use(logger.Middleware(s.logs.For("http")))
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/", nil))
+
+	id := rec.Header().Get(logger.RequestIDHeader)
+	s.Assert().NotEmpty(id)
+	s.Assert().Equal(id, s.records()[0]["request_id"])
+}
+
+func (s *LoggerTestSuite) TestSuccessTraceAttrs() {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	s.logs.For("usecase").InfoContext(ctx, "hello")
+
+	records := s.records()
+	s.Require().Len(records, 1)
+	s.Assert().Equal(sc.TraceID().String(), records[0]["trace_id"])
+	s.Assert().Equal(sc.SpanID().String(), records[0]["span_id"])
+}
@@ -0,0 +1,176 @@
+// Package metrics holds the Prometheus collectors shared by every layer and
+// exposes them on /metrics.
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "marvel"
+
+const (
+	CacheHit       = "hit"
+	CacheMiss      = "miss"
+	CacheTombstone = "tombstone"
+	CacheError     = "error"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests served, by route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latencies, by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	CacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Redis cache lookups, by kind of entry and result (hit, miss, tombstone or error).",
+	}, []string{"kind", "result"})
+
+	TombstonesStored = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "tombstones_stored_total",
+		Help:      "Tombstones cached for characters and pages Marvel API does not know, by kind of entry.",
+	}, []string{"kind"})
+
+	PreRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "pre_refreshes_total",
+		Help:      "Refreshes of popular entries scheduled shortly before they expire, by kind of entry.",
+	}, []string{"kind"})
+
+	UpstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "requests_total",
+		Help:      "Marvel API calls, by endpoint and status code.",
+	}, []string{"endpoint", "code"})
+
+	UpstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "request_duration_seconds",
+		Help:      "Marvel API call latencies, by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	UpstreamQuotaUsed = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "quota_used",
+		Help:      "Marvel API calls made since midnight UTC, when the daily quota resets.",
+	})
+
+	UpstreamQuotaLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "quota_limit",
+		Help:      "Marvel API daily call quota.",
+	})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ratelimit",
+		Name:      "rejections_total",
+		Help:      "Requests rejected by a rate limit, by limit (client, global or miss).",
+	}, []string{"limit"})
+)
+
+var quota = &dailyCounter{}
+
+// dailyCounter counts events and starts over at midnight UTC.
+type dailyCounter struct {
+	mu    sync.Mutex
+	day   string
+	count int
+}
+
+func (d *dailyCounter) inc(now time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	day := now.UTC().Format("2006-01-02")
+	if day != d.day {
+		d.day = day
+		d.count = 0
+	}
+	d.count++
+
+	return d.count
+}
+
+// ObserveUpstream records a Marvel API call. code is 0 when no response was
+// received.
+func ObserveUpstream(endpoint string, code int, duration time.Duration) {
+	label := "error"
+	if code != 0 {
+		label = strconv.Itoa(code)
+	}
+
+	UpstreamRequests.WithLabelValues(endpoint, label).Inc()
+	UpstreamDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+	UpstreamQuotaUsed.Set(float64(quota.inc(time.Now())))
+}
+
+// RegisterPendingJobs exports the background job queue depth read from fn.
+func RegisterPendingJobs(fn func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "background",
+		Name:      "jobs_pending",
+		Help:      "Background cache refreshes scheduled but not finished.",
+	}, func() float64 {
+		return float64(fn())
+	})
+}
+
+// Middleware records the count and latency of every request, labelled with
+// the route pattern rather than the raw path to keep cardinality bounded.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			method := c.Request().Method
+			status := strconv.Itoa(c.Response().Status)
+
+			HTTPRequests.WithLabelValues(method, route, status).Inc()
+			HTTPDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
+
+func NewHandler(e *echo.Echo) {
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+}
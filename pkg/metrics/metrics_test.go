@@ -0,0 +1,85 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	e *echo.Echo
+}
+
+func TestMetrics(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func (s *MetricsTestSuite) SetupTest() {
+	s.e = echo.New()
+	s.e.Use(metrics.Middleware())
+	s.e.GET("/characters/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return errors.New("SomeError")
+		}
+		return c.JSON(http.StatusOK, c.Param("id"))
+	})
+	metrics.NewHandler(s.e)
+}
+
+func (s *MetricsTestSuite) serve(path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(echo.GET, path, strings.NewReader(""))
+	s.e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s *MetricsTestSuite) TestSuccessMiddleware() {
+	counter := metrics.HTTPRequests.WithLabelValues("GET", "/characters/:id", "200")
+	before := testutil.ToFloat64(counter)
+
+	s.serve("/characters/1")
+	s.serve("/characters/2")
+	s.Assert().Equal(before+2, testutil.ToFloat64(counter))
+}
+
+func (s *MetricsTestSuite) TestFailedMiddleware() {
+	counter := metrics.HTTPRequests.WithLabelValues("GET", "/characters/:id", "500")
+	before := testutil.ToFloat64(counter)
+
+	rec := s.serve("/characters/0")
+	s.Assert().Equal(http.StatusInternalServerError, rec.Code)
+	s.Assert().Equal(before+1, testutil.ToFloat64(counter))
+}
+
+func (s *MetricsTestSuite) TestSuccessObserveUpstream() {
+	ok := metrics.UpstreamRequests.WithLabelValues("character", "200")
+	failed := metrics.UpstreamRequests.WithLabelValues("character", "error")
+	before, beforeFailed := testutil.ToFloat64(ok), testutil.ToFloat64(failed)
+	quota := testutil.ToFloat64(metrics.UpstreamQuotaUsed)
+
+	metrics.ObserveUpstream("character", http.StatusOK, time.Millisecond)
+	metrics.ObserveUpstream("character", 0, time.Millisecond)
+	s.Assert().Equal(before+1, testutil.ToFloat64(ok))
+	s.Assert().Equal(beforeFailed+1, testutil.ToFloat64(failed))
+	s.Assert().Equal(quota+2, testutil.ToFloat64(metrics.UpstreamQuotaUsed))
+}
+
+func (s *MetricsTestSuite) TestSuccessHandler() {
+	metrics.RegisterPendingJobs(func() int { return 3 })
+
+	rec := s.serve("/metrics")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Contains(rec.Body.String(), "marvel_background_jobs_pending 3")
+	s.Assert().Contains(rec.Body.String(), "marvel_http_requests_total")
+}
@@ -0,0 +1,188 @@
+// Package prerefresh refreshes the characters and pages looked up the most
+// shortly before they expire, so that popular entries are not missed, and
+// so that Marvel API is not called for all of them at once when they would
+// have expired together. Lookups are counted by the popularity repository.
+package prerefresh
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/worker"
+)
+
+// key is a cached character or page.
+type key struct {
+	kind string
+	id   int
+}
+
+// hit is how often a key was looked up within the popularity window.
+type hit struct {
+	key
+	hits int64
+}
+
+// Options tune a Refresher. Every Interval, the Top keys looked up at least
+// MinHits times within Popularity are refreshed when they expire within
+// Window.
+type Options struct {
+	Interval   time.Duration
+	Window     time.Duration
+	Popularity time.Duration
+	Top        int
+	MinHits    int64
+}
+
+// Refresher refreshes the popular keys in the background, on the worker
+// pool, so that they are left alone while background refreshes are paused
+// and drained on shutdown. It remembers the popular keys of its last check,
+// for the write repository to keep them longer.
+type Refresher struct {
+	client     redis.Cmdable
+	keys       cachekey.Builder
+	writer     domain.CharacterWriteRepository
+	pool       *worker.Pool
+	popularity domain.PopularityRepository
+	opts       Options
+	logger     *slog.Logger
+
+	mu      sync.RWMutex
+	popular map[key]bool
+}
+
+func NewRefresher(client redis.Cmdable, keys cachekey.Builder, writer domain.CharacterWriteRepository, pool *worker.Pool, popularity domain.PopularityRepository, opts Options, logger *slog.Logger) *Refresher {
+	return &Refresher{
+		client:     client,
+		keys:       keys,
+		writer:     writer,
+		pool:       pool,
+		popularity: popularity,
+		opts:       opts,
+		logger:     logger,
+		popular:    map[key]bool{},
+	}
+}
+
+// Popular tells whether the character or page id, by kind, was among the
+// popular keys of the last check.
+func (r *Refresher) Popular(kind string, id int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.popular[key{kind: kind, id: id}]
+}
+
+// Run checks the popular keys every interval until ctx is done.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Check(ctx)
+		}
+	}
+}
+
+// Check schedules the refresh of the popular keys expiring within the
+// window, and returns how many were scheduled. Keys that expired already
+// are left to the next lookup.
+func (r *Refresher) Check(ctx context.Context) int {
+	top, err := r.top(ctx)
+	if err != nil {
+		r.logger.WarnContext(ctx, "Ranking popular keys failed", "error", err)
+		return 0
+	}
+	popular := make(map[key]bool, len(top))
+	for _, hit := range top {
+		popular[hit.key] = true
+	}
+	r.mu.Lock()
+	r.popular = popular
+	r.mu.Unlock()
+	if len(top) == 0 {
+		return 0
+	}
+
+	pipe := r.client.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(top))
+	for i, hit := range top {
+		ttls[i] = pipe.PTTL(ctx, r.redisKey(hit.key))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.logger.WarnContext(ctx, "Checking popular keys failed", "keys", len(top), "error", err)
+		return 0
+	}
+
+	scheduled := 0
+	for i, hit := range top {
+		if ttl := ttls[i].Val(); ttl <= 0 || ttl > r.opts.Window {
+			continue
+		}
+
+		hit := hit
+		err := r.pool.Go(ctx, func(ctx context.Context) {
+			if err := r.refresh(ctx, hit.key); err != nil {
+				r.logger.WarnContext(ctx, "Pre-refresh failed", "kind", hit.kind, "id", hit.id, "error", err)
+			}
+		})
+		if err != nil {
+			r.logger.DebugContext(ctx, "Pre-refresh not scheduled", "kind", hit.kind, "id", hit.id, "error", err)
+			break
+		}
+		metrics.PreRefreshes.WithLabelValues(hit.kind).Inc()
+		scheduled++
+	}
+
+	return scheduled
+}
+
+// top ranks the characters and pages together, the most looked up first.
+func (r *Refresher) top(ctx context.Context) ([]hit, error) {
+	top := []hit{}
+	for _, kind := range []string{cachekey.KindCharacter, cachekey.KindPage} {
+		hits, err := r.popularity.Top(ctx, kind, r.opts.Popularity, r.opts.Top, r.opts.MinHits)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hits {
+			top = append(top, hit{key: key{kind: kind, id: h.ID}, hits: h.Hits})
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].hits > top[j].hits
+	})
+	if len(top) > r.opts.Top {
+		top = top[:r.opts.Top]
+	}
+
+	return top, nil
+}
+
+func (r *Refresher) redisKey(key key) string {
+	if key.kind == cachekey.KindPage {
+		return r.keys.Page(key.id)
+	}
+
+	return r.keys.Character(key.id)
+}
+
+func (r *Refresher) refresh(ctx context.Context, key key) error {
+	if key.kind == cachekey.KindPage {
+		return r.writer.RefreshByPage(ctx, key.id)
+	}
+
+	return r.writer.RefreshByID(ctx, key.id)
+}
@@ -0,0 +1,113 @@
+package prerefresh_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/domain/mocks"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/prerefresh"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/worker"
+)
+
+type PreRefreshTestSuite struct {
+	suite.Suite
+	mr         *miniredis.Miniredis
+	pool       *worker.Pool
+	writer     *mocks.CharacterWriteRepository
+	popularity *mocks.PopularityRepository
+	refresher  *prerefresh.Refresher
+}
+
+func TestPreRefresh(t *testing.T) {
+	suite.Run(t, new(PreRefreshTestSuite))
+}
+
+func (s *PreRefreshTestSuite) SetupTest() {
+	mr, err := miniredis.Run()
+	s.Require().NoError(err)
+	s.mr = mr
+
+	s.pool = worker.NewPool()
+	s.writer = new(mocks.CharacterWriteRepository)
+	s.popularity = new(mocks.PopularityRepository)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	opts := prerefresh.Options{Interval: time.Minute, Window: 10 * time.Minute, Popularity: time.Hour, Top: 2, MinHits: 2}
+	s.refresher = prerefresh.NewRefresher(client, cachekey.New(cachekey.DefaultPrefix), s.writer, s.pool, s.popularity, opts, logger.Discard())
+}
+
+func (s *PreRefreshTestSuite) TearDownTest() {
+	s.mr.Close()
+}
+
+func (s *PreRefreshTestSuite) top(characters, pages []domain.Hit) {
+	s.popularity.On("Top", mock.Anything, cachekey.KindCharacter, time.Hour, 2, int64(2)).Return(characters, nil)
+	s.popularity.On("Top", mock.Anything, cachekey.KindPage, time.Hour, 2, int64(2)).Return(pages, nil)
+}
+
+func (s *PreRefreshTestSuite) TestSuccessCheck() {
+	s.mr.Set("marvel-v2-character-id-1", "{}")
+	s.mr.SetTTL("marvel-v2-character-id-1", 5*time.Minute)
+	s.mr.Set("marvel-v2-character-id-2", "{}")
+	s.mr.SetTTL("marvel-v2-character-id-2", time.Hour)
+	s.mr.Set("marvel-v2-characters-page-3", "[1]")
+	s.mr.SetTTL("marvel-v2-characters-page-3", time.Minute)
+	// Expiring soon but not among the top two.
+	s.top([]domain.Hit{{ID: 1, Hits: 4}, {ID: 2, Hits: 4}}, []domain.Hit{{ID: 3, Hits: 3}})
+
+	s.writer.On("RefreshByID", mock.Anything, 1).Return(nil)
+
+	s.Assert().Equal(1, s.refresher.Check(context.Background()))
+	s.Require().NoError(s.pool.Shutdown(context.Background()))
+	s.writer.AssertCalled(s.T(), "RefreshByID", mock.Anything, 1)
+	s.writer.AssertNotCalled(s.T(), "RefreshByPage", mock.Anything, mock.Anything)
+
+	s.Assert().True(s.refresher.Popular(cachekey.KindCharacter, 2))
+	s.Assert().False(s.refresher.Popular(cachekey.KindPage, 3))
+}
+
+func (s *PreRefreshTestSuite) TestSuccessCheckPage() {
+	s.mr.Set("marvel-v2-characters-page-3", "[1]")
+	s.mr.SetTTL("marvel-v2-characters-page-3", time.Minute)
+	s.top([]domain.Hit{}, []domain.Hit{{ID: 3, Hits: 2}})
+	s.writer.On("RefreshByPage", mock.Anything, 3).Return(domain.ErrInternalServerError)
+
+	s.Assert().Equal(1, s.refresher.Check(context.Background()))
+	s.Require().NoError(s.pool.Shutdown(context.Background()))
+	s.writer.AssertCalled(s.T(), "RefreshByPage", mock.Anything, 3)
+	s.Assert().True(s.refresher.Popular(cachekey.KindPage, 3))
+}
+
+func (s *PreRefreshTestSuite) TestCheckPaused() {
+	s.mr.Set("marvel-v2-character-id-1", "{}")
+	s.mr.SetTTL("marvel-v2-character-id-1", time.Minute)
+	s.top([]domain.Hit{{ID: 1, Hits: 2}}, []domain.Hit{})
+	s.pool.SetPaused(true)
+
+	s.Assert().Equal(0, s.refresher.Check(context.Background()))
+	s.writer.AssertNotCalled(s.T(), "RefreshByID", mock.Anything, mock.Anything)
+}
+
+func (s *PreRefreshTestSuite) TestFailedCheck() {
+	s.top([]domain.Hit{{ID: 1, Hits: 2}}, []domain.Hit{})
+	s.mr.Close()
+
+	s.Assert().Equal(0, s.refresher.Check(context.Background()))
+	// The ranking still stands.
+	s.Assert().True(s.refresher.Popular(cachekey.KindCharacter, 1))
+}
+
+func (s *PreRefreshTestSuite) TestFailedCheckTop() {
+	s.popularity.On("Top", mock.Anything, cachekey.KindCharacter, time.Hour, 2, int64(2)).Return(nil, domain.ErrInternalServerError)
+
+	s.Assert().Equal(0, s.refresher.Check(context.Background()))
+	s.Assert().False(s.refresher.Popular(cachekey.KindCharacter, 1))
+}
@@ -0,0 +1,147 @@
+// Package problem writes errors as RFC 7807 application/problem+json
+// responses.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+)
+
+const (
+	ContentType = "application/problem+json"
+	typePrefix  = "urn:golang-marvel-demo:problem:"
+)
+
+// NotCachedRetryAfter is the delay suggested to clients asking for a resource
+// whose background refresh was just scheduled.
+var NotCachedRetryAfter = 5
+
+// retryable is implemented by errors that lift after some time, such as
+// rate limits.
+type retryable interface {
+	RetryAfter() time.Duration
+}
+
+// Problem is the response body. Code is the domain error code, also found at
+// the end of Type. RetryAfter, in seconds, is set for resources that are not
+// cached yet and rate limited requests, and mirrors the Retry-After header.
+type Problem struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Status     int    `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Instance   string `json:"instance,omitempty"`
+	Code       string `json:"code"`
+	RetryAfter int    `json:"retryAfter,omitempty"`
+}
+
+// StatusOf maps the code of err to an HTTP status.
+func StatusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	switch domain.CodeOf(err) {
+	case domain.CodeNotFound:
+		return http.StatusNotFound
+	case domain.CodeBadRequest:
+		return http.StatusBadRequest
+	case domain.CodeNotCached:
+		return http.StatusServiceUnavailable
+	case domain.CodeUnauthorized:
+		return http.StatusUnauthorized
+	case domain.CodeForbidden:
+		return http.StatusForbidden
+	case domain.CodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FromError describes err for the request to instance. Only the message of
+// the domain error is disclosed, never the error it wraps.
+func FromError(err error, instance string) Problem {
+	code := domain.CodeOf(err)
+	status := StatusOf(err)
+
+	p := Problem{
+		Type:     typePrefix + string(code),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: instance,
+		Code:     string(code),
+	}
+	var e *domain.Error
+	if errors.As(err, &e) {
+		p.Detail = e.Message
+	}
+	if code == domain.CodeNotCached {
+		p.RetryAfter = NotCachedRetryAfter
+	}
+	var r retryable
+	if errors.As(err, &r) && r.RetryAfter() > 0 {
+		p.RetryAfter = int(math.Ceil(r.RetryAfter().Seconds()))
+	}
+
+	return p
+}
+
+// Write sends p, with its Retry-After header when set. Problems are never
+// cached, a resource missing now may be there on the next try.
+func Write(c echo.Context, p Problem) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	if p.RetryAfter > 0 {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(p.RetryAfter))
+	}
+
+	return c.Blob(p.Status, ContentType, body)
+}
+
+// Error writes err as a problem for the current request.
+func Error(c echo.Context, err error) error {
+	return Write(c, FromError(err, c.Request().URL.RequestURI()))
+}
+
+// HTTPErrorHandler replaces the echo error handler so that routing errors,
+// such as unknown paths, are problems too.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	he, ok := err.(*echo.HTTPError)
+	if !ok {
+		_ = Error(c, err)
+		return
+	}
+
+	code := domain.CodeInternal
+	switch {
+	case he.Code == http.StatusNotFound:
+		code = domain.CodeNotFound
+	case he.Code < http.StatusInternalServerError:
+		code = domain.CodeBadRequest
+	}
+	_ = Write(c, Problem{
+		Type:     typePrefix + string(code),
+		Title:    http.StatusText(he.Code),
+		Status:   he.Code,
+		Detail:   fmt.Sprint(he.Message),
+		Instance: c.Request().URL.RequestURI(),
+		Code:     string(code),
+	})
+}
@@ -0,0 +1,73 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+type ProblemTestSuite struct {
+	suite.Suite
+}
+
+func TestProblem(t *testing.T) {
+	suite.Run(t, new(ProblemTestSuite))
+}
+
+func (s *ProblemTestSuite) TestFromError() {
+	p := problem.FromError(fmt.Errorf("GetByID: %w", domain.ErrNotFound), "/characters/1")
+	s.Assert().Equal(http.StatusNotFound, p.Status)
+	s.Assert().Equal("Resource not found", p.Detail)
+	s.Assert().Equal("/characters/1", p.Instance)
+	s.Assert().Equal(0, p.RetryAfter)
+
+	p = problem.FromError(domain.ErrCacheKeyEmpty, "/characters/1")
+	s.Assert().Equal(http.StatusServiceUnavailable, p.Status)
+	s.Assert().Equal(problem.NotCachedRetryAfter, p.RetryAfter)
+
+	p = problem.FromError(domain.ErrUnauthorized, "/admin/cache/stats")
+	s.Assert().Equal(http.StatusUnauthorized, p.Status)
+	s.Assert().Equal("unauthorized", p.Code)
+
+	p = problem.FromError(domain.ErrForbidden, "/admin/cache/stats")
+	s.Assert().Equal(http.StatusForbidden, p.Status)
+
+	p = problem.FromError(domain.ErrQuotaExceeded, "/characters/1")
+	s.Assert().Equal(http.StatusTooManyRequests, p.Status)
+	s.Assert().Equal("rate_limited", p.Code)
+}
+
+func (s *ProblemTestSuite) TestFromErrorHidesCause() {
+	p := problem.FromError(domain.ErrInternalServerError.Wrap(errors.New("dial tcp 10.0.0.1:6379")), "/characters")
+	s.Assert().Equal(http.StatusInternalServerError, p.Status)
+	s.Assert().Equal("Internal Server Error", p.Detail)
+
+	p = problem.FromError(errors.New("dial tcp 10.0.0.1:6379"), "/characters")
+	s.Assert().Equal("", p.Detail)
+	s.Assert().Equal("internal", p.Code)
+}
+
+func (s *ProblemTestSuite) TestHTTPErrorHandler() {
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/unknown", nil))
+
+	s.Assert().Equal(http.StatusNotFound, rec.Code)
+	s.Assert().Equal(problem.ContentType, rec.Header().Get(echo.HeaderContentType))
+	var p problem.Problem
+	err := json.Unmarshal(rec.Body.Bytes(), &p)
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal("not_found", p.Code)
+	s.Assert().Equal("/unknown", p.Instance)
+}
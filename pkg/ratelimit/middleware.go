@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/labstack/echo"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/auth"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+)
+
+// Headers of the IETF RateLimit header fields draft.
+const (
+	LimitHeader     = "RateLimit-Limit"
+	RemainingHeader = "RateLimit-Remaining"
+	ResetHeader     = "RateLimit-Reset"
+)
+
+// Limits are enforced by Middleware. Client is the limit of each client and
+// Global of all of them together. Miss is the limit of each client on cache
+// misses, which fetch from Marvel API, and is usually stricter.
+type Limits struct {
+	Client Limit
+	Global Limit
+	Miss   Limit
+}
+
+// DynamicLimits holds Limits that Store replaces while requests are checked
+// against them, as on configuration reloads. Buckets keep their tokens, then
+// refill and are capped according to the new limits.
+type DynamicLimits struct {
+	limits atomic.Pointer[Limits]
+}
+
+func NewDynamicLimits(limits Limits) *DynamicLimits {
+	d := &DynamicLimits{}
+	d.Store(limits)
+
+	return d
+}
+
+func (d *DynamicLimits) Load() Limits {
+	return *d.limits.Load()
+}
+
+func (d *DynamicLimits) Store(limits Limits) {
+	d.limits.Store(&limits)
+}
+
+// Middleware limits requests with limiter, against the limits of the time.
+// Clients are told apart by their identity when authenticated, by their IP
+// otherwise, so it belongs after the auth middleware. When limiter fails,
+// requests are let through.
+func Middleware(limiter Limiter, limits *DynamicLimits, logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx, res, err := Check(req.Context(), limiter, limits.Load(), ClientKey(c), logger)
+			if res.Limit > 0 {
+				setHeaders(c, res)
+			}
+			if err != nil {
+				return problem.Error(c, err)
+			}
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// Check counts a request of client against the client and global limits,
+// and returns ctx with the miss limit of client. It fails with an *Error once
+// either limit is reached. res is the bucket of client, or the one that
+// rejected the request, and is zero when there is none to report. When
+// limiter fails, the request is let through.
+func Check(ctx context.Context, limiter Limiter, limits Limits, client string, logger *slog.Logger) (context.Context, Result, error) {
+	var report Result
+	if limits.Client.Enabled() {
+		res, err := limiter.Allow(ctx, "client-"+client, limits.Client)
+		switch {
+		case err != nil:
+			logger.WarnContext(ctx, "Rate limit check failed", "limit", "client", "error", err)
+		case !res.Allowed:
+			return ctx, res, rejected("client", res)
+		default:
+			report = res
+		}
+	}
+	if limits.Global.Enabled() {
+		res, err := limiter.Allow(ctx, "global", limits.Global)
+		switch {
+		case err != nil:
+			logger.WarnContext(ctx, "Rate limit check failed", "limit", "global", "error", err)
+		case !res.Allowed:
+			return ctx, res, rejected("global", res)
+		}
+	}
+	if limits.Miss.Enabled() {
+		ctx = WithMissLimit(ctx, limiter, "miss-"+client, limits.Miss, logger)
+	}
+
+	return ctx, report, nil
+}
+
+// ClientKey tells the client of a request apart: by the method and subject
+// of its identity when authenticated, by its IP otherwise.
+func ClientKey(c echo.Context) string {
+	if id, ok := auth.IdentityFrom(c.Request().Context()); ok {
+		return id.Method + "-" + id.Subject
+	}
+
+	return "ip-" + c.RealIP()
+}
+
+func setHeaders(c echo.Context, res Result) {
+	h := c.Response().Header()
+	h.Set(LimitHeader, strconv.FormatInt(res.Limit, 10))
+	h.Set(RemainingHeader, strconv.FormatInt(res.Remaining, 10))
+	h.Set(ResetHeader, strconv.FormatInt(int64(math.Ceil(res.Reset.Seconds())), 10))
+}
+
+func rejected(limit string, res Result) error {
+	metrics.RateLimited.WithLabelValues(limit).Inc()
+
+	return &Error{retryAfter: res.RetryAfter}
+}
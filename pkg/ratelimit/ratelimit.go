@@ -0,0 +1,118 @@
+// Package ratelimit limits the requests of clients with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/metrics"
+)
+
+// Limit lets Burst requests through at once, then refills PerMinute of them
+// a minute. A zero PerMinute means unlimited.
+type Limit struct {
+	PerMinute int64
+	Burst     int64
+}
+
+func (l Limit) Enabled() bool {
+	return l.PerMinute > 0
+}
+
+func (l Limit) perSecond() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// Result is the state of a bucket after a request was counted, or rejected
+// when Allowed is false. Reset is when the bucket is full again, RetryAfter
+// when the next request is allowed.
+type Result struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter takes a token from the bucket of key, filled according to limit.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// resultOf describes a bucket of limit left with tokens.
+func resultOf(allowed bool, tokens float64, limit Limit) Result {
+	rate := limit.perSecond()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int64(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+
+	return time.Duration(s * float64(time.Second))
+}
+
+// Error rejects a request over a limit. It is domain.ErrRateLimited, and
+// tells when to try again.
+type Error struct {
+	retryAfter time.Duration
+}
+
+func (e *Error) Error() string { return domain.ErrRateLimited.Error() }
+
+func (e *Error) Unwrap() error { return domain.ErrRateLimited }
+
+func (e *Error) RetryAfter() time.Duration { return e.retryAfter }
+
+type ctxKey int
+
+const missKey ctxKey = iota
+
+type missLimit struct {
+	limiter Limiter
+	key     string
+	limit   Limit
+	logger  *slog.Logger
+}
+
+// WithMissLimit returns a context whose cache misses are taken from the
+// bucket key of limiter, filled according to limit.
+func WithMissLimit(ctx context.Context, limiter Limiter, key string, limit Limit, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, missKey, missLimit{limiter: limiter, key: key, limit: limit, logger: logger})
+}
+
+// AllowMiss charges a cache miss, which fetches from Marvel API, to the
+// client of ctx. It fails with an *Error once the client made too many, and
+// is nil for contexts without a miss limit or when the limiter fails.
+func AllowMiss(ctx context.Context) error {
+	m, ok := ctx.Value(missKey).(missLimit)
+	if !ok {
+		return nil
+	}
+
+	res, err := m.limiter.Allow(ctx, m.key, m.limit)
+	if err != nil {
+		m.logger.WarnContext(ctx, "Rate limit check failed", "limit", "miss", "error", err)
+		return nil
+	}
+	if !res.Allowed {
+		metrics.RateLimited.WithLabelValues("miss").Inc()
+		return &Error{retryAfter: res.RetryAfter}
+	}
+
+	return nil
+}
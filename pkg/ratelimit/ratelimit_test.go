@@ -0,0 +1,205 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/domain"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/logger"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/problem"
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/ratelimit"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	mr *miniredis.Miniredis
+}
+
+func TestRateLimit(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
+
+func (s *RateLimitTestSuite) SetupTest() {
+	mr, err := miniredis.Run()
+	s.Require().NoError(err)
+	s.mr = mr
+}
+
+func (s *RateLimitTestSuite) TearDownTest() {
+	s.mr.Close()
+}
+
+func (s *RateLimitTestSuite) limiters() map[string]ratelimit.Limiter {
+	client := redis.NewClient(&redis.Options{Addr: s.mr.Addr()})
+	return map[string]ratelimit.Limiter{
+		"memory": ratelimit.NewMemoryLimiter(),
+		"redis":  ratelimit.NewRedisLimiter(client, cachekey.New(cachekey.DefaultPrefix)),
+	}
+}
+
+func (s *RateLimitTestSuite) TestBurst() {
+	limit := ratelimit.Limit{PerMinute: 1, Burst: 2}
+	for name, limiter := range s.limiters() {
+		res, err := limiter.Allow(context.Background(), "lorem", limit)
+		s.Require().NoError(err, name)
+		s.Assert().True(res.Allowed, name)
+		s.Assert().Equal(int64(2), res.Limit, name)
+		s.Assert().Equal(int64(1), res.Remaining, name)
+
+		res, _ = limiter.Allow(context.Background(), "lorem", limit)
+		s.Assert().True(res.Allowed, name)
+		s.Assert().Equal(int64(0), res.Remaining, name)
+
+		res, _ = limiter.Allow(context.Background(), "lorem", limit)
+		s.Assert().False(res.Allowed, name)
+		s.Assert().InDelta(time.Minute.Seconds(), res.RetryAfter.Seconds(), 1, name)
+		s.Assert().InDelta((2 * time.Minute).Seconds(), res.Reset.Seconds(), 1, name)
+
+		res, _ = limiter.Allow(context.Background(), "ipsum", limit)
+		s.Assert().True(res.Allowed, name)
+	}
+}
+
+func (s *RateLimitTestSuite) TestRefill() {
+	limit := ratelimit.Limit{PerMinute: 6000, Burst: 1}
+	for name, limiter := range s.limiters() {
+		res, _ := limiter.Allow(context.Background(), "lorem", limit)
+		s.Assert().True(res.Allowed, name)
+		res, _ = limiter.Allow(context.Background(), "lorem", limit)
+		s.Assert().False(res.Allowed, name)
+
+		time.Sleep(15 * time.Millisecond)
+		res, _ = limiter.Allow(context.Background(), "lorem", limit)
+		s.Assert().True(res.Allowed, name)
+	}
+}
+
+func (s *RateLimitTestSuite) TestRedisExpiry() {
+	limiter := ratelimit.NewRedisLimiter(redis.NewClient(&redis.Options{Addr: s.mr.Addr()}), cachekey.New("staging-"))
+	_, err := limiter.Allow(context.Background(), "lorem", ratelimit.Limit{PerMinute: 60, Burst: 10})
+	s.Require().NoError(err)
+
+	s.Assert().True(s.mr.Exists("staging-ratelimit-lorem"))
+	s.Assert().InDelta(2*time.Second, s.mr.TTL("staging-ratelimit-lorem"), float64(time.Second))
+}
+
+func (s *RateLimitTestSuite) TestFailedRedis() {
+	limiter := ratelimit.NewRedisLimiter(redis.NewClient(&redis.Options{Addr: s.mr.Addr()}), cachekey.New(cachekey.DefaultPrefix))
+	s.mr.Close()
+
+	_, err := limiter.Allow(context.Background(), "lorem", ratelimit.Limit{PerMinute: 60, Burst: 10})
+	s.Assert().Error(err)
+}
+
+func (s *RateLimitTestSuite) TestAllowMiss() {
+	s.Assert().NoError(ratelimit.AllowMiss(context.Background()))
+
+	ctx := ratelimit.WithMissLimit(context.Background(), ratelimit.NewMemoryLimiter(), "miss-lorem", ratelimit.Limit{PerMinute: 1, Burst: 1}, logger.Discard())
+	s.Assert().NoError(ratelimit.AllowMiss(ctx))
+
+	err := ratelimit.AllowMiss(ctx)
+	s.Assert().ErrorIs(err, domain.ErrRateLimited)
+	p := problem.FromError(err, "/characters/1")
+	s.Assert().Equal(http.StatusTooManyRequests, p.Status)
+	s.Assert().InDelta(60, p.RetryAfter, 1)
+}
+
+func (s *RateLimitTestSuite) serve(e *echo.Echo, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(echo.GET, "/characters/1", nil)
+	req.Header.Set(echo.HeaderXRealIP, ip)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s *RateLimitTestSuite) TestMiddlewareClient() {
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.GET("/characters/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, ratelimit.Middleware(ratelimit.NewMemoryLimiter(), ratelimit.NewDynamicLimits(ratelimit.Limits{
+		Client: ratelimit.Limit{PerMinute: 60, Burst: 2},
+	}), logger.Discard()))
+
+	rec := s.serve(e, "10.0.0.1")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+	s.Assert().Equal("2", rec.Header().Get(ratelimit.LimitHeader))
+	s.Assert().Equal("1", rec.Header().Get(ratelimit.RemainingHeader))
+	s.Assert().Equal("1", rec.Header().Get(ratelimit.ResetHeader))
+
+	s.serve(e, "10.0.0.1")
+	rec = s.serve(e, "10.0.0.1")
+	s.Assert().Equal(http.StatusTooManyRequests, rec.Code)
+	s.Assert().Equal("1", rec.Header().Get("Retry-After"))
+	s.Assert().Equal("0", rec.Header().Get(ratelimit.RemainingHeader))
+	s.Assert().Contains(rec.Body.String(), `"code":"rate_limited"`)
+
+	rec = s.serve(e, "10.0.0.2")
+	s.Assert().Equal(http.StatusOK, rec.Code)
+}
+
+func (s *RateLimitTestSuite) TestMiddlewareGlobal() {
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.GET("/characters/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, ratelimit.Middleware(ratelimit.NewMemoryLimiter(), ratelimit.NewDynamicLimits(ratelimit.Limits{
+		Client: ratelimit.Limit{PerMinute: 60, Burst: 2},
+		Global: ratelimit.Limit{PerMinute: 1, Burst: 2},
+	}), logger.Discard()))
+
+	s.Assert().Equal(http.StatusOK, s.serve(e, "10.0.0.1").Code)
+	s.Assert().Equal(http.StatusOK, s.serve(e, "10.0.0.2").Code)
+	s.Assert().Equal(http.StatusTooManyRequests, s.serve(e, "10.0.0.3").Code)
+}
+
+func (s *RateLimitTestSuite) TestMiddlewareMiss() {
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.GET("/characters/:id", func(c echo.Context) error {
+		if err := ratelimit.AllowMiss(c.Request().Context()); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	}, ratelimit.Middleware(ratelimit.NewMemoryLimiter(), ratelimit.NewDynamicLimits(ratelimit.Limits{
+		Client: ratelimit.Limit{PerMinute: 60, Burst: 10},
+		Miss:   ratelimit.Limit{PerMinute: 1, Burst: 1},
+	}), logger.Discard()))
+
+	s.Assert().Equal(http.StatusOK, s.serve(e, "10.0.0.1").Code)
+	rec := s.serve(e, "10.0.0.1")
+	s.Assert().Equal(http.StatusTooManyRequests, rec.Code)
+	s.Assert().Equal("60", rec.Header().Get("Retry-After"))
+	s.Assert().Equal(http.StatusOK, s.serve(e, "10.0.0.2").Code)
+}
+
+func (s *RateLimitTestSuite) TestMiddlewareReload() {
+	limits := ratelimit.NewDynamicLimits(ratelimit.Limits{
+		Client: ratelimit.Limit{PerMinute: 60, Burst: 1},
+	})
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.GET("/characters/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, ratelimit.Middleware(ratelimit.NewMemoryLimiter(), limits, logger.Discard()))
+
+	s.Assert().Equal(http.StatusOK, s.serve(e, "10.0.0.1").Code)
+	s.Assert().Equal(http.StatusTooManyRequests, s.serve(e, "10.0.0.1").Code)
+
+	limits.Store(ratelimit.Limits{})
+	s.Assert().Equal(http.StatusOK, s.serve(e, "10.0.0.1").Code)
+
+	limits.Store(ratelimit.Limits{Global: ratelimit.Limit{PerMinute: 1, Burst: 1}})
+	s.Assert().Equal(http.StatusOK, s.serve(e, "10.0.0.2").Code)
+	s.Assert().Equal(http.StatusTooManyRequests, s.serve(e, "10.0.0.3").Code)
+}
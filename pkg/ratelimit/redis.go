@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/cachekey"
+)
+
+// takeScript refills and takes from a bucket atomically, so that replicas
+// share it. The time is passed by the caller rather than read with TIME,
+// which would keep the script from writing on older Redis versions, so
+// replicas need synchronized clocks. Buckets expire once they are full.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+if now > updated then
+	tokens = math.min(burst, tokens + (now - updated) * rate)
+	updated = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'updated', updated)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisLimiter keeps its buckets in Redis, shared by every replica, under
+// the key prefix of the cache but apart from its values so that purges leave
+// them alone.
+type RedisLimiter struct {
+	client *redis.Client
+	keys   cachekey.Builder
+}
+
+func NewRedisLimiter(client *redis.Client, keys cachekey.Builder) *RedisLimiter {
+	return &RedisLimiter{client: client, keys: keys}
+}
+
+func (r *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	perMillisecond := limit.perSecond() / 1000
+	now := time.Now().UnixMilli()
+
+	val, err := takeScript.Run(ctx, r.client, []string{r.keys.RateLimit(key)},
+		strconv.FormatFloat(perMillisecond, 'g', -1, 64), limit.Burst, now).Result()
+	if err != nil {
+		return Result{}, err
+	}
+	res, ok := val.([]interface{})
+	if !ok || len(res) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", val)
+	}
+
+	allowed, _ := res[0].(int64)
+	raw, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(tokens) {
+		tokens = 0
+	}
+
+	return resultOf(allowed == 1, tokens, limit), nil
+}
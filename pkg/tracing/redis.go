@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	redis "github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisHook traces every command sent by a redis client. Commands issued
+// outside of a trace, such as health probes, are not recorded.
+type RedisHook struct{}
+
+func (RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return start(ctx, "redis "+cmd.Name(), attribute.String("db.operation.name", cmd.Name())), nil
+}
+
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	end(ctx, cmd.Err())
+	return nil
+}
+
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return start(ctx, "redis pipeline", attribute.Int("db.operation.batch.size", len(cmds))), nil
+}
+
+func (RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if err = cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+			break
+		}
+	}
+	end(ctx, err)
+	return nil
+}
+
+func start(ctx context.Context, name string, attr attribute.KeyValue) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	ctx, _ = tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system.name", "redis"), attr),
+	)
+	return ctx
+}
+
+// end closes the span opened by start. A missing key is an answer, not a
+// failure.
+func end(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	End(span, err)
+}
@@ -0,0 +1,142 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/tracing"
+)
+
+type TracingTestSuite struct {
+	suite.Suite
+	spans *tracetest.SpanRecorder
+}
+
+func TestTracing(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
+
+func (s *TracingTestSuite) SetupTest() {
+	s.spans = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.spans)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+func (s *TracingTestSuite) attr(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func (s *TracingTestSuite) TestSuccessMiddleware() {
+	e := echo.New()
+	e.Use(tracing.Middleware("/healthz"))
+	e.GET("/characters/:id", func(c echo.Context) error {
+		s.Assert().True(trace.SpanContextFromContext(c.Request().Context()).IsValid())
+		return c.NoContent(http.StatusInternalServerError)
+	})
+	e.GET("/healthz", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(echo.GET, "/characters/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(echo.GET, "/healthz", nil))
+
+	spans := s.spans.Ended()
+	s.Require().Len(spans, 1)
+	s.Assert().Equal("GET /characters/:id", spans[0].Name())
+	s.Assert().Equal("4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	s.Assert().Equal(codes.Error, spans[0].Status().Code)
+	s.Assert().Equal(int64(http.StatusInternalServerError), s.attr(spans[0], "http.response.status_code").AsInt64())
+}
+
+func (s *TracingTestSuite) TestSuccessRedisHook() {
+	mr, err := miniredis.Run()
+	s.Require().Nil(err)
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	client.AddHook(tracing.RedisHook{})
+	defer client.Close()
+
+	client.Get(context.Background(), "untraced")
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	client.Get(ctx, "missing")
+	client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "foo", "bar", 0)
+		pipe.Get(ctx, "foo")
+		return nil
+	})
+	parent.End()
+
+	spans := s.spans.Ended()
+	s.Require().Len(spans, 3)
+	s.Assert().Equal("redis get", spans[0].Name())
+	s.Assert().Equal(codes.Unset, spans[0].Status().Code)
+	s.Assert().Equal(parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	s.Assert().Equal("redis pipeline", spans[1].Name())
+	s.Assert().Equal(int64(2), s.attr(spans[1], "db.operation.batch.size").AsInt64())
+}
+
+func (s *TracingTestSuite) TestSuccessTransport() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Assert().Empty(r.Header.Get("traceparent"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: tracing.Transport(http.DefaultTransport)}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/v1/public/characters?apikey=secret", nil)
+	res, err := client.Do(req)
+	s.Require().Nil(err)
+	res.Body.Close()
+	parent.End()
+
+	spans := s.spans.Ended()
+	s.Require().Len(spans, 2)
+	s.Assert().Equal("HTTP GET", spans[0].Name())
+	s.Assert().Equal("/v1/public/characters", s.attr(spans[0], "url.path").AsString())
+	for _, kv := range spans[0].Attributes() {
+		s.Assert().NotContains(kv.Value.Emit(), "secret")
+	}
+}
+
+func (s *TracingTestSuite) TestSuccessSetupFile() {
+	file := s.T().TempDir() + "/traces.json"
+
+	flush, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "file", File: file, SampleRatio: 1})
+	s.Require().Nil(err)
+	_, span := otel.Tracer("test").Start(context.Background(), "written")
+	span.End()
+	s.Assert().Nil(flush(context.Background()))
+
+	content, err := os.ReadFile(file)
+	s.Assert().Nil(err)
+	s.Assert().Contains(string(content), `"Name":"written"`)
+}
+
+func (s *TracingTestSuite) TestFailedSetupUnknownExporter() {
+	_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "jaeger"})
+	s.Assert().NotNil(err)
+}
@@ -0,0 +1,104 @@
+// Package worker runs fire-and-forget background jobs while keeping track of
+// them, so they can be drained before the process exits.
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrClosed = errors.New("Worker pool is closed. Not accepting jobs")
+	ErrPaused = errors.New("Worker pool is paused. Not accepting jobs")
+)
+
+type Pool struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	closed  bool
+	paused  int32
+	pending int64
+}
+
+func NewPool() *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Pool{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go runs fn in its own goroutine. The context handed to fn carries the
+// values of parent, such as the request ID, but outlives it: it is only
+// cancelled when Shutdown gives up waiting.
+func (p *Pool) Go(parent context.Context, fn func(ctx context.Context)) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	if atomic.LoadInt32(&p.paused) == 1 {
+		return ErrPaused
+	}
+
+	p.wg.Add(1)
+	atomic.AddInt64(&p.pending, 1)
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	stop := context.AfterFunc(p.ctx, cancel)
+	go func() {
+		defer p.wg.Done()
+		defer atomic.AddInt64(&p.pending, -1)
+		defer stop()
+		defer cancel()
+
+		fn(ctx)
+	}()
+
+	return nil
+}
+
+// SetPaused makes Go reject new jobs while paused. Jobs already running are
+// not affected.
+func (p *Pool) SetPaused(paused bool) {
+	var v int32
+	if paused {
+		v = 1
+	}
+	atomic.StoreInt32(&p.paused, v)
+}
+
+// Pending returns the number of jobs that have been scheduled but have not
+// finished yet.
+func (p *Pool) Pending() int {
+	return int(atomic.LoadInt64(&p.pending))
+}
+
+// Shutdown stops accepting new jobs and waits for the running ones to finish.
+// When ctx is done first, the jobs still running are cancelled and ctx.Err()
+// is returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	defer p.cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,107 @@
+package worker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hezbymuhammad/golang-marvel-demo/pkg/worker"
+)
+
+type PoolTestSuite struct {
+	suite.Suite
+	pool *worker.Pool
+}
+
+func TestPool(t *testing.T) {
+	suite.Run(t, new(PoolTestSuite))
+}
+
+func (s *PoolTestSuite) SetupTest() {
+	s.pool = worker.NewPool()
+}
+
+func (s *PoolTestSuite) TestSuccessShutdownWaitsForJobs() {
+	release := make(chan struct{})
+	finished := make(chan struct{})
+
+	err := s.pool.Go(context.Background(), func(ctx context.Context) {
+		<-release
+		close(finished)
+	})
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal(1, s.pool.Pending())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+
+	err = s.pool.Shutdown(context.Background())
+	s.Assert().Equal(err, nil)
+	s.Assert().Equal(0, s.pool.Pending())
+
+	select {
+	case <-finished:
+	default:
+		s.T().Fatal("Shutdown returned before the job finished")
+	}
+}
+
+func (s *PoolTestSuite) TestFailedShutdownDeadline() {
+	cancelled := make(chan struct{})
+
+	err := s.pool.Go(context.Background(), func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+	s.Assert().Equal(err, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = s.pool.Shutdown(ctx)
+	s.Assert().Equal(err, context.DeadlineExceeded)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		s.T().Fatal("Job context was not cancelled after the deadline")
+	}
+}
+
+func (s *PoolTestSuite) TestFailedGoAfterShutdown() {
+	err := s.pool.Shutdown(context.Background())
+	s.Assert().Equal(err, nil)
+
+	err = s.pool.Go(context.Background(), func(ctx context.Context) {})
+	s.Assert().Equal(err, worker.ErrClosed)
+}
+
+func (s *PoolTestSuite) TestPausedGo() {
+	s.pool.SetPaused(true)
+	err := s.pool.Go(context.Background(), func(ctx context.Context) {})
+	s.Assert().Equal(err, worker.ErrPaused)
+
+	s.pool.SetPaused(false)
+	err = s.pool.Go(context.Background(), func(ctx context.Context) {})
+	s.Assert().Equal(err, nil)
+}
+
+type ctxKey struct{}
+
+func (s *PoolTestSuite) TestSuccessGoDetachesParent() {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	done := make(chan struct{})
+
+	err := s.pool.Go(parent, func(ctx context.Context) {
+		cancel()
+		s.Assert().Equal(nil, ctx.Err())
+		s.Assert().Equal("value", ctx.Value(ctxKey{}))
+		close(done)
+	})
+	s.Assert().Equal(err, nil)
+	<-done
+}